@@ -42,6 +42,7 @@ var (
 		resourceLockNamespace    string
 		tlsCipherSuites          []string
 		tlsMinVersion            string
+		canaryPrometheusURL      string
 	}
 )
 
@@ -52,6 +53,7 @@ func init() {
 	startCmd.PersistentFlags().StringVar(&startOpts.promMetricsListenAddress, "metrics-listen-address", "127.0.0.1:8797", "Listen address for prometheus metrics listener")
 	startCmd.PersistentFlags().StringSliceVar(&startOpts.tlsCipherSuites, "tls-cipher-suites", nil, "Comma-separated list of cipher suites for the metrics server")
 	startCmd.PersistentFlags().StringVar(&startOpts.tlsMinVersion, "tls-min-version", "VersionTLS12", "Minimum TLS version supported for the metrics server")
+	startCmd.PersistentFlags().StringVar(&startOpts.canaryPrometheusURL, "canary-prometheus-url", node.DefaultCanaryPrometheusURL, "Prometheus API to run the canary health queries of pools against; Canary health queries are disabled if empty")
 }
 
 func runStartCmd(_ *cobra.Command, _ []string) {
@@ -115,6 +117,8 @@ func runStartCmd(_ *cobra.Command, _ []string) {
 		pinnedImageSet := pinnedimageset.New(
 			ctrlctx.InformerFactory.Machineconfiguration().V1().PinnedImageSets(),
 			ctrlctx.InformerFactory.Machineconfiguration().V1().MachineConfigPools(),
+			ctrlctx.KubeInformerFactory.Core().V1().Nodes(),
+			ctrlctx.OperatorInformerFactory.Operator().V1().MachineConfigurations(),
 			ctrlctx.ClientBuilder.KubeClientOrDie("pinned-image-set-controller"),
 			ctrlctx.ClientBuilder.MachineConfigClientOrDie("pinned-image-set-controller"),
 		)
@@ -201,6 +205,31 @@ func runStartCmd(_ *cobra.Command, _ []string) {
 }
 
 func createControllers(ctx *ctrlcommon.ControllerContext) []ctrlcommon.Controller {
+	nodeController := node.New(
+		ctx.InformerFactory.Machineconfiguration().V1().ControllerConfigs(),
+		ctx.InformerFactory.Machineconfiguration().V1().MachineConfigs(),
+		ctx.InformerFactory.Machineconfiguration().V1().MachineConfigPools(),
+		ctx.KubeInformerFactory.Core().V1().Nodes(),
+		ctx.KubeInformerFactory.Core().V1().Pods(),
+		ctx.OCLInformerFactory.Machineconfiguration().V1().MachineOSConfigs(),
+		ctx.OCLInformerFactory.Machineconfiguration().V1().MachineOSBuilds(),
+		ctx.InformerFactory.Machineconfiguration().V1().MachineConfigNodes(),
+		ctx.ConfigInformerFactory.Config().V1().Schedulers(),
+		ctx.OperatorInformerFactory.Operator().V1().MachineConfigurations(),
+		ctx.InformerFactory.Machineconfiguration().V1alpha1().OSImageStreams(),
+		ctx.ConfigInformerFactory.Config().V1().Infrastructures(),
+		ctx.ClientBuilder.KubeClientOrDie("node-update-controller"),
+		ctx.ClientBuilder.MachineConfigClientOrDie("node-update-controller"),
+		ctx.FeatureGatesHandler,
+	)
+	if startOpts.canaryPrometheusURL != "" {
+		querier, err := node.NewPrometheusCanaryQuerier(startOpts.canaryPrometheusURL)
+		if err != nil {
+			klog.Warningf("Canary health queries are disabled: %v", err)
+		} else {
+			nodeController.AddCanaryHealthChecker(node.NewQueryCanaryHealthChecker(querier))
+		}
+	}
 
 	var controllers []ctrlcommon.Controller
 	controllers = append(controllers,
@@ -253,28 +282,15 @@ func createControllers(ctx *ctrlcommon.ControllerContext) []ctrlcommon.Controlle
 			ctx.InformerFactory.Machineconfiguration().V1().KubeletConfigs(),
 			ctx.OperatorInformerFactory.Operator().V1().MachineConfigurations(),
 			ctx.InformerFactory.Machineconfiguration().V1alpha1().OSImageStreams(),
+			ctx.KubeInformerFactory.Core().V1().Nodes(),
+			ctx.InformerFactory.Machineconfiguration().V1().MachineConfigNodes(),
+			ctx.OCLInformerFactory.Machineconfiguration().V1().MachineOSBuilds(),
 			ctx.ClientBuilder.KubeClientOrDie("render-controller"),
 			ctx.ClientBuilder.MachineConfigClientOrDie("render-controller"),
 			ctx.FeatureGatesHandler,
 		),
 		// The node controller consumes data written by the above
-		node.New(
-			ctx.InformerFactory.Machineconfiguration().V1().ControllerConfigs(),
-			ctx.InformerFactory.Machineconfiguration().V1().MachineConfigs(),
-			ctx.InformerFactory.Machineconfiguration().V1().MachineConfigPools(),
-			ctx.KubeInformerFactory.Core().V1().Nodes(),
-			ctx.KubeInformerFactory.Core().V1().Pods(),
-			ctx.OCLInformerFactory.Machineconfiguration().V1().MachineOSConfigs(),
-			ctx.OCLInformerFactory.Machineconfiguration().V1().MachineOSBuilds(),
-			ctx.InformerFactory.Machineconfiguration().V1().MachineConfigNodes(),
-			ctx.ConfigInformerFactory.Config().V1().Schedulers(),
-			ctx.OperatorInformerFactory.Operator().V1().MachineConfigurations(),
-			ctx.InformerFactory.Machineconfiguration().V1alpha1().OSImageStreams(),
-			ctx.ConfigInformerFactory.Config().V1().Infrastructures(),
-			ctx.ClientBuilder.KubeClientOrDie("node-update-controller"),
-			ctx.ClientBuilder.MachineConfigClientOrDie("node-update-controller"),
-			ctx.FeatureGatesHandler,
-		),
+		nodeController,
 	)
 
 	return controllers
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	daemon "github.com/openshift/machine-config-operator/pkg/daemon"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	planOutputText = "text"
+	planOutputJSON = "json"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show what updating to a MachineConfig would do to a node",
+	Long: `Show what the Machine Config Daemon would do to move a node from one rendered
+MachineConfig to another, without cluster access and without changing the host.
+
+On a node, the current MachineConfig defaults to the one the daemon last
+applied; elsewhere --current is required. Node disruption policies and irreconcilable overrides are read from a
+MachineConfiguration manifest, such as the output of
+"oc get machineconfiguration cluster -o yaml"; without one, the default
+policies are used.
+
+Examples:
+  # Compare two rendered MachineConfigs:
+  $ machine-config-daemon plan --current rendered-worker-old.yaml --desired rendered-worker-new.yaml
+
+  # Compare this host's current config against a candidate:
+  $ machine-config-daemon plan --desired rendered-worker-new.yaml --machineconfiguration cluster.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runPlanCmd,
+}
+
+// planOnNodeCurrentConfigPath is where the daemon stores the config it last
+// applied, which the current config defaults to when run on a node.
+var planOnNodeCurrentConfigPath = daemonconsts.CurrentMachineConfigPath
+
+var planOpts struct {
+	currentConfigFile        string
+	desiredConfigFile        string
+	machineConfigurationFile string
+	firstBoot                bool
+	output                   string
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.PersistentFlags().StringVar(&planOpts.currentConfigFile, "current", "", "The MachineConfig file the node is currently on. Required unless run on a node, where it defaults to the config the daemon last applied.")
+	planCmd.PersistentFlags().StringVar(&planOpts.desiredConfigFile, "desired", "", "The MachineConfig file to update the node to.")
+	planCmd.PersistentFlags().StringVar(&planOpts.machineConfigurationFile, "machineconfiguration", "", "The MachineConfiguration file holding the cluster's node disruption policies.")
+	planCmd.PersistentFlags().BoolVar(&planOpts.firstBoot, "firstboot", false, "Plan the update as done during firstboot, where node disruption policies do not apply.")
+	planCmd.PersistentFlags().StringVarP(&planOpts.output, "output", "o", planOutputText, "Output format, one of: text, json.")
+}
+
+func runPlanCmd(cmd *cobra.Command, _ []string) error {
+	flag.Set("logtostderr", "true")
+	flag.Parse()
+
+	return runPlan(cmd.OutOrStdout())
+}
+
+func runPlan(w io.Writer) error {
+	if planOpts.desiredConfigFile == "" {
+		return fmt.Errorf("--desired is required")
+	}
+	if planOpts.output != planOutputText && planOpts.output != planOutputJSON {
+		return fmt.Errorf("unsupported output format %q: must be %q or %q", planOpts.output, planOutputText, planOutputJSON)
+	}
+
+	currentConfigFile := planOpts.currentConfigFile
+	if currentConfigFile == "" {
+		if _, err := os.Stat(planOnNodeCurrentConfigPath); err != nil {
+			return fmt.Errorf("--current is required when not run on a node: %w", err)
+		}
+		currentConfigFile = planOnNodeCurrentConfigPath
+	}
+
+	currentConfig := &mcfgv1.MachineConfig{}
+	if err := readManifest(currentConfigFile, currentConfig); err != nil {
+		return err
+	}
+	desiredConfig := &mcfgv1.MachineConfig{}
+	if err := readManifest(planOpts.desiredConfigFile, desiredConfig); err != nil {
+		return err
+	}
+
+	mcop := &opv1.MachineConfiguration{}
+	if planOpts.machineConfigurationFile != "" {
+		if err := readManifest(planOpts.machineConfigurationFile, mcop); err != nil {
+			return err
+		}
+	}
+
+	// The operator merges the user defined policies into the defaults to
+	// produce the policies the daemon acts on; do the same here so that the
+	// manifest does not need an up to date status.
+	var clusterPolicies *opv1.NodeDisruptionPolicyClusterStatus
+	if !planOpts.firstBoot {
+		merged := apihelpers.MergeClusterPolicies(mcop.Spec.NodeDisruptionPolicy)
+		clusterPolicies = &merged
+	}
+
+	plan, err := daemon.PlanUpdate(currentConfig, desiredConfig, &mcop.Spec.IrreconcilableValidationOverrides, clusterPolicies)
+	if err != nil {
+		return err
+	}
+
+	if planOpts.output == planOutputJSON {
+		out, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+		return nil
+	}
+	printPlan(w, plan)
+	return nil
+}
+
+// readManifest reads a YAML or JSON manifest into obj.
+func readManifest(path string, obj interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// printPlan prints a human readable summary of plan.
+func printPlan(w io.Writer, plan *daemon.UpdatePlan) {
+	fmt.Fprintf(w, "Update from %s to %s\n", plan.CurrentConfig, plan.DesiredConfig)
+	if !plan.Reconcilable {
+		fmt.Fprintf(w, "Reconcilable: no\n  %s\n", plan.UnreconcilableReason)
+		return
+	}
+	fmt.Fprintf(w, "Reconcilable: yes\n")
+
+	printValue := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+	printList := func(name string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s:\n", name)
+		for _, value := range values {
+			fmt.Fprintf(w, "  %s\n", value)
+		}
+	}
+
+	printValue("OS image", plan.OSImageURL)
+	printValue("Kernel type", plan.KernelType)
+	printList("Kernel arguments", plan.KernelArguments)
+	printList("Extensions added", plan.ExtensionsAdded)
+	printList("Extensions removed", plan.ExtensionsRemoved)
+	printList("Files written", plan.FilesWritten)
+	printList("Files removed", plan.FilesRemoved)
+	printList("Units written", plan.UnitsWritten)
+	printList("Units removed", plan.UnitsRemoved)
+	printList("Units enabled", plan.UnitsEnabled)
+	printList("Units disabled", plan.UnitsDisabled)
+	if plan.UsersUpdated {
+		fmt.Fprintf(w, "SSH keys or password hashes updated\n")
+	}
+
+	if plan.NodeDisruptionActions != nil {
+		actions := make([]string, 0, len(plan.NodeDisruptionActions))
+		for _, action := range plan.NodeDisruptionActions {
+			switch {
+			case action.Reload != nil:
+				actions = append(actions, fmt.Sprintf("%s %s", action.Type, action.Reload.ServiceName))
+			case action.Restart != nil:
+				actions = append(actions, fmt.Sprintf("%s %s", action.Type, action.Restart.ServiceName))
+			default:
+				actions = append(actions, string(action.Type))
+			}
+		}
+		printList("Node disruption actions", actions)
+	} else {
+		printValue("Post config change actions", strings.Join(plan.PostConfigChangeActions, ", "))
+	}
+	fmt.Fprintf(w, "Drain required: %t\n", plan.DrainRequired)
+	fmt.Fprintf(w, "Reboot required: %t\n", plan.RebootRequired)
+}
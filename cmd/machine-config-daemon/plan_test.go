@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	daemon "github.com/openshift/machine-config-operator/pkg/daemon"
+)
+
+// runPlanCommand runs the plan subcommand with args and returns its output.
+func runPlanCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	// Flags keep their values across executions of the command.
+	planCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		require.NoError(t, f.Value.Set(f.DefValue))
+	})
+	out := &bytes.Buffer{}
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs(append([]string{"plan"}, args...))
+	defer rootCmd.SetOut(nil)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestPlanCmd(t *testing.T) {
+	current := filepath.Join("testdata", "rendered-worker-old.yaml")
+	desired := filepath.Join("testdata", "rendered-worker-new.yaml")
+	mcop := filepath.Join("testdata", "machineconfiguration.yaml")
+
+	t.Run("text", func(t *testing.T) {
+		out, err := runPlanCommand(t, "--current", current, "--desired", desired, "--machineconfiguration", mcop)
+		require.NoError(t, err)
+		assert.Equal(t, `Update from rendered-worker-old to rendered-worker-new
+Reconcilable: yes
+Files written:
+  /etc/foo.conf
+Files removed:
+  /etc/bar.conf
+Node disruption actions:
+  Restart foo.service
+Drain required: false
+Reboot required: false
+`, out)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := runPlanCommand(t, "--current", current, "--desired", desired, "--machineconfiguration", mcop, "-o", "json")
+		require.NoError(t, err)
+		plan := &daemon.UpdatePlan{}
+		require.NoError(t, json.Unmarshal([]byte(out), plan))
+		assert.Equal(t, "rendered-worker-old", plan.CurrentConfig)
+		assert.Equal(t, "rendered-worker-new", plan.DesiredConfig)
+		assert.Equal(t, []string{"/etc/foo.conf"}, plan.FilesWritten)
+		assert.Equal(t, []string{"/etc/bar.conf"}, plan.FilesRemoved)
+		require.Len(t, plan.NodeDisruptionActions, 1)
+		assert.EqualValues(t, "foo.service", plan.NodeDisruptionActions[0].Restart.ServiceName)
+	})
+
+	t.Run("default policies", func(t *testing.T) {
+		// Without the cluster's policies, changing a file not covered by the
+		// default policies reboots the node.
+		out, err := runPlanCommand(t, "--current", current, "--desired", desired, "-o", "json")
+		require.NoError(t, err)
+		plan := &daemon.UpdatePlan{}
+		require.NoError(t, json.Unmarshal([]byte(out), plan))
+		assert.True(t, plan.DrainRequired)
+		assert.True(t, plan.RebootRequired)
+	})
+
+	t.Run("firstboot", func(t *testing.T) {
+		out, err := runPlanCommand(t, "--current", current, "--desired", desired, "--firstboot")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Post config change actions: reboot\n")
+		assert.NotContains(t, out, "Node disruption actions")
+	})
+
+	t.Run("current defaults to the config on the node", func(t *testing.T) {
+		defer func(path string) { planOnNodeCurrentConfigPath = path }(planOnNodeCurrentConfigPath)
+		planOnNodeCurrentConfigPath = current
+		out, err := runPlanCommand(t, "--desired", desired, "--machineconfiguration", mcop)
+		require.NoError(t, err)
+		assert.Contains(t, out, "Update from rendered-worker-old to rendered-worker-new\n")
+	})
+
+	errorTests := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "current required off the node",
+			args:          []string{"--desired", desired},
+			expectedError: "--current is required when not run on a node",
+		},
+		{
+			name:          "desired required",
+			args:          []string{"--current", current},
+			expectedError: "--desired is required",
+		},
+		{
+			name:          "unsupported output",
+			args:          []string{"--current", current, "--desired", desired, "-o", "yaml"},
+			expectedError: `unsupported output format "yaml"`,
+		},
+		{
+			name:          "missing config",
+			args:          []string{"--current", filepath.Join("testdata", "missing.yaml"), "--desired", desired},
+			expectedError: "could not read testdata/missing.yaml",
+		},
+	}
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func(path string) { planOnNodeCurrentConfigPath = path }(planOnNodeCurrentConfigPath)
+			planOnNodeCurrentConfigPath = filepath.Join(t.TempDir(), "currentconfig")
+			_, err := runPlanCommand(t, test.args...)
+			assert.ErrorContains(t, err, test.expectedError)
+		})
+	}
+}
@@ -222,6 +222,7 @@ func runStartCmd(_ *cobra.Command, _ []string) {
 	pinnedImageSetManager := daemon.NewPinnedImageSetManager(
 		startOpts.nodeName,
 		criClient,
+		kubeClient,
 		ctrlctx.ClientBuilder.MachineConfigClientOrDie(componentName),
 		ctrlctx.InformerFactory.Machineconfiguration().V1().PinnedImageSets(),
 		nodeScopedInformer,
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"fmt"
+	"os"
 
+	mcfginformers "github.com/openshift/client-go/machineconfiguration/informers/externalversions"
+	"github.com/openshift/machine-config-operator/internal/clients"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
 	"github.com/openshift/machine-config-operator/pkg/server"
 	"github.com/openshift/machine-config-operator/pkg/version"
 	"github.com/spf13/cobra"
 
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -23,6 +30,10 @@ var (
 	startOpts struct {
 		kubeconfig   string
 		apiserverURL string
+		nodeIdentity []string
+		nodeClientCA string
+		metricsAddr  string
+		accessLog    string
 	}
 )
 
@@ -30,6 +41,11 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 	startCmd.PersistentFlags().StringVar(&startOpts.kubeconfig, "kubeconfig", "", "Kubeconfig file to access a remote cluster (testing only)")
 	startCmd.PersistentFlags().StringVar(&startOpts.apiserverURL, "apiserver-url", "", "URL for apiserver; Used to generate kubeconfig")
+	startCmd.PersistentFlags().StringSliceVar(&startOpts.nodeIdentity, "node-identity", nil,
+		fmt.Sprintf("Methods machines must prove their identity with to be served configs: %s, %s; Configs are served to anyone if empty", server.NodeIdentityBootstrapToken, server.NodeIdentityClientCertificate))
+	startCmd.PersistentFlags().StringVar(&startOpts.nodeClientCA, "node-client-ca", "", "CA bundle to verify node client certificates with; Required by the client-certificate node identity")
+	startCmd.PersistentFlags().StringVar(&startOpts.metricsAddr, "metrics-listen-address", "", "Address to serve the config request metrics on; Metrics are not served if empty")
+	startCmd.PersistentFlags().StringVar(&startOpts.accessLog, "access-log", "", "File to write a JSON access log of the config requests to, - for stdout; No access log is written if empty")
 
 }
 
@@ -55,13 +71,91 @@ func runStartCmd(_ *cobra.Command, _ []string) {
 	klog.Infof("Launching server with tls min version: %v & cipher suites %v", rootOpts.tlsminversion, rootOpts.tlsciphersuites)
 	tlsConfig := ctrlcommon.GetGoTLSConfig(rootOpts.tlsminversion, rootOpts.tlsciphersuites)
 
-	apiHandler := server.NewServerAPIHandler(cs)
-	secureServer := server.NewAPIServer(apiHandler, rootOpts.sport, false, rootOpts.cert, rootOpts.key, tlsConfig)
+	stopCh := make(chan struct{})
+	authenticators, secureTLSConfig, err := nodeAuthenticators(tlsConfig, stopCh)
+	if err != nil {
+		klog.Exitf("failed to set up node identity: %v", err)
+	}
+
+	apiHandler := server.NewServerAPIHandler(cs, authenticators...)
+	if startOpts.accessLog != "" {
+		accessLog, err := openAccessLog(startOpts.accessLog)
+		if err != nil {
+			klog.Exitf("failed to open access log: %v", err)
+		}
+		apiHandler.WithAccessLog(accessLog)
+	}
+	secureServer := server.NewAPIServer(apiHandler, rootOpts.sport, false, rootOpts.cert, rootOpts.key, secureTLSConfig)
 	insecureServer := server.NewAPIServer(apiHandler, rootOpts.isport, true, "", "", tlsConfig)
 
-	stopCh := make(chan struct{})
+	if startOpts.metricsAddr != "" {
+		go ctrlcommon.StartMetricsListener(startOpts.metricsAddr, stopCh, server.RegisterMCSMetrics, rootOpts.tlsminversion, rootOpts.tlsciphersuites)
+	}
 	go secureServer.Serve()
 	go insecureServer.Serve()
 	<-stopCh
 	panic("not possible")
 }
+
+// nodeAuthenticators returns the authenticators of the node identity methods
+// and the TLS config of the secure server, which verifies node client
+// certificates if they authenticate nodes.
+func nodeAuthenticators(tlsConfig *tls.Config, stopCh <-chan struct{}) ([]server.NodeAuthenticator, *tls.Config, error) {
+	var authenticators []server.NodeAuthenticator
+	if len(startOpts.nodeIdentity) == 0 {
+		return nil, tlsConfig, nil
+	}
+	clientsBuilder, err := clients.NewBuilder(startOpts.kubeconfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Kubernetes rest client: %w", err)
+	}
+	for _, method := range startOpts.nodeIdentity {
+		switch method {
+		case server.NodeIdentityBootstrapToken:
+			authenticators = append(authenticators, server.NewBootstrapTokenAuthenticator(clientsBuilder.KubeClientOrDie("machine-config-server-node-identity")))
+		case server.NodeIdentityClientCertificate:
+			if startOpts.nodeClientCA == "" {
+				return nil, nil, fmt.Errorf("--node-client-ca is required by the %s node identity", method)
+			}
+			caData, err := os.ReadFile(startOpts.nodeClientCA)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read node client CA: %w", err)
+			}
+			clientCAs := x509.NewCertPool()
+			if !clientCAs.AppendCertsFromPEM(caData) {
+				return nil, nil, fmt.Errorf("no certificates found in node client CA %s", startOpts.nodeClientCA)
+			}
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ClientCAs = clientCAs
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			// Nodes can only fetch the config of their pool.
+			mcfgInformerFactory := mcfginformers.NewSharedInformerFactory(clientsBuilder.MachineConfigClientOrDie("machine-config-server-node-identity"), 0)
+			mcpInformer := mcfgInformerFactory.Machineconfiguration().V1().MachineConfigPools()
+			mcpLister := mcpInformer.Lister()
+			mcfgInformerFactory.Start(stopCh)
+			if !cache.WaitForCacheSync(stopCh, mcpInformer.Informer().HasSynced) {
+				return nil, nil, fmt.Errorf("failed to sync machine config pools")
+			}
+			authenticators = append(authenticators, server.NewClientCertificateAuthenticator(clientsBuilder.KubeClientOrDie("machine-config-server-node-identity"), mcpLister))
+		default:
+			return nil, nil, fmt.Errorf("unknown node identity %q", method)
+		}
+	}
+	if len(authenticators) > 0 {
+		klog.Infof("Serving configs to machines authenticated by: %v", startOpts.nodeIdentity)
+	}
+	return authenticators, tlsConfig, nil
+}
+
+// openAccessLog returns the access log writing to path, or to stdout if path
+// is -.
+func openAccessLog(path string) (*server.AccessLog, error) {
+	if path == "-" {
+		return server.NewAccessLog(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewAccessLog(f), nil
+}
@@ -2,59 +2,233 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 
-	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
-
 	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
 	"github.com/openshift/machine-config-operator/test/framework"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
 )
 
+const (
+	outputFormatText string = "text"
+	outputFormatJSON string = "json"
+	outputFormatDyff string = "dyff"
+)
+
 var (
 	diffCmd = &cobra.Command{
-		Use:   "diff",
+		Use:   "diff <from> <to>",
 		Short: "Diffs MachineConfigs",
-		Long:  "",
+		Long: `Shows the semantic differences between two MachineConfigs.
+
+Each MachineConfig is read from a file if one exists at the given path, and
+fetched from the cluster by name otherwise. Files, systemd units and dropins,
+kernel arguments, extensions, the kernel type and the OS image are diffed
+separately, with the Ignition file contents decoded.
+
+Examples:
+  # Diff two rendered configs from the cluster:
+  $ mcdiff diff rendered-worker-1234 rendered-worker-5678
+
+  # Diff two files, attributing each change to the MachineConfigs it comes from:
+  $ mcdiff diff old.yaml new.yaml --attribute --sources-dir ./machineconfigs
+
+  # Diff the whole MachineConfigs with dyff, as mcdiff used to, keeping the
+  # decoded YAML files it compares in the current directory:
+  $ mcdiff diff rendered-worker-1234 rendered-worker-5678 -o dyff --keep-files`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return diffMCs(args)
 		},
 	}
 
 	convertToYAML bool
+	attribute     bool
+	sourcesDir    string
+	outputFormat  string
 	keepFiles     bool
 )
 
 func init() {
 	rootCmd.AddCommand(diffCmd)
 	diffCmd.PersistentFlags().BoolVar(&convertToYAML, "convert-to-yaml", false, "Converts any JSON payloads that are found into YAML before diffing")
-	diffCmd.PersistentFlags().BoolVar(&keepFiles, "keep-files", false, "Keeps the files used for diffing")
+	diffCmd.PersistentFlags().BoolVar(&attribute, "attribute", false, "Attributes each change to the source MachineConfigs in the spec.configuration.source of the rendered config's pool")
+	diffCmd.PersistentFlags().StringVar(&sourcesDir, "sources-dir", "", "Directory of MachineConfig YAML files to attribute changes to, instead of the sources listed by the cluster's pools")
+	diffCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputFormatText, "Output format, one of: text, json, dyff. dyff diffs the whole MachineConfigs with their file contents decoded using the dyff command")
+	diffCmd.PersistentFlags().BoolVar(&keepFiles, "keep-files", false, "Keeps the files used for diffing with dyff in the current directory")
 }
 
 func diffMCs(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("no MachineConfigs given")
+	if outputFormat != outputFormatText && outputFormat != outputFormatJSON && outputFormat != outputFormatDyff {
+		return fmt.Errorf("unsupported output format %q: must be '%s', '%s' or '%s'", outputFormat, outputFormatText, outputFormatJSON, outputFormatDyff)
+	}
+	if keepFiles && outputFormat != outputFormatDyff {
+		return fmt.Errorf("--keep-files only applies to the '%s' output format", outputFormatDyff)
+	}
+
+	loader := &mcLoader{}
+	from, err := loader.load(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := loader.load(args[1])
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputFormatDyff {
+		return dyffMCs(from, to)
+	}
+
+	opts := diffOpts{convertToYAML: convertToYAML}
+	diff, err := diffMachineConfigs(from, to, opts)
+	if err != nil {
+		return err
+	}
+
+	if attribute {
+		fromSources, err := loader.loadSources(from, opts)
+		if err != nil {
+			return err
+		}
+		toSources, err := loader.loadSources(to, opts)
+		if err != nil {
+			return err
+		}
+		if err := attributeChanges(diff, from, to, fromSources, toSources, opts); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == outputFormatJSON {
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	printDiff(os.Stdout, diff)
+	return nil
+}
+
+// mcLoader reads MachineConfigs from files or, failing that, from the cluster.
+// The cluster client is only created when needed so that files can be diffed
+// without cluster access.
+type mcLoader struct {
+	cs *framework.ClientSet
+}
+
+func (l *mcLoader) load(nameOrPath string) (*mcfgv1.MachineConfig, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return readMCFile(nameOrPath)
 	}
 
-	if len(args) == 1 {
-		return fmt.Errorf("only one MachineConfig given")
+	if l.cs == nil {
+		l.cs = framework.NewClientSet("")
 	}
+	return l.cs.MachineConfigs().Get(context.TODO(), nameOrPath, metav1.GetOptions{})
+}
+
+// loadSources loads the source MachineConfigs of a rendered config. These
+// are taken from the spec.configuration.source of the pool which currently
+// targets or has rolled out the rendered config, or, when a sources directory
+// is given, are all of the MachineConfigs in it.
+func (l *mcLoader) loadSources(rendered *mcfgv1.MachineConfig, opts diffOpts) (*sourceItems, error) {
+	var sources []*mcfgv1.MachineConfig
+	if sourcesDir != "" {
+		paths, err := filepath.Glob(filepath.Join(sourcesDir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			source, err := readMCFile(path)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, source)
+		}
+		return newSourceItems(sources, opts)
+	}
+
+	refs, err := l.getSourceRefs(rendered.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		source, err := l.load(ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not load source %s of %s: %w", ref.Name, rendered.Name, err)
+		}
+		sources = append(sources, source)
+	}
+	return newSourceItems(sources, opts)
+}
 
-	cs := framework.NewClientSet("")
+// getSourceRefs returns the source MachineConfigs the cluster's pools list for
+// the given rendered config.
+func (l *mcLoader) getSourceRefs(renderedName string) ([]corev1.ObjectReference, error) {
+	if l.cs == nil {
+		l.cs = framework.NewClientSet("")
+	}
+	pools, err := l.cs.MachineConfigPools().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools.Items {
+		for _, config := range []mcfgv1.MachineConfigPoolStatusConfiguration{pool.Spec.Configuration, pool.Status.Configuration} {
+			if config.Name == renderedName {
+				return config.Source, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no MachineConfigPool lists the sources of %s; use --sources-dir to provide them", renderedName)
+}
 
-	eg := errgroup.Group{}
+func readMCFile(path string) (*mcfgv1.MachineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mc := &mcfgv1.MachineConfig{}
+	if err := yaml.Unmarshal(data, mc); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return mc, nil
+}
 
-	mc1 := args[0]
-	mc2 := args[1]
+func printDiff(w io.Writer, diff *mcDiff) {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(w, "No differences between %s and %s\n", diff.From, diff.To)
+		return
+	}
+	for _, c := range diff.Changes {
+		header := string(c.Kind)
+		if c.Name != "" {
+			header += " " + c.Name
+		}
+		fmt.Fprintf(w, "=== %s (%s)\n", header, c.Action)
+		if len(c.Sources) > 0 {
+			fmt.Fprintf(w, "from: %v\n", c.Sources)
+		}
+		fmt.Fprintln(w, c.Diff)
+	}
+}
 
+// dyffMCs writes both MachineConfigs, with their Ignition config parsed and
+// file contents decoded, to YAML files and diffs them with dyff.
+func dyffMCs(from, to *mcfgv1.MachineConfig) error {
 	dirname := ""
 	if keepFiles {
 		cwd, err := os.Getwd()
@@ -64,7 +238,6 @@ func diffMCs(args []string) error {
 
 		dirname = cwd
 	} else {
-
 		tempdir, err := os.MkdirTemp("", "")
 		if err != nil {
 			return err
@@ -75,32 +248,21 @@ func diffMCs(args []string) error {
 		dirname = tempdir
 	}
 
-	eg.Go(func() error {
-		return getMCAndWriteToFile(cs, dirname, mc1)
-	})
-
-	eg.Go(func() error {
-		return getMCAndWriteToFile(cs, dirname, mc2)
-	})
-
-	if err := eg.Wait(); err != nil {
-		return err
+	for _, mc := range []*mcfgv1.MachineConfig{from, to} {
+		if err := writeMCToFile(dirname, mc); err != nil {
+			return err
+		}
 	}
 
 	klog.Infof("Running dyff command")
-	out := exec.Command("dyff", "between", getMCFilename(dirname, mc1), getMCFilename(dirname, mc2))
+	out := exec.Command("dyff", "between", getMCFilename(dirname, from.Name), getMCFilename(dirname, to.Name))
 	out.Stdout = os.Stdout
 	out.Stderr = os.Stderr
 
 	return out.Run()
 }
 
-func getMCAndWriteToFile(cs *framework.ClientSet, dirname, name string) error {
-	mc, err := cs.MachineConfigs().Get(context.TODO(), name, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
+func writeMCToFile(dirname string, mc *mcfgv1.MachineConfig) error {
 	outBytes, err := yaml.Marshal(mc)
 	if err != nil {
 		return err
@@ -119,7 +281,7 @@ func getMCAndWriteToFile(cs *framework.ClientSet, dirname, name string) error {
 
 	genericized["spec"].(map[string]interface{})["config"] = parsedIgnConfig
 
-	filename := getMCFilename(dirname, name)
+	filename := getMCFilename(dirname, mc.Name)
 
 	outBytes, err = yaml.Marshal(genericized)
 	if err != nil {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMCToFile(t *testing.T) {
+	dir := t.TempDir()
+	mc := newTestMC("rendered-0", []ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "one\ntwo\n")}, nil, nil, nil, "quay.io/os@sha256:0")
+	require.NoError(t, writeMCToFile(dir, mc))
+
+	// The file contents are decoded for dyff to diff them line by line.
+	out, err := os.ReadFile(getMCFilename(dir, mc.Name))
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "source: |\n")
+	assert.Contains(t, string(out), "name: rendered-0\n")
+}
+
+func TestDiffMCsFlags(t *testing.T) {
+	defer func() { outputFormat, keepFiles = outputFormatText, false }()
+
+	outputFormat = "yaml"
+	assert.ErrorContains(t, diffMCs([]string{"a", "b"}), "unsupported output format")
+
+	outputFormat, keepFiles = outputFormatText, true
+	assert.ErrorContains(t, diffMCs([]string{"a", "b"}), "--keep-files only applies to the 'dyff' output format")
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+type changeKind string
+
+const (
+	changeKindFile            changeKind = "file"
+	changeKindUnit            changeKind = "unit"
+	changeKindDropin          changeKind = "dropin"
+	changeKindKernelArguments changeKind = "kernelArguments"
+	changeKindExtensions      changeKind = "extensions"
+	changeKindKernelType      changeKind = "kernelType"
+	changeKindOSImageURL      changeKind = "osImageURL"
+)
+
+type changeAction string
+
+const (
+	changeActionAdded   changeAction = "added"
+	changeActionRemoved changeAction = "removed"
+	changeActionChanged changeAction = "changed"
+)
+
+// change is a single semantic difference between two MachineConfigs.
+type change struct {
+	Kind   changeKind   `json:"kind"`
+	Name   string       `json:"name"`
+	Action changeAction `json:"action"`
+	// Diff is a unified diff of the rendered item.
+	Diff string `json:"diff"`
+	// Sources are the MachineConfigs the change comes from, when attribution
+	// was requested.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// mcDiff is the semantic difference between two MachineConfigs.
+type mcDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []change `json:"changes"`
+}
+
+// diffOpts controls how MachineConfig items are rendered for diffing.
+type diffOpts struct {
+	// convertToYAML converts JSON file contents to YAML.
+	convertToYAML bool
+}
+
+// renderedItems maps an item's kind and name to its rendered text form.
+type renderedItems map[changeKind]map[string]string
+
+func (r renderedItems) add(kind changeKind, name, text string) {
+	if r[kind] == nil {
+		r[kind] = map[string]string{}
+	}
+	r[kind][name] = text
+}
+
+// renderItems renders every item of a MachineConfig that is diffed
+// separately into text, keyed by kind and name.
+func renderItems(mc *mcfgv1.MachineConfig, opts diffOpts) (renderedItems, error) {
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Ignition config of %s: %w", mc.Name, err)
+	}
+
+	items := renderedItems{}
+	for _, file := range ignConfig.Storage.Files {
+		text, err := renderFile(file, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not render file %s of %s: %w", file.Path, mc.Name, err)
+		}
+		items.add(changeKindFile, file.Path, text)
+	}
+	for _, unit := range ignConfig.Systemd.Units {
+		items.add(changeKindUnit, unit.Name, renderUnit(unit))
+		for _, dropin := range unit.Dropins {
+			items.add(changeKindDropin, unit.Name+"/"+dropin.Name, stringValue(dropin.Contents))
+		}
+	}
+	if len(mc.Spec.KernelArguments) > 0 {
+		items.add(changeKindKernelArguments, "", strings.Join(mc.Spec.KernelArguments, "\n")+"\n")
+	}
+	if len(mc.Spec.Extensions) > 0 {
+		items.add(changeKindExtensions, "", strings.Join(mc.Spec.Extensions, "\n")+"\n")
+	}
+	if mc.Spec.KernelType != "" {
+		items.add(changeKindKernelType, "", mc.Spec.KernelType+"\n")
+	}
+	if mc.Spec.OSImageURL != "" {
+		items.add(changeKindOSImageURL, "", mc.Spec.OSImageURL+"\n")
+	}
+	return items, nil
+}
+
+// renderFile renders a file's metadata followed by its decoded contents.
+func renderFile(file ign3types.File, opts diffOpts) (string, error) {
+	var b strings.Builder
+	if file.Mode != nil {
+		fmt.Fprintf(&b, "# mode: %#o\n", *file.Mode)
+	}
+	if file.User.Name != nil {
+		fmt.Fprintf(&b, "# user: %s\n", *file.User.Name)
+	}
+	if file.Group.Name != nil {
+		fmt.Fprintf(&b, "# group: %s\n", *file.Group.Name)
+	}
+
+	contents, err := ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+	if err != nil {
+		return "", err
+	}
+	if opts.convertToYAML {
+		// Contents which are not JSON are diffed as they are.
+		if converted, err := yaml.JSONToYAML(contents); err == nil {
+			contents = converted
+		}
+	}
+	b.Write(contents)
+	return b.String(), nil
+}
+
+// renderUnit renders a unit's state followed by its contents. Dropins are
+// diffed separately.
+func renderUnit(unit ign3types.Unit) string {
+	var b strings.Builder
+	if unit.Enabled != nil {
+		fmt.Fprintf(&b, "# enabled: %t\n", *unit.Enabled)
+	}
+	if unit.Mask != nil {
+		fmt.Fprintf(&b, "# mask: %t\n", *unit.Mask)
+	}
+	b.WriteString(stringValue(unit.Contents))
+	return b.String()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// diffMachineConfigs computes the semantic differences between two
+// MachineConfigs, ordered by kind and name.
+func diffMachineConfigs(from, to *mcfgv1.MachineConfig, opts diffOpts) (*mcDiff, error) {
+	fromItems, err := renderItems(from, opts)
+	if err != nil {
+		return nil, err
+	}
+	toItems, err := renderItems(to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &mcDiff{From: from.Name, To: to.Name, Changes: []change{}}
+	for _, kind := range []changeKind{changeKindOSImageURL, changeKindKernelType, changeKindKernelArguments, changeKindExtensions, changeKindFile, changeKindUnit, changeKindDropin} {
+		names := sets.KeySet(fromItems[kind]).Union(sets.KeySet(toItems[kind]))
+		for _, name := range sets.List(names) {
+			fromText, inFrom := fromItems[kind][name]
+			toText, inTo := toItems[kind][name]
+			if inFrom && inTo && fromText == toText {
+				continue
+			}
+
+			action := changeActionChanged
+			switch {
+			case !inFrom:
+				action = changeActionAdded
+			case !inTo:
+				action = changeActionRemoved
+			}
+
+			label := string(kind)
+			if name != "" {
+				label = name
+			}
+			unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(fromText),
+				B:        difflib.SplitLines(toText),
+				FromFile: from.Name + ":" + label,
+				ToFile:   to.Name + ":" + label,
+				Context:  3,
+			})
+			if err != nil {
+				return nil, err
+			}
+			diff.Changes = append(diff.Changes, change{Kind: kind, Name: name, Action: action, Diff: unified})
+		}
+	}
+	return diff, nil
+}
+
+// sourceItems records which items each source MachineConfig of a rendered
+// config defines.
+type sourceItems struct {
+	names []string
+	items map[string]renderedItems
+}
+
+// newSourceItems renders the given source MachineConfigs of a rendered config.
+func newSourceItems(sources []*mcfgv1.MachineConfig, opts diffOpts) (*sourceItems, error) {
+	s := &sourceItems{items: map[string]renderedItems{}}
+	for _, source := range sources {
+		items, err := renderItems(source, opts)
+		if err != nil {
+			return nil, err
+		}
+		s.names = append(s.names, source.Name)
+		s.items[source.Name] = items
+	}
+	sort.Strings(s.names)
+	return s, nil
+}
+
+// definedBy returns the sources which define the given item. For list items
+// such as kernel arguments, only sources defining one of the given elements
+// are returned.
+func (s *sourceItems) definedBy(kind changeKind, name string, elements sets.Set[string]) []string {
+	var names []string
+	for _, source := range s.names {
+		text, ok := s.items[source][kind][name]
+		if !ok {
+			continue
+		}
+		if elements != nil && !elements.HasAny(strings.Split(strings.TrimSpace(text), "\n")...) {
+			continue
+		}
+		names = append(names, source)
+	}
+	return names
+}
+
+// providing returns the sources which define the given item as it appears in
+// the rendered config. When sources override each other, that is the one whose
+// definition won; if none match exactly, all sources defining the item are
+// returned.
+func (s *sourceItems) providing(kind changeKind, name, text string) []string {
+	defining := s.definedBy(kind, name, nil)
+	var matching []string
+	for _, source := range defining {
+		if s.items[source][kind][name] == text {
+			matching = append(matching, source)
+		}
+	}
+	if len(matching) == 0 {
+		return defining
+	}
+	return matching
+}
+
+// attributeChanges sets the sources of each change: items present in the new
+// config are attributed to the new config's sources defining them, removed
+// items to the old config's sources.
+func attributeChanges(diff *mcDiff, from, to *mcfgv1.MachineConfig, fromSources, toSources *sourceItems, opts diffOpts) error {
+	fromItems, err := renderItems(from, opts)
+	if err != nil {
+		return err
+	}
+	toItems, err := renderItems(to, opts)
+	if err != nil {
+		return err
+	}
+
+	for i := range diff.Changes {
+		c := &diff.Changes[i]
+		switch c.Kind {
+		case changeKindKernelArguments, changeKindExtensions:
+			// Attribute each added element to the sources of the new config and
+			// each removed element to the sources of the old config.
+			fromElements := listElements(fromItems[c.Kind][c.Name])
+			toElements := listElements(toItems[c.Kind][c.Name])
+			sources := sets.New(toSources.definedBy(c.Kind, c.Name, toElements.Difference(fromElements))...)
+			sources.Insert(fromSources.definedBy(c.Kind, c.Name, fromElements.Difference(toElements))...)
+			c.Sources = sets.List(sources)
+		case changeKindFile, changeKindUnit, changeKindDropin, changeKindKernelType, changeKindOSImageURL:
+			if c.Action == changeActionRemoved {
+				c.Sources = fromSources.providing(c.Kind, c.Name, fromItems[c.Kind][c.Name])
+			} else {
+				c.Sources = toSources.providing(c.Kind, c.Name, toItems[c.Kind][c.Name])
+			}
+		}
+	}
+	return nil
+}
+
+func listElements(text string) sets.Set[string] {
+	elements := sets.New[string]()
+	for _, element := range strings.Split(text, "\n") {
+		if element != "" {
+			elements.Insert(element)
+		}
+	}
+	return elements
+}
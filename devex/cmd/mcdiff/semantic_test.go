@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func newTestMC(name string, files []ign3types.File, units []ign3types.Unit, kargs, extensions []string, osImageURL string) *mcfgv1.MachineConfig {
+	return helpers.NewMachineConfigExtended(name, nil, nil, files, units, []ign3types.SSHAuthorizedKey{}, extensions, false, kargs, "", osImageURL)
+}
+
+func TestDiffMachineConfigs(t *testing.T) {
+	unit := ign3types.Unit{
+		Name:     "foo.service",
+		Contents: ptr.To("[Unit]\n"),
+		Dropins:  []ign3types.Dropin{{Name: "10-foo.conf", Contents: ptr.To("[Service]\n")}},
+	}
+	enabledUnit := unit
+	enabledUnit.Enabled = ptr.To(true)
+	enabledUnit.Dropins = nil
+
+	from := newTestMC("rendered-0",
+		[]ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "one\ntwo\n"), ctrlcommon.NewIgnFile("/etc/removed", "gone\n")},
+		[]ign3types.Unit{unit}, []string{"foo=1", "bar"}, nil, "quay.io/os@sha256:0")
+	to := newTestMC("rendered-1",
+		[]ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "one\nthree\n"), ctrlcommon.NewIgnFile("/etc/added", "new\n")},
+		[]ign3types.Unit{enabledUnit}, []string{"foo=2", "bar"}, []string{"usbguard"}, "quay.io/os@sha256:0")
+
+	diff, err := diffMachineConfigs(from, to, diffOpts{})
+	require.NoError(t, err)
+
+	type summary struct {
+		kind   changeKind
+		name   string
+		action changeAction
+	}
+	var got []summary
+	for _, c := range diff.Changes {
+		got = append(got, summary{c.Kind, c.Name, c.Action})
+	}
+	assert.Equal(t, []summary{
+		{changeKindKernelArguments, "", changeActionChanged},
+		{changeKindExtensions, "", changeActionAdded},
+		{changeKindFile, "/etc/a", changeActionChanged},
+		{changeKindFile, "/etc/added", changeActionAdded},
+		{changeKindFile, "/etc/removed", changeActionRemoved},
+		{changeKindUnit, "foo.service", changeActionChanged},
+		{changeKindDropin, "foo.service/10-foo.conf", changeActionRemoved},
+	}, got)
+
+	assert.Equal(t, `--- rendered-0:/etc/a
++++ rendered-1:/etc/a
+@@ -1,4 +1,4 @@
+ # mode: 0644
+ one
+-two
++three
+ 
+`, diff.Changes[2].Diff)
+	assert.Contains(t, diff.Changes[5].Diff, "+# enabled: true\n")
+
+	diff, err = diffMachineConfigs(from, from, diffOpts{})
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changes)
+}
+
+func TestRenderFileConvertToYAML(t *testing.T) {
+	file := ctrlcommon.NewIgnFile("/etc/config.json", `{"key":"value"}`)
+
+	text, err := renderFile(file, diffOpts{convertToYAML: true})
+	require.NoError(t, err)
+	assert.Equal(t, "# mode: 0644\nkey: value\n", text)
+
+	file = ctrlcommon.NewIgnFile("/etc/plain", "not: [json")
+	text, err = renderFile(file, diffOpts{convertToYAML: true})
+	require.NoError(t, err)
+	assert.Equal(t, "# mode: 0644\nnot: [json", text)
+}
+
+func TestAttributeChanges(t *testing.T) {
+	base := newTestMC("00-worker", []ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "one\n")}, nil, []string{"foo=1"}, nil, "quay.io/os@sha256:0")
+	custom := newTestMC("99-custom", []ign3types.File{ctrlcommon.NewIgnFile("/etc/custom", "custom\n")}, nil, []string{"custom"}, nil, "")
+	override := newTestMC("99-override", []ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "two\n")}, nil, nil, nil, "")
+
+	from := newTestMC("rendered-0", []ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "one\n")}, nil, []string{"foo=1"}, nil, "quay.io/os@sha256:0")
+	to := newTestMC("rendered-1", []ign3types.File{ctrlcommon.NewIgnFile("/etc/a", "two\n"), ctrlcommon.NewIgnFile("/etc/custom", "custom\n")},
+		nil, []string{"foo=1", "custom"}, nil, "quay.io/os@sha256:0")
+
+	opts := diffOpts{}
+	diff, err := diffMachineConfigs(from, to, opts)
+	require.NoError(t, err)
+
+	fromSources, err := newSourceItems([]*mcfgv1.MachineConfig{base}, opts)
+	require.NoError(t, err)
+	toSources, err := newSourceItems([]*mcfgv1.MachineConfig{base, custom, override}, opts)
+	require.NoError(t, err)
+	require.NoError(t, attributeChanges(diff, from, to, fromSources, toSources, opts))
+
+	sources := map[string][]string{}
+	for _, c := range diff.Changes {
+		sources[string(c.Kind)+" "+c.Name] = c.Sources
+	}
+	assert.Equal(t, map[string][]string{
+		"kernelArguments ": {"99-custom"},
+		"file /etc/a":      {"99-override"},
+		"file /etc/custom": {"99-custom"},
+	}, sources)
+}
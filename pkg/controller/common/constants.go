@@ -27,12 +27,142 @@ const (
 	// ReleaseImageVersionAnnotationKey is used to tag the rendered machineconfigs & controller config with the release image version.
 	ReleaseImageVersionAnnotationKey = "machineconfiguration.openshift.io/release-image-version"
 
+	// ProvenanceAnnotationKey is set on rendered MachineConfigs to a JSON encoded MachineConfigProvenance recording
+	// which source MachineConfig each file and systemd unit of the rendered config comes from. It is left out when
+	// too large to be stored.
+	ProvenanceAnnotationKey = "machineconfiguration.openshift.io/provenance"
+
 	// OSImageURLOverriddenKey is used to tag a rendered machineconfig when OSImageURL has been overridden from default using machineconfig
 	OSImageURLOverriddenKey = "machineconfiguration.openshift.io/os-image-url-overridden"
 
 	// RenderedMachineConfigPrefix is the name prefix for rendered MachineConfigs
 	RenderedMachineConfigPrefix = "rendered-"
 
+	// RenderedConfigRetentionAnnotationKey is set on a MachineConfigPool to override how many of the most recent
+	// unreferenced rendered MachineConfigs are kept when the render controller garbage collects the pool's rendered configs.
+	RenderedConfigRetentionAnnotationKey = "machineconfiguration.openshift.io/rendered-config-retention"
+
+	// UpdateStrategyAnnotationKey is set on a MachineConfigPool to choose the order in which the node controller
+	// moves the pool's nodes to a new config. See the node controller for the supported values.
+	UpdateStrategyAnnotationKey = "machineconfiguration.openshift.io/update-strategy"
+
+	// UpdateOrderAnnotationKey is set on a MachineConfigPool using the "Ordered" update strategy to a comma-separated
+	// list of node names, which are updated first and in the given order.
+	UpdateOrderAnnotationKey = "machineconfiguration.openshift.io/update-order"
+
+	// UpdatePriorityLabelAnnotationKey is set on a MachineConfigPool using the "NodeLabelPriority" update strategy to
+	// override the node label holding each node's update priority. Defaults to UpdatePriorityNodeLabel.
+	UpdatePriorityLabelAnnotationKey = "machineconfiguration.openshift.io/update-priority-label"
+
+	// UpdatePriorityNodeLabel is the default node label holding an integer update priority; lower values update first.
+	UpdatePriorityNodeLabel = "machineconfiguration.openshift.io/update-priority"
+
+	// MaintenanceWindowAnnotationKey is set on a MachineConfigPool to restrict when the node controller may start
+	// updating nodes to a new config. The value is a semicolon-separated list of windows such as
+	// "Sat 02:00-06:00 UTC" or "Mon-Fri 22:00-04:00 Europe/Berlin".
+	MaintenanceWindowAnnotationKey = "machineconfiguration.openshift.io/maintenance-window"
+
+	// MachineConfigPoolMaintenanceWindow is the MachineConfigPool condition reporting whether the pool's
+	// maintenance window is currently open. It is only set on pools with a maintenance window.
+	MachineConfigPoolMaintenanceWindow = "MaintenanceWindow"
+
+	// CanaryNodesAnnotationKey is set on a MachineConfigPool to the number of nodes that are updated to a new config
+	// first. The rest of the pool only starts updating once these canaries have updated, soaked and passed their
+	// health checks.
+	CanaryNodesAnnotationKey = "machineconfiguration.openshift.io/canary-nodes"
+
+	// CanarySoakPeriodAnnotationKey is set on a MachineConfigPool to how long (e.g. "30m") the canary nodes must stay
+	// healthy after updating before the rest of the pool is updated. Defaults to 10 minutes.
+	CanarySoakPeriodAnnotationKey = "machineconfiguration.openshift.io/canary-soak-period"
+
+	// CanaryHealthQueriesAnnotationKey is set on a MachineConfigPool to a newline-separated list of Prometheus-style
+	// queries. The canary stage fails if any of them returns a result. "$canaryNodes" is replaced with a regular
+	// expression matching the names of the canary nodes. The queries run against the cluster monitoring Thanos querier.
+	CanaryHealthQueriesAnnotationKey = "machineconfiguration.openshift.io/canary-health-queries"
+
+	// MachineConfigPoolCanaryVerified is the MachineConfigPool condition reporting the state of the pool's canary
+	// stage. It is only set on pools with canary nodes.
+	MachineConfigPoolCanaryVerified = "CanaryVerified"
+
+	// RollbackDegradedNodesAnnotationKey is set on a MachineConfigPool to the number of nodes that may go degraded
+	// applying the same rendered MachineConfig before the node controller rolls the pool back to the last config all
+	// of its nodes reached. Automatic rollback is disabled if unset.
+	RollbackDegradedNodesAnnotationKey = "machineconfiguration.openshift.io/rollback-degraded-nodes"
+
+	// RolledBackFromAnnotationKey is set by the node controller on a MachineConfigPool it rolled back, to the rendered
+	// MachineConfig it rolled back from. The pool is not moved to that config again; the annotation is cleared once
+	// the pool targets a different config, or may be removed by an administrator to retry the rollout.
+	RolledBackFromAnnotationKey = "machineconfiguration.openshift.io/rolled-back-from"
+
+	// RollbackReasonAnnotationKey is set by the node controller alongside RolledBackFromAnnotationKey to explain why
+	// the pool was rolled back.
+	RollbackReasonAnnotationKey = "machineconfiguration.openshift.io/rollback-reason"
+
+	// MachineConfigPoolRolledBack is the MachineConfigPool condition reporting that the node controller rolled the
+	// pool back from its rendered MachineConfig. It is only set while the rollback is in effect.
+	MachineConfigPoolRolledBack = "RolledBack"
+
+	// StrictConflictsAnnotationKey is set to "true" on a MachineConfigPool to make the render controller refuse to
+	// render the pool while its user MachineConfigs define the same file, unit or dropin differently, or a MachineConfig
+	// generated by the controllers overrides a user one, instead of letting the MachineConfig merged last take
+	// precedence. User MachineConfigs overriding generated ones are only reported.
+	StrictConflictsAnnotationKey = "machineconfiguration.openshift.io/strict-conflicts"
+
+	// MachineConfigPoolConflictingMachineConfigs is the MachineConfigPool condition reporting that several of the
+	// pool's MachineConfigs, at least one of them a user MachineConfig, define the same file, unit or dropin
+	// differently. It is only set while there are conflicts.
+	MachineConfigPoolConflictingMachineConfigs = "ConflictingMachineConfigs"
+
+	// ConfigDriftPolicyAnnotationKey is set on a MachineConfigPool to choose what the daemon does when the on-disk
+	// state of a node drifts from its current config: "Report" only emits an event, "Degrade" (the default) marks the
+	// node degraded and "Restore" rewrites the drifted files and units and runs their node disruption policy actions.
+	ConfigDriftPolicyAnnotationKey = "machineconfiguration.openshift.io/config-drift-policy"
+
+	// HealthProbesAnnotationKey is set on a MachineConfigPool to a JSON list of health probes the daemon runs on each
+	// node of the pool once it rebooted or otherwise finished applying an update, but not when the daemon merely
+	// restarts. A node is only marked done with its update once all of its probes pass. See the daemon for the
+	// supported probe types.
+	HealthProbesAnnotationKey = "machineconfiguration.openshift.io/health-probes"
+
+	// PreUpdateHookURLAnnotationKey is set on a MachineConfigPool to the URL of an HTTP pre-update hook. Before draining
+	// each node of the pool for an update, the daemon POSTs the update to it and lets it proceed with, delay or reject
+	// the update. HTTPS hooks may be served with a certificate signed by the service CA.
+	PreUpdateHookURLAnnotationKey = "machineconfiguration.openshift.io/pre-update-hook-url"
+
+	// PrefetchMaxConcurrentPullsAnnotationKey is set on a MachineConfigPool to the maximum number of images each node
+	// of the pool pulls at once when prefetching its PinnedImageSets. It can only lower the number of prefetch workers
+	// the daemon runs.
+	PrefetchMaxConcurrentPullsAnnotationKey = "machineconfiguration.openshift.io/prefetch-max-concurrent-pulls"
+
+	// PrefetchMaxBytesPerSecondAnnotationKey is set on a MachineConfigPool to a quantity, e.g. "50Mi", limiting the
+	// rate at which each node of the pool starts pulling the images of its PinnedImageSets, by their compressed size.
+	// This is an admission rate, not a bandwidth cap: a pull which has started runs as fast as the network allows, so
+	// the rate only holds on average over many images. Use it with PrefetchMaxConcurrentPullsAnnotationKey to bound
+	// the bandwidth used at any time.
+	PrefetchMaxBytesPerSecondAnnotationKey = "machineconfiguration.openshift.io/prefetch-max-bytes-per-second"
+
+	// PrefetchMaxNodesAnnotationKey is set on the cluster MachineConfiguration to the maximum number of nodes in the
+	// cluster which prefetch PinnedImageSet images at once. Nodes wait for the pinned image set controller to grant
+	// them a slot before pulling. Unset or "0" does not limit the number of nodes.
+	PrefetchMaxNodesAnnotationKey = "machineconfiguration.openshift.io/prefetch-max-nodes"
+
+	// NewNodeConfigPolicyAnnotationKey is set on a MachineConfigPool to choose which of its rendered MachineConfigs the
+	// machine-config-server serves to new nodes while the pool rolls out a new config: "Current" always serves the
+	// config the pool's nodes are on, "Target" always serves the config the pool is moving to and "TargetAfterUpdated"
+	// (the default) serves the config the pool is moving to once enough of its nodes updated to it.
+	NewNodeConfigPolicyAnnotationKey = "machineconfiguration.openshift.io/new-node-config-policy"
+
+	// NewNodeConfigUpdatedPercentAnnotationKey is set on a MachineConfigPool using the "TargetAfterUpdated" new node
+	// config policy to the percentage of the pool's nodes which must have updated before new nodes are served the
+	// config the pool is moving to. Defaults to at least one node.
+	NewNodeConfigUpdatedPercentAnnotationKey = "machineconfiguration.openshift.io/new-node-config-updated-percent"
+
+	// NodeIdentityAnnotationKey is set on the cluster MachineConfiguration to a comma separated list of the methods
+	// machines must prove their identity with before the machine-config-server serves them a config:
+	// "bootstrap-token" and "client-certificate". With "bootstrap-token" the operator mints a token for each pool and
+	// embeds it in the pool's managed stub user-data. Unset or empty serves configs to anyone.
+	NodeIdentityAnnotationKey = "machineconfiguration.openshift.io/node-identity"
+
 	// ControllerConfigName is the name of the ControllerConfig object that controllers use
 	ControllerConfigName = "machine-config-controller"
 
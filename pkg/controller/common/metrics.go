@@ -84,6 +84,13 @@ var (
 			Help: "total number of degraded machines in specified pool",
 		}, []string{"pool"})
 
+	// MCCRenderedConfigsGarbageCollected counts the rendered MachineConfigs deleted by the render controller
+	MCCRenderedConfigsGarbageCollected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mcc_rendered_configs_garbage_collected_total",
+			Help: "total number of rendered MachineConfigs garbage collected for a specified pool",
+		}, []string{"pool"})
+
 	// MCCUnavailableMachineCount is the unavailable machines in the pool
 	MCCUnavailableMachineCount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -104,6 +111,7 @@ func RegisterMCCMetrics() error {
 		MCCDegradedMachineCount,
 		MCCUnavailableMachineCount,
 		MCCBootImageSkewEnforcementNone,
+		MCCRenderedConfigsGarbageCollected,
 	})
 
 	if err != nil {
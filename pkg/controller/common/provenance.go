@@ -0,0 +1,284 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxProvenanceAnnotationSize is the size above which the provenance of a
+// rendered MachineConfig is not recorded, so that it leaves room for the
+// other annotations within the 256KiB the API server allows for all of them.
+const maxProvenanceAnnotationSize = 128 * 1024
+
+// MachineConfigProvenance records which source MachineConfig each file and
+// systemd unit of a rendered MachineConfig comes from. When several source
+// MachineConfigs define the same item, the one merged last wins and is
+// recorded. A unit is attributed to the MachineConfig defining its contents,
+// not to those which only enable, disable or mask it.
+type MachineConfigProvenance struct {
+	// Files maps a file path to the MachineConfig it comes from.
+	Files map[string]string `json:"files,omitempty"`
+	// Units maps a systemd unit name to the MachineConfig it comes from.
+	// Dropins are recorded as "<unit>.d/<dropin>", their path relative to
+	// the systemd unit directory.
+	Units map[string]string `json:"units,omitempty"`
+}
+
+// GetMachineConfigProvenance computes the provenance of the rendered
+// MachineConfig that MergeMachineConfigs produces from configs.
+func GetMachineConfigProvenance(configs []*mcfgv1.MachineConfig) (*MachineConfigProvenance, error) {
+	sorted := make([]*mcfgv1.MachineConfig, len(configs))
+	copy(sorted, configs)
+	if err := sortMachineConfigsForMerge(sorted); err != nil {
+		return nil, err
+	}
+
+	provenance := &MachineConfigProvenance{
+		Files: map[string]string{},
+		Units: map[string]string{},
+	}
+	for _, config := range sorted {
+		if config.Spec.Config.Raw == nil {
+			continue
+		}
+		ignConfig, err := ParseAndConvertConfig(config.Spec.Config.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Ignition config of %s failed: %w", config.Name, err)
+		}
+		for _, file := range ignConfig.Storage.Files {
+			provenance.Files[file.Path] = config.Name
+		}
+		for _, unit := range ignConfig.Systemd.Units {
+			// Units without contents are only attributed to the config
+			// enabling or masking them if no config defines their contents.
+			if _, ok := provenance.Units[unit.Name]; unit.Contents != nil || !ok {
+				provenance.Units[unit.Name] = config.Name
+			}
+			for _, dropin := range unit.Dropins {
+				provenance.Units[unit.Name+".d/"+dropin.Name] = config.Name
+			}
+		}
+	}
+	return provenance, nil
+}
+
+// SetMachineConfigProvenanceAnnotation records the provenance of the rendered
+// MachineConfig merged from configs on it. The provenance is left out if it is
+// too large to be stored in an annotation.
+func SetMachineConfigProvenanceAnnotation(rendered *mcfgv1.MachineConfig, configs []*mcfgv1.MachineConfig) error {
+	provenance, err := GetMachineConfigProvenance(configs)
+	if err != nil {
+		return err
+	}
+	provenanceJSON, err := json.Marshal(provenance)
+	if err != nil {
+		return err
+	}
+	if len(provenanceJSON) > maxProvenanceAnnotationSize {
+		klog.Warningf("Not recording the provenance of %s: at %d bytes it exceeds the %d bytes allowed", rendered.Name, len(provenanceJSON), maxProvenanceAnnotationSize)
+		delete(rendered.Annotations, ProvenanceAnnotationKey)
+		return nil
+	}
+	metav1.SetMetaDataAnnotation(&rendered.ObjectMeta, ProvenanceAnnotationKey, string(provenanceJSON))
+	return nil
+}
+
+// GetMachineConfigProvenanceFromAnnotation returns the provenance recorded on
+// a rendered MachineConfig, or nil if it has none.
+func GetMachineConfigProvenanceFromAnnotation(mc *mcfgv1.MachineConfig) (*MachineConfigProvenance, error) {
+	value, ok := mc.Annotations[ProvenanceAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	provenance := &MachineConfigProvenance{}
+	if err := json.Unmarshal([]byte(value), provenance); err != nil {
+		return nil, fmt.Errorf("could not parse %s annotation of %s: %w", ProvenanceAnnotationKey, mc.Name, err)
+	}
+	return provenance, nil
+}
+
+// FileSource returns the MachineConfig the given file path comes from, or an
+// empty string if it is unknown.
+func (p *MachineConfigProvenance) FileSource(path string) string {
+	if p == nil {
+		return ""
+	}
+	return p.Files[path]
+}
+
+// UnitSource returns the MachineConfig the given unit or dropin comes from, or
+// an empty string if it is unknown.
+func (p *MachineConfigProvenance) UnitSource(name string) string {
+	if p == nil {
+		return ""
+	}
+	return p.Units[name]
+}
+
+// MachineConfigConflict is a file, systemd unit or dropin that several source
+// MachineConfigs define differently. Only the definition of the MachineConfig
+// merged last ends up in the rendered MachineConfig.
+type MachineConfigConflict struct {
+	// Kind is one of "file", "unit" or "dropin".
+	Kind string
+	// Name is the file path, unit name or "<unit>.d/<dropin>".
+	Name string
+	// MachineConfigs are the MachineConfigs defining the item, in merge
+	// order; the last one wins.
+	MachineConfigs []string
+	// OverridesGenerated is set when a user MachineConfig, merged last, only
+	// overrides the definitions of MachineConfigs generated by the
+	// controllers. That is how generated configuration is customized, so the
+	// conflict is only informational.
+	OverridesGenerated bool
+}
+
+func (c MachineConfigConflict) String() string {
+	last := c.MachineConfigs[len(c.MachineConfigs)-1]
+	if c.OverridesGenerated {
+		return fmt.Sprintf("%s %s is defined differently by %s; %s overrides the generated definition",
+			c.Kind, c.Name, strings.Join(c.MachineConfigs, ", "), last)
+	}
+	return fmt.Sprintf("%s %s is defined differently by %s; %s takes precedence",
+		c.Kind, c.Name, strings.Join(c.MachineConfigs, ", "), last)
+}
+
+// GetMachineConfigConflicts returns the files, units and dropins that
+// MergeMachineConfigs would merge from differing definitions in configs.
+// Identical definitions, and units which are only enabled, disabled or masked
+// by several MachineConfigs, are not conflicts. Neither are items only
+// MachineConfigs generated by the controllers define, as overriding the
+// templates is what they are for. A user MachineConfig overriding generated
+// ones is reported as OverridesGenerated, unless user MachineConfigs also
+// disagree among themselves or a generated MachineConfig overrides a user one.
+func GetMachineConfigConflicts(configs []*mcfgv1.MachineConfig) ([]MachineConfigConflict, error) {
+	sorted := make([]*mcfgv1.MachineConfig, len(configs))
+	copy(sorted, configs)
+	if err := sortMachineConfigsForMerge(sorted); err != nil {
+		return nil, err
+	}
+
+	type definition struct {
+		configs   []string
+		values    []interface{}
+		generated []bool
+	}
+	definitions := map[string]*definition{}
+	var keys []string
+	define := func(kind, name string, value interface{}, config *mcfgv1.MachineConfig) {
+		key := kind + " " + name
+		d, ok := definitions[key]
+		if !ok {
+			d = &definition{}
+			definitions[key] = d
+			keys = append(keys, key)
+		}
+		d.configs = append(d.configs, config.Name)
+		d.values = append(d.values, value)
+		d.generated = append(d.generated, isGeneratedMachineConfig(config))
+	}
+
+	for _, config := range sorted {
+		if config.Spec.Config.Raw == nil {
+			continue
+		}
+		ignConfig, err := ParseAndConvertConfig(config.Spec.Config.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Ignition config of %s failed: %w", config.Name, err)
+		}
+		for _, file := range ignConfig.Storage.Files {
+			define("file", file.Path, newFileDefinition(file), config)
+		}
+		for _, unit := range ignConfig.Systemd.Units {
+			if unit.Contents != nil {
+				define("unit", unit.Name, *unit.Contents, config)
+			}
+			for _, dropin := range unit.Dropins {
+				contents := ""
+				if dropin.Contents != nil {
+					contents = *dropin.Contents
+				}
+				define("dropin", unit.Name+".d/"+dropin.Name, contents, config)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	var conflicts []MachineConfigConflict
+	for _, key := range keys {
+		d := definitions[key]
+		last := len(d.values) - 1
+		var userValue interface{}
+		hasUser, userConflict, differs := false, false, false
+		for i, value := range d.values {
+			if value != d.values[last] {
+				differs = true
+			}
+			if d.generated[i] {
+				continue
+			}
+			if !hasUser {
+				hasUser, userValue = true, value
+			} else if value != userValue {
+				userConflict = true
+			}
+		}
+		if !hasUser || !differs {
+			continue
+		}
+		kind, name, _ := strings.Cut(key, " ")
+		conflicts = append(conflicts, MachineConfigConflict{
+			Kind:               kind,
+			Name:               name,
+			MachineConfigs:     d.configs,
+			OverridesGenerated: !userConflict && !d.generated[last],
+		})
+	}
+	return conflicts, nil
+}
+
+// isGeneratedMachineConfig returns whether a MachineConfig was generated by
+// one of the controllers rather than created by a user.
+func isGeneratedMachineConfig(config *mcfgv1.MachineConfig) bool {
+	if _, ok := config.Annotations[GeneratedByControllerVersionAnnotationKey]; ok {
+		return true
+	}
+	return metav1.GetControllerOf(config) != nil
+}
+
+// fileDefinition is what makes two definitions of a file the same: the same
+// contents written with the same mode, however the contents are encoded.
+type fileDefinition struct {
+	contents string
+	mode     int
+}
+
+func newFileDefinition(file ign3types.File) fileDefinition {
+	// Ignition writes files without a mode as 0644.
+	def := fileDefinition{mode: 0o644}
+	if file.Mode != nil {
+		def.mode = *file.Mode
+	}
+	decoded, err := DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+	if err != nil {
+		// Compare contents which cannot be decoded as they are encoded.
+		def.contents = fmt.Sprintf("%s %s", stringValue(file.Contents.Compression), stringValue(file.Contents.Source))
+		return def
+	}
+	def.contents = string(decoded)
+	return def
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetMachineConfigProvenance(t *testing.T) {
+	newMC := func(name, role string, files []ign3types.File, units []ign3types.Unit) *mcfgv1.MachineConfig {
+		rawIgn, err := json.Marshal(ign3types.Config{
+			Ignition: ign3types.Ignition{Version: ign3types.MaxVersion.String()},
+			Storage:  ign3types.Storage{Files: files},
+			Systemd:  ign3types.Systemd{Units: units},
+		})
+		require.NoError(t, err)
+		return &mcfgv1.MachineConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{MachineConfigRoleLabel: role}},
+			Spec:       mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: rawIgn}},
+		}
+	}
+	contents := "[Unit]\n"
+	enabled := true
+
+	configs := []*mcfgv1.MachineConfig{
+		// Custom pool configs take priority over worker configs, regardless of name.
+		newMC("00-infra", "infra", []ign3types.File{NewIgnFile("/etc/shared", "infra")}, nil),
+		newMC("99-worker", MachineConfigPoolWorker, []ign3types.File{NewIgnFile("/etc/shared", "worker"), NewIgnFile("/etc/worker", "worker")}, nil),
+		newMC("50-units", MachineConfigPoolWorker, nil, []ign3types.Unit{{
+			Name:     "foo.service",
+			Contents: &contents,
+			Dropins:  []ign3types.Dropin{{Name: "10-foo.conf", Contents: &contents}},
+		}}),
+		// Enabling units keeps them attributed to the config defining them.
+		newMC("60-enable", MachineConfigPoolWorker, nil, []ign3types.Unit{{Name: "foo.service", Enabled: &enabled}, {Name: "bar.service", Enabled: &enabled}}),
+		newMC("70-enable", MachineConfigPoolWorker, nil, []ign3types.Unit{{Name: "bar.service", Enabled: &enabled}}),
+		{ObjectMeta: metav1.ObjectMeta{Name: "01-empty", Labels: map[string]string{MachineConfigRoleLabel: MachineConfigPoolWorker}}},
+	}
+
+	provenance, err := GetMachineConfigProvenance(configs)
+	require.NoError(t, err)
+	assert.Equal(t, &MachineConfigProvenance{
+		Files: map[string]string{"/etc/shared": "00-infra", "/etc/worker": "99-worker"},
+		Units: map[string]string{"foo.service": "50-units", "foo.service.d/10-foo.conf": "50-units", "bar.service": "60-enable"},
+	}, provenance)
+
+	// The given configs are left in their original order.
+	assert.Equal(t, "00-infra", configs[0].Name)
+
+	var nilProvenance *MachineConfigProvenance
+	assert.Equal(t, "", nilProvenance.FileSource("/etc/shared"))
+	assert.Equal(t, "", nilProvenance.UnitSource("foo.service"))
+}
+
+func TestSetMachineConfigProvenanceAnnotation(t *testing.T) {
+	newMC := func(files []ign3types.File) *mcfgv1.MachineConfig {
+		rawIgn, err := json.Marshal(ign3types.Config{
+			Ignition: ign3types.Ignition{Version: ign3types.MaxVersion.String()},
+			Storage:  ign3types.Storage{Files: files},
+		})
+		require.NoError(t, err)
+		return &mcfgv1.MachineConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "99-worker", Labels: map[string]string{MachineConfigRoleLabel: MachineConfigPoolWorker}},
+			Spec:       mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: rawIgn}},
+		}
+	}
+
+	rendered := &mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{Name: "rendered-worker"}}
+	require.NoError(t, SetMachineConfigProvenanceAnnotation(rendered, []*mcfgv1.MachineConfig{newMC([]ign3types.File{NewIgnFile("/etc/foo", "foo")})}))
+	provenance, err := GetMachineConfigProvenanceFromAnnotation(rendered)
+	require.NoError(t, err)
+	assert.Equal(t, "99-worker", provenance.FileSource("/etc/foo"))
+
+	// Provenance too large for an annotation is left out.
+	var files []ign3types.File
+	for i := 0; len(files)*30 < maxProvenanceAnnotationSize; i++ {
+		files = append(files, NewIgnFile(fmt.Sprintf("/etc/many/file-%d", i), ""))
+	}
+	require.NoError(t, SetMachineConfigProvenanceAnnotation(rendered, []*mcfgv1.MachineConfig{newMC(files)}))
+	assert.NotContains(t, rendered.Annotations, ProvenanceAnnotationKey)
+}
+
+func TestGetMachineConfigConflicts(t *testing.T) {
+	newMC := func(name string, files []ign3types.File, units []ign3types.Unit) *mcfgv1.MachineConfig {
+		rawIgn, err := json.Marshal(ign3types.Config{
+			Ignition: ign3types.Ignition{Version: ign3types.MaxVersion.String()},
+			Storage:  ign3types.Storage{Files: files},
+			Systemd:  ign3types.Systemd{Units: units},
+		})
+		require.NoError(t, err)
+		return &mcfgv1.MachineConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{MachineConfigRoleLabel: MachineConfigPoolWorker}},
+			Spec:       mcfgv1.MachineConfigSpec{Config: runtime.RawExtension{Raw: rawIgn}},
+		}
+	}
+	unitContents := "[Unit]\n"
+	otherUnitContents := "[Unit]\nDescription=other\n"
+	enabled := true
+
+	configs := []*mcfgv1.MachineConfig{
+		newMC("99-override", []ign3types.File{NewIgnFile("/etc/conflict", "override"), NewIgnFile("/etc/same", "same")},
+			[]ign3types.Unit{{Name: "foo.service", Contents: &otherUnitContents}, {Name: "bar.service", Enabled: &enabled}}),
+		newMC("00-base", []ign3types.File{NewIgnFile("/etc/conflict", "base"), NewIgnFile("/etc/same", "same")},
+			[]ign3types.Unit{
+				{Name: "foo.service", Contents: &unitContents, Dropins: []ign3types.Dropin{{Name: "10-foo.conf", Contents: &unitContents}}},
+				{Name: "bar.service", Contents: &unitContents},
+			}),
+		newMC("50-dropin", nil, []ign3types.Unit{{Name: "foo.service", Dropins: []ign3types.Dropin{{Name: "10-foo.conf"}}}}),
+		// The same contents encoded differently and without the default mode
+		// are the same definition, but a different mode is not.
+		newMC("60-encoded", []ign3types.File{
+			{Node: ign3types.Node{Path: "/etc/same"}, FileEmbedded1: ign3types.FileEmbedded1{Contents: ign3types.Resource{Source: ptr.To("data:text/plain;charset=utf-8;base64,c2FtZQ==")}}},
+			{Node: ign3types.Node{Path: "/etc/mode"}, FileEmbedded1: ign3types.FileEmbedded1{Mode: ptr.To(0o755), Contents: ign3types.Resource{Source: ptr.To("data:,mode")}}},
+		}, nil),
+		newMC("70-mode", []ign3types.File{NewIgnFile("/etc/mode", "mode"), NewIgnFile("/etc/generated-wins", "user")}, nil),
+	}
+	// Items only MachineConfigs generated by the controllers define do not
+	// conflict, user MachineConfigs overriding them only do informationally.
+	generated := newMC("01-generated", []ign3types.File{NewIgnFile("/etc/conflict", "generated"), NewIgnFile("/etc/mode", "generated"), NewIgnFile("/etc/generated", "template")}, nil)
+	generated.Annotations = map[string]string{GeneratedByControllerVersionAnnotationKey: "version"}
+	owned := newMC("98-owned", []ign3types.File{NewIgnFile("/etc/same", "owned"), NewIgnFile("/etc/generated", "owned"), NewIgnFile("/etc/generated-wins", "owned")}, nil)
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "machineconfiguration.openshift.io/v1", Kind: "KubeletConfig", Name: "owner", Controller: ptr.To(true)}}
+	configs = append(configs, generated, owned)
+
+	conflicts, err := GetMachineConfigConflicts(configs)
+	require.NoError(t, err)
+	assert.Equal(t, []MachineConfigConflict{
+		{Kind: "dropin", Name: "foo.service.d/10-foo.conf", MachineConfigs: []string{"00-base", "50-dropin"}},
+		{Kind: "file", Name: "/etc/conflict", MachineConfigs: []string{"00-base", "01-generated", "99-override"}},
+		{Kind: "file", Name: "/etc/generated-wins", MachineConfigs: []string{"70-mode", "98-owned"}},
+		{Kind: "file", Name: "/etc/mode", MachineConfigs: []string{"01-generated", "60-encoded", "70-mode"}},
+		{Kind: "file", Name: "/etc/same", MachineConfigs: []string{"00-base", "60-encoded", "98-owned", "99-override"}, OverridesGenerated: true},
+		{Kind: "unit", Name: "foo.service", MachineConfigs: []string{"00-base", "99-override"}},
+	}, conflicts)
+	assert.Equal(t, "file /etc/conflict is defined differently by 00-base, 01-generated, 99-override; 99-override takes precedence", conflicts[1].String())
+	assert.Equal(t, "file /etc/same is defined differently by 00-base, 60-encoded, 98-owned, 99-override; 99-override overrides the generated definition", conflicts[4].String())
+
+	conflicts, err = GetMachineConfigConflicts(configs[1:2])
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
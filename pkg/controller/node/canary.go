@@ -0,0 +1,266 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeErrs "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultCanarySoakPeriod is how long canary nodes must stay healthy when
+	// the pool does not set CanarySoakPeriodAnnotationKey.
+	defaultCanarySoakPeriod = 10 * time.Minute
+
+	canaryUpdatingReason = "CanaryUpdating"
+	canarySoakingReason  = "CanarySoaking"
+	canaryPassedReason   = "CanaryPassed"
+	canaryFailedReason   = "CanaryFailed"
+	canaryInvalidReason  = "InvalidCanaryConfiguration"
+
+	// canaryNodesPlaceholder is replaced in canary health queries with a
+	// regular expression matching the canary node names.
+	canaryNodesPlaceholder = "$canaryNodes"
+)
+
+// CanaryHealthChecker is consulted while a pool's canary nodes are soaking.
+// An error fails the canary stage and pauses the pool.
+type CanaryHealthChecker interface {
+	CheckCanaryHealth(ctx context.Context, pool *mcfgv1.MachineConfigPool, canaries []*corev1.Node) error
+}
+
+// CanaryQuerier runs a Prometheus-style instant query and returns the number
+// of samples in its result.
+type CanaryQuerier interface {
+	Query(ctx context.Context, query string) (int, error)
+}
+
+// queryCanaryHealthChecker fails the canary stage if any of the queries in the
+// pool's CanaryHealthQueriesAnnotationKey annotation returns a result, in the
+// same way a firing alert would.
+type queryCanaryHealthChecker struct {
+	querier CanaryQuerier
+}
+
+// NewQueryCanaryHealthChecker returns a CanaryHealthChecker evaluating the
+// pool's canary health queries with the given querier.
+func NewQueryCanaryHealthChecker(querier CanaryQuerier) CanaryHealthChecker {
+	return &queryCanaryHealthChecker{querier: querier}
+}
+
+func (q *queryCanaryHealthChecker) CheckCanaryHealth(ctx context.Context, pool *mcfgv1.MachineConfigPool, canaries []*corev1.Node) error {
+	names := make([]string, 0, len(canaries))
+	for _, node := range canaries {
+		names = append(names, regexp.QuoteMeta(node.Name))
+	}
+	nodesRegex := strings.Join(names, "|")
+
+	var errs []error
+	for _, query := range strings.Split(pool.Annotations[ctrlcommon.CanaryHealthQueriesAnnotationKey], "\n") {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			continue
+		}
+		query = strings.ReplaceAll(query, canaryNodesPlaceholder, nodesRegex)
+		results, err := q.querier.Query(ctx, query)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("query %q failed: %w", query, err))
+			continue
+		}
+		if results > 0 {
+			errs = append(errs, fmt.Errorf("query %q returned %d results", query, results))
+		}
+	}
+	return kubeErrs.NewAggregate(errs)
+}
+
+// AddCanaryHealthChecker registers an additional health check for canary
+// nodes, on top of the built-in node readiness and MCD state checks.
+func (ctrl *Controller) AddCanaryHealthChecker(checker CanaryHealthChecker) {
+	ctrl.canaryHealthCheckers = append(ctrl.canaryHealthCheckers, checker)
+}
+
+type canaryPhase string
+
+const (
+	canaryPhaseUpdating canaryPhase = "Updating"
+	canaryPhaseSoaking  canaryPhase = "Soaking"
+	canaryPhasePassed   canaryPhase = "Passed"
+	canaryPhaseFailed   canaryPhase = "Failed"
+	canaryPhaseInvalid  canaryPhase = "Invalid"
+)
+
+// canaryStatus is the state of a pool's canary stage.
+type canaryStatus struct {
+	// configured is false if the pool has no canary nodes.
+	configured bool
+	phase      canaryPhase
+	// maxNewNodes is the number of additional nodes that may start updating,
+	// or -1 if the canary stage does not restrict the rollout.
+	maxNewNodes int
+	// soakRemaining is how long the canaries still need to soak.
+	soakRemaining time.Duration
+	message       string
+}
+
+// evaluateCanary determines where the pool's rollout is with respect to its
+// canary stage. The first nodes to target the pool's config are the canaries;
+// the rest of the pool is held back until all of them have updated and stayed
+// healthy for the soak period.
+//
+// The soak start is taken from the transition time of the pool's canary
+// condition, so that it survives controller restarts.
+func (ctrl *Controller) evaluateCanary(pool *mcfgv1.MachineConfigPool, nodes []*corev1.Node, layered bool, mosc *mcfgv1.MachineOSConfig, mosb *mcfgv1.MachineOSBuild, now time.Time) canaryStatus {
+	value, ok := pool.Annotations[ctrlcommon.CanaryNodesAnnotationKey]
+	if !ok {
+		return canaryStatus{maxNewNodes: -1}
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		return canaryStatus{configured: true, phase: canaryPhaseInvalid, message: fmt.Sprintf("invalid canary node count %q, expected a positive integer", value)}
+	}
+	soakPeriod := defaultCanarySoakPeriod
+	if value, ok := pool.Annotations[ctrlcommon.CanarySoakPeriodAnnotationKey]; ok {
+		soakPeriod, err = time.ParseDuration(value)
+		if err != nil || soakPeriod < 0 {
+			return canaryStatus{configured: true, phase: canaryPhaseInvalid, message: fmt.Sprintf("invalid canary soak period %q", value)}
+		}
+	}
+	if count > len(nodes) {
+		count = len(nodes)
+	}
+
+	var canaries, done []*corev1.Node
+	for _, node := range nodes {
+		lns := ctrlcommon.NewLayeredNodeState(node)
+		if lns.CheckNodeCandidacyForUpdate(layered, pool, mosc, mosb) {
+			continue
+		}
+		canaries = append(canaries, node)
+		if lns.IsDone(pool, layered, mosc, mosb) {
+			done = append(done, node)
+		}
+	}
+
+	// Once more nodes than the canaries target the new config, the canary
+	// stage has passed and the rollout is no longer restricted.
+	if len(canaries) > count || len(done) == len(nodes) {
+		return canaryStatus{configured: true, phase: canaryPhasePassed, maxNewNodes: -1, message: "Canary nodes passed their health checks"}
+	}
+
+	if err := checkCanaryNodes(canaries); err != nil {
+		return canaryStatus{configured: true, phase: canaryPhaseFailed, message: err.Error()}
+	}
+
+	if len(canaries) < count {
+		return canaryStatus{configured: true, phase: canaryPhaseUpdating, maxNewNodes: count - len(canaries),
+			message: fmt.Sprintf("Updating %d canary nodes", count)}
+	}
+	if len(done) < len(canaries) {
+		return canaryStatus{configured: true, phase: canaryPhaseUpdating,
+			message: fmt.Sprintf("Waiting for %d of %d canary nodes to finish updating", len(canaries)-len(done), count)}
+	}
+
+	var errs []error
+	for _, checker := range ctrl.canaryHealthCheckers {
+		if err := checker.CheckCanaryHealth(context.TODO(), pool, canaries); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return canaryStatus{configured: true, phase: canaryPhaseFailed, message: kubeErrs.NewAggregate(errs).Error()}
+	}
+
+	soakStart := now
+	cond := apihelpers.GetMachineConfigPoolCondition(pool.Status, mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolCanaryVerified))
+	if cond != nil && cond.Reason == canarySoakingReason {
+		soakStart = cond.LastTransitionTime.Time
+	}
+	if remaining := soakStart.Add(soakPeriod).Sub(now); remaining > 0 {
+		return canaryStatus{configured: true, phase: canaryPhaseSoaking, soakRemaining: remaining,
+			message: fmt.Sprintf("Canary nodes are soaking until %s", soakStart.Add(soakPeriod).UTC().Format(time.RFC3339))}
+	}
+
+	return canaryStatus{configured: true, phase: canaryPhasePassed, maxNewNodes: -1, message: "Canary nodes passed their health checks"}
+}
+
+// checkCanaryNodes runs the built-in health checks against the canary nodes:
+// the MCD must not be degraded, and nodes that finished updating must be Ready.
+func checkCanaryNodes(canaries []*corev1.Node) error {
+	var errs []error
+	for _, node := range canaries {
+		lns := ctrlcommon.NewLayeredNodeState(node)
+		if lns.IsNodeDegraded() || lns.IsNodeUnreconcilable() {
+			errs = append(errs, fmt.Errorf("canary node %s is degraded", node.Name))
+			continue
+		}
+		if lns.IsNodeDone() && !lns.IsNodeReady() {
+			errs = append(errs, fmt.Errorf("canary node %s is not ready: %w", node.Name, lns.CheckNodeReady()))
+		}
+	}
+	return kubeErrs.NewAggregate(errs)
+}
+
+// setCanaryCondition reports the state of the pool's canary stage in the pool
+// status, or removes the condition if the pool has no canary nodes.
+func setCanaryCondition(status *mcfgv1.MachineConfigPoolStatus, canary canaryStatus) {
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolCanaryVerified)
+	if !canary.configured {
+		apihelpers.RemoveMachineConfigPoolCondition(status, condType)
+		return
+	}
+
+	var condStatus corev1.ConditionStatus
+	var reason string
+	switch canary.phase {
+	case canaryPhaseUpdating:
+		condStatus, reason = corev1.ConditionUnknown, canaryUpdatingReason
+	case canaryPhaseSoaking:
+		condStatus, reason = corev1.ConditionFalse, canarySoakingReason
+	case canaryPhasePassed:
+		condStatus, reason = corev1.ConditionTrue, canaryPassedReason
+	case canaryPhaseFailed:
+		condStatus, reason = corev1.ConditionFalse, canaryFailedReason
+	default:
+		condStatus, reason = corev1.ConditionFalse, canaryInvalidReason
+	}
+
+	// The transition time of the soaking condition marks the start of the soak
+	// period, so make sure it is reset when soaking starts over.
+	if cur := apihelpers.GetMachineConfigPoolCondition(*status, condType); cur != nil && reason == canarySoakingReason && cur.Reason != canarySoakingReason {
+		apihelpers.RemoveMachineConfigPoolCondition(status, condType)
+	}
+	apihelpers.SetMachineConfigPoolCondition(status, *apihelpers.NewMachineConfigPoolCondition(condType, condStatus, reason, canary.message))
+}
+
+// haltCanaryRollout pauses a pool whose canary stage failed so that no further
+// nodes are updated until an administrator has looked at it.
+func (ctrl *Controller) haltCanaryRollout(pool *mcfgv1.MachineConfigPool, canary canaryStatus) error {
+	if pool.Spec.Paused {
+		return nil
+	}
+	klog.Warningf("Pool %s: canary stage failed, pausing pool: %s", pool.Name, canary.message)
+
+	// Re-fetch the pool so that nothing but the paused field is written back.
+	newPool, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), pool.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	newPool.Spec.Paused = true
+	if _, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Update(context.TODO(), newPool, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not pause MachineConfigPool %q after canary failure: %w", pool.Name, err)
+	}
+	ctrl.eventRecorder.Eventf(pool, corev1.EventTypeWarning, canaryFailedReason, "Paused pool after canary nodes failed health checks for %s: %s", pool.Spec.Configuration.Name, canary.message)
+	return nil
+}
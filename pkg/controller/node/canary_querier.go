@@ -0,0 +1,116 @@
+package node
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCanaryPrometheusURL is the cluster monitoring Thanos querier,
+	// which canary health queries are run against by default.
+	DefaultCanaryPrometheusURL = "https://thanos-querier.openshift-monitoring.svc:9091"
+
+	// serviceCAPath is the service CA bundle OpenShift injects into the
+	// service account volume of every pod.
+	serviceCAPath = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+	// serviceAccountTokenPath is the token of the pod's service account.
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	canaryQueryTimeout = 30 * time.Second
+)
+
+// prometheusQuerier runs instant queries against the Prometheus HTTP API,
+// authenticating with the controller's service account token.
+type prometheusQuerier struct {
+	url       string
+	tokenPath string
+	client    *http.Client
+}
+
+// NewPrometheusCanaryQuerier returns a CanaryQuerier running instant queries
+// against the Prometheus HTTP API at baseURL, verifying its certificate with
+// the service CA and authenticating with the service account token.
+func NewPrometheusCanaryQuerier(baseURL string) (CanaryQuerier, error) {
+	return newPrometheusQuerier(baseURL, serviceCAPath, serviceAccountTokenPath)
+}
+
+func newPrometheusQuerier(baseURL, caPath, tokenPath string) (*prometheusQuerier, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caPath != "" {
+		caData, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle for canary health queries: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in %s", caPath)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &prometheusQuerier{
+		url:       strings.TrimSuffix(baseURL, "/"),
+		tokenPath: tokenPath,
+		client:    &http.Client{Transport: transport, Timeout: canaryQueryTimeout},
+	}, nil
+}
+
+// prometheusQueryResponse is the part of a Prometheus API instant query
+// response the canary health checks look at.
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string            `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (p *prometheusQuerier) Query(ctx context.Context, query string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/api/v1/query", strings.NewReader(url.Values{"query": {query}}.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if p.tokenPath != "" {
+		// The token is read for each query as it is rotated.
+		token, err := os.ReadFile(p.tokenPath)
+		if err != nil {
+			return 0, fmt.Errorf("could not read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return 0, err
+	}
+
+	var result prometheusQueryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("unexpected response with status %s: %w", resp.Status, err)
+	}
+	if result.Status != "success" {
+		return 0, fmt.Errorf("query failed with status %s: %s", resp.Status, result.Error)
+	}
+	if result.Data.ResultType != "vector" && result.Data.ResultType != "matrix" {
+		// Scalars and strings always have a value; only series can be empty.
+		return 0, fmt.Errorf("query returned a %s, expected an instant vector", result.Data.ResultType)
+	}
+	return len(result.Data.Result), nil
+}
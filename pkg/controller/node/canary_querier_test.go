@@ -0,0 +1,65 @@
+package node
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusQuerier(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.FormValue("query") {
+		case `up{node=~"a|b"} == 0`:
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"0"]},{"metric":{},"value":[0,"0"]}]}}`)
+		case "healthy":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+		case "scalar(1)":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[0,"1"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	tokenPath := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	require.NoError(t, os.WriteFile(tokenPath, []byte("secret-token\n"), 0o600))
+
+	querier, err := newPrometheusQuerier(server.URL+"/", caPath, tokenPath)
+	require.NoError(t, err)
+
+	results, err := querier.Query(context.TODO(), `up{node=~"a|b"} == 0`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, results)
+
+	results, err = querier.Query(context.TODO(), "healthy")
+	require.NoError(t, err)
+	assert.Equal(t, 0, results)
+
+	_, err = querier.Query(context.TODO(), "scalar(1)")
+	assert.ErrorContains(t, err, "expected an instant vector")
+
+	_, err = querier.Query(context.TODO(), "invalid(")
+	assert.ErrorContains(t, err, "parse error")
+
+	// The server certificate is not trusted without the CA.
+	untrusted, err := newPrometheusQuerier(server.URL, "", tokenPath)
+	require.NoError(t, err)
+	_, err = untrusted.Query(context.TODO(), "healthy")
+	assert.Error(t, err)
+}
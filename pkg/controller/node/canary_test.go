@@ -0,0 +1,314 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeCanaryHealthChecker struct {
+	err error
+}
+
+func (f *fakeCanaryHealthChecker) CheckCanaryHealth(_ context.Context, _ *mcfgv1.MachineConfigPool, _ []*corev1.Node) error {
+	return f.err
+}
+
+type fakeCanaryQuerier struct {
+	results map[string]int
+	queries []string
+}
+
+func (f *fakeCanaryQuerier) Query(_ context.Context, query string) (int, error) {
+	f.queries = append(f.queries, query)
+	results, ok := f.results[query]
+	if !ok {
+		return 0, fmt.Errorf("unexpected query")
+	}
+	return results, nil
+}
+
+func TestEvaluateCanary(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	done := daemonconsts.MachineConfigDaemonStateDone
+
+	oldNode := func(name string) *corev1.Node {
+		return newNodeWithReadyAndDaemonState(name, machineConfigV0, machineConfigV0, corev1.ConditionTrue, done)
+	}
+	updatedNode := func(name string) *corev1.Node {
+		return newNodeWithReadyAndDaemonState(name, machineConfigV1, machineConfigV1, corev1.ConditionTrue, done)
+	}
+	updatingNode := func(name string) *corev1.Node {
+		return newNodeWithReadyAndDaemonState(name, machineConfigV0, machineConfigV1, corev1.ConditionTrue, daemonconsts.MachineConfigDaemonStateWorking)
+	}
+	soakingCondition := func(since time.Duration) []mcfgv1.MachineConfigPoolCondition {
+		return []mcfgv1.MachineConfigPoolCondition{{
+			Type:               mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolCanaryVerified),
+			Status:             corev1.ConditionFalse,
+			Reason:             canarySoakingReason,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}
+	}
+
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		conditions    []mcfgv1.MachineConfigPoolCondition
+		nodes         []*corev1.Node
+		checkers      []CanaryHealthChecker
+		phase         canaryPhase
+		maxNewNodes   int
+		soakRemaining time.Duration
+	}{
+		{
+			name:        "not configured",
+			nodes:       []*corev1.Node{oldNode("node-0")},
+			maxNewNodes: -1,
+		},
+		{
+			name:        "invalid count",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "zero"},
+			nodes:       []*corev1.Node{oldNode("node-0")},
+			phase:       canaryPhaseInvalid,
+		},
+		{
+			name: "invalid soak period",
+			annotations: map[string]string{
+				ctrlcommon.CanaryNodesAnnotationKey:      "1",
+				ctrlcommon.CanarySoakPeriodAnnotationKey: "soon",
+			},
+			nodes: []*corev1.Node{oldNode("node-0")},
+			phase: canaryPhaseInvalid,
+		},
+		{
+			name:        "no canaries yet",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			nodes:       []*corev1.Node{oldNode("node-0"), oldNode("node-1"), oldNode("node-2")},
+			phase:       canaryPhaseUpdating,
+			maxNewNodes: 2,
+		},
+		{
+			name:        "canary count capped at pool size",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "5"},
+			nodes:       []*corev1.Node{updatingNode("node-0"), oldNode("node-1")},
+			phase:       canaryPhaseUpdating,
+			maxNewNodes: 1,
+		},
+		{
+			name:        "waiting for canaries to finish",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			nodes:       []*corev1.Node{updatedNode("node-0"), updatingNode("node-1"), oldNode("node-2")},
+			phase:       canaryPhaseUpdating,
+		},
+		{
+			name:          "soak starts",
+			annotations:   map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			nodes:         []*corev1.Node{updatedNode("node-0"), updatedNode("node-1"), oldNode("node-2")},
+			phase:         canaryPhaseSoaking,
+			soakRemaining: defaultCanarySoakPeriod,
+		},
+		{
+			name: "still soaking",
+			annotations: map[string]string{
+				ctrlcommon.CanaryNodesAnnotationKey:      "2",
+				ctrlcommon.CanarySoakPeriodAnnotationKey: "1h",
+			},
+			conditions:    soakingCondition(20 * time.Minute),
+			nodes:         []*corev1.Node{updatedNode("node-0"), updatedNode("node-1"), oldNode("node-2")},
+			phase:         canaryPhaseSoaking,
+			soakRemaining: 40 * time.Minute,
+		},
+		{
+			name:        "soak complete",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			conditions:  soakingCondition(defaultCanarySoakPeriod),
+			nodes:       []*corev1.Node{updatedNode("node-0"), updatedNode("node-1"), oldNode("node-2")},
+			checkers:    []CanaryHealthChecker{&fakeCanaryHealthChecker{}},
+			phase:       canaryPhasePassed,
+			maxNewNodes: -1,
+		},
+		{
+			name:        "health checker fails",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			conditions:  soakingCondition(time.Minute),
+			nodes:       []*corev1.Node{updatedNode("node-0"), updatedNode("node-1"), oldNode("node-2")},
+			checkers:    []CanaryHealthChecker{&fakeCanaryHealthChecker{err: fmt.Errorf("alert firing")}},
+			phase:       canaryPhaseFailed,
+		},
+		{
+			name:        "canary degraded",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			nodes: []*corev1.Node{
+				updatedNode("node-0"),
+				newNodeWithReadyAndDaemonState("node-1", machineConfigV0, machineConfigV1, corev1.ConditionTrue, daemonconsts.MachineConfigDaemonStateDegraded),
+				oldNode("node-2"),
+			},
+			phase: canaryPhaseFailed,
+		},
+		{
+			name:        "canary not ready after update",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "2"},
+			nodes: []*corev1.Node{
+				updatedNode("node-0"),
+				newNodeWithReadyAndDaemonState("node-1", machineConfigV1, machineConfigV1, corev1.ConditionFalse, done),
+				oldNode("node-2"),
+			},
+			phase: canaryPhaseFailed,
+		},
+		{
+			name:        "rollout past the canaries",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "1"},
+			nodes:       []*corev1.Node{updatedNode("node-0"), updatingNode("node-1"), oldNode("node-2")},
+			phase:       canaryPhasePassed,
+			maxNewNodes: -1,
+		},
+		{
+			name:        "pool up to date",
+			annotations: map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "1"},
+			nodes:       []*corev1.Node{updatedNode("node-0")},
+			phase:       canaryPhasePassed,
+			maxNewNodes: -1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+			pool.Annotations = test.annotations
+			pool.Status.Conditions = test.conditions
+
+			ctrl := &Controller{canaryHealthCheckers: test.checkers}
+			canary := ctrl.evaluateCanary(pool, test.nodes, false, nil, nil, now)
+			assert.Equal(t, test.annotations != nil, canary.configured)
+			assert.Equal(t, test.phase, canary.phase, canary.message)
+			assert.Equal(t, test.maxNewNodes, canary.maxNewNodes)
+			assert.Equal(t, test.soakRemaining, canary.soakRemaining)
+		})
+	}
+}
+
+func TestSetCanaryCondition(t *testing.T) {
+	t.Parallel()
+
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolCanaryVerified)
+	failedSince := metav1.NewTime(time.Now().Add(-time.Hour))
+	status := mcfgv1.MachineConfigPoolStatus{
+		Conditions: []mcfgv1.MachineConfigPoolCondition{{
+			Type:               condType,
+			Status:             corev1.ConditionFalse,
+			Reason:             canaryFailedReason,
+			LastTransitionTime: failedSince,
+		}},
+	}
+
+	// Soaking after a failure starts a fresh soak period.
+	setCanaryCondition(&status, canaryStatus{configured: true, phase: canaryPhaseSoaking})
+	cond := apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, canarySoakingReason, cond.Reason)
+	assert.True(t, cond.LastTransitionTime.After(failedSince.Time))
+
+	setCanaryCondition(&status, canaryStatus{configured: true, phase: canaryPhasePassed})
+	cond = apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+
+	setCanaryCondition(&status, canaryStatus{})
+	assert.Nil(t, apihelpers.GetMachineConfigPoolCondition(status, condType))
+}
+
+func TestCalculateStatusKeepsCanaryCondition(t *testing.T) {
+	t.Parallel()
+
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolCanaryVerified)
+	soakingSince := metav1.NewTime(time.Now().Add(-time.Minute))
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	pool.Annotations = map[string]string{ctrlcommon.CanaryNodesAnnotationKey: "1"}
+	pool.Status.Conditions = []mcfgv1.MachineConfigPoolCondition{{
+		Type:               condType,
+		Status:             corev1.ConditionFalse,
+		Reason:             canarySoakingReason,
+		LastTransitionTime: soakingSince,
+	}}
+	nodes := []*corev1.Node{
+		newNodeWithReadyAndDaemonState("node-0", machineConfigV1, machineConfigV1, corev1.ConditionTrue, daemonconsts.MachineConfigDaemonStateDone),
+		newNodeWithReadyAndDaemonState("node-1", machineConfigV0, machineConfigV0, corev1.ConditionTrue, daemonconsts.MachineConfigDaemonStateDone),
+	}
+
+	// Status syncs outside of syncMachineConfigPool do not run the health
+	// checks, which would fail the canary stage here.
+	ctrl := newFixture(t).newController()
+	ctrl.AddCanaryHealthChecker(&fakeCanaryHealthChecker{err: fmt.Errorf("unhealthy")})
+	status := ctrl.calculateStatus([]*mcfgv1.MachineConfigNode{}, nil, pool, nodes, nil, nil, nil)
+	cond := apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, canarySoakingReason, cond.Reason)
+	assert.True(t, cond.LastTransitionTime.Equal(&soakingSince))
+
+	canary := ctrl.evaluateCanary(pool, nodes, false, nil, nil, time.Now())
+	status = ctrl.calculateStatus([]*mcfgv1.MachineConfigNode{}, nil, pool, nodes, nil, nil, &canary)
+	cond = apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, canaryFailedReason, cond.Reason)
+
+	// The condition is removed once the pool has no canary nodes.
+	pool.Annotations = nil
+	status = ctrl.calculateStatus([]*mcfgv1.MachineConfigNode{}, nil, pool, nodes, nil, nil, nil)
+	assert.Nil(t, apihelpers.GetMachineConfigPoolCondition(status, condType))
+}
+
+func TestQueryCanaryHealthChecker(t *testing.T) {
+	t.Parallel()
+
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	pool.Annotations = map[string]string{
+		ctrlcommon.CanaryHealthQueriesAnnotationKey: "up{node=~\"$canaryNodes\"} == 0\n\nALERTS{severity=\"critical\"}",
+	}
+	canaries := []*corev1.Node{newNode("node-0", machineConfigV1, machineConfigV1), newNode("node.1", machineConfigV1, machineConfigV1)}
+
+	querier := &fakeCanaryQuerier{results: map[string]int{
+		"up{node=~\"node-0|node\\.1\"} == 0": 0,
+		"ALERTS{severity=\"critical\"}":      0,
+	}}
+	checker := NewQueryCanaryHealthChecker(querier)
+	assert.NoError(t, checker.CheckCanaryHealth(context.TODO(), pool, canaries))
+	assert.Len(t, querier.queries, 2)
+
+	querier.results["ALERTS{severity=\"critical\"}"] = 2
+	err := checker.CheckCanaryHealth(context.TODO(), pool, canaries)
+	assert.ErrorContains(t, err, "returned 2 results")
+}
+
+func TestHaltCanaryRollout(t *testing.T) {
+	t.Parallel()
+
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	client := fake.NewSimpleClientset(pool)
+	recorder := record.NewFakeRecorder(1)
+	ctrl := &Controller{client: client, eventRecorder: recorder}
+
+	require.NoError(t, ctrl.haltCanaryRollout(pool, canaryStatus{phase: canaryPhaseFailed, message: "canary node node-0 is degraded"}))
+
+	updated, err := client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), pool.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, updated.Spec.Paused)
+	assert.Contains(t, <-recorder.Events, "canary node node-0 is degraded")
+}
@@ -0,0 +1,246 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	maintenanceWindowOpenReason    = "WindowOpen"
+	maintenanceWindowClosedReason  = "WindowClosed"
+	maintenanceWindowInvalidReason = "InvalidMaintenanceWindow"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// maintenanceWindow is a recurring weekly window during which a pool may start
+// updating nodes. A window whose end is before its start runs past midnight,
+// into the day after each of its days.
+type maintenanceWindow struct {
+	days     [7]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+// parseMaintenanceWindows parses a semicolon-separated list of windows of the
+// form "<days> <HH:MM>-<HH:MM> [<timezone>]". Days are "*", a day name, or a
+// comma-separated list of day names and ranges such as "Mon-Fri,Sun". The
+// timezone defaults to UTC.
+func parseMaintenanceWindows(value string) ([]maintenanceWindow, error) {
+	var windows []maintenanceWindow
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		window, err := parseMaintenanceWindow(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", entry, err)
+		}
+		windows = append(windows, window)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no maintenance windows found in %q", value)
+	}
+	return windows, nil
+}
+
+func parseMaintenanceWindow(entry string) (maintenanceWindow, error) {
+	window := maintenanceWindow{location: time.UTC}
+
+	fields := strings.Fields(entry)
+	if len(fields) != 2 && len(fields) != 3 {
+		return window, fmt.Errorf("expected \"<days> <HH:MM>-<HH:MM> [<timezone>]\"")
+	}
+
+	days, err := parseWeekdays(fields[0])
+	if err != nil {
+		return window, err
+	}
+	window.days = days
+
+	start, end, found := strings.Cut(fields[1], "-")
+	if !found {
+		return window, fmt.Errorf("expected a time range like 02:00-06:00, got %q", fields[1])
+	}
+	if window.start, err = parseTimeOfDay(start); err != nil {
+		return window, err
+	}
+	if window.end, err = parseTimeOfDay(end); err != nil {
+		return window, err
+	}
+	if window.start == window.end {
+		return window, fmt.Errorf("window start and end must differ")
+	}
+
+	if len(fields) == 3 {
+		if window.location, err = time.LoadLocation(fields[2]); err != nil {
+			return window, fmt.Errorf("unknown timezone %q: %w", fields[2], err)
+		}
+	}
+
+	return window, nil
+}
+
+func parseWeekdays(value string) ([7]bool, error) {
+	var days [7]bool
+	if value == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return days, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// Ranges may wrap around the end of the week, e.g. Fri-Mon.
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// opening returns the time the window opens on the calendar day of t.
+func (w maintenanceWindow) opening(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.location).Add(w.start)
+}
+
+// contains returns true if the window is open at now.
+func (w maintenanceWindow) contains(now time.Time) bool {
+	length := w.end - w.start
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+
+	local := now.In(w.location)
+	// Check the occurrences starting today and yesterday, the latter covering
+	// windows that run past midnight.
+	for _, day := range []time.Time{local, local.AddDate(0, 0, -1)} {
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		opening := w.opening(day)
+		if !now.Before(opening) && now.Before(opening.Add(length)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpening returns the next time after now at which the window opens.
+func (w maintenanceWindow) nextOpening(now time.Time) time.Time {
+	local := now.In(w.location)
+	for i := 0; i <= 7; i++ {
+		day := local.AddDate(0, 0, i)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		if opening := w.opening(day); opening.After(now) {
+			return opening
+		}
+	}
+	// Unreachable for a window with at least one day.
+	return time.Time{}
+}
+
+// maintenanceWindowStatus describes whether a pool may start updating nodes.
+type maintenanceWindowStatus struct {
+	// configured is false if the pool has no maintenance window.
+	configured bool
+	open       bool
+	// nextOpening is set while the window is closed.
+	nextOpening time.Time
+	err         error
+}
+
+// getMaintenanceWindowStatus evaluates the pool's maintenance window at now.
+// An invalid window is treated as closed so that a typo does not allow
+// updates at unexpected times.
+func getMaintenanceWindowStatus(pool *mcfgv1.MachineConfigPool, now time.Time) maintenanceWindowStatus {
+	value, ok := pool.Annotations[ctrlcommon.MaintenanceWindowAnnotationKey]
+	if !ok {
+		return maintenanceWindowStatus{open: true}
+	}
+
+	windows, err := parseMaintenanceWindows(value)
+	if err != nil {
+		return maintenanceWindowStatus{configured: true, err: err}
+	}
+
+	status := maintenanceWindowStatus{configured: true}
+	for _, window := range windows {
+		if window.contains(now) {
+			status.open = true
+			status.nextOpening = time.Time{}
+			return status
+		}
+		next := window.nextOpening(now)
+		if status.nextOpening.IsZero() || next.Before(status.nextOpening) {
+			status.nextOpening = next
+		}
+	}
+	return status
+}
+
+// setMaintenanceWindowCondition reports the state of the pool's maintenance
+// window in the pool status, or removes the condition if there is none.
+func setMaintenanceWindowCondition(status *mcfgv1.MachineConfigPoolStatus, pool *mcfgv1.MachineConfigPool, now time.Time) {
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolMaintenanceWindow)
+
+	mw := getMaintenanceWindowStatus(pool, now)
+	if !mw.configured {
+		apihelpers.RemoveMachineConfigPoolCondition(status, condType)
+		return
+	}
+
+	var cond *mcfgv1.MachineConfigPoolCondition
+	switch {
+	case mw.err != nil:
+		cond = apihelpers.NewMachineConfigPoolCondition(condType, corev1.ConditionFalse, maintenanceWindowInvalidReason,
+			fmt.Sprintf("Not updating nodes: %v", mw.err))
+	case mw.open:
+		cond = apihelpers.NewMachineConfigPoolCondition(condType, corev1.ConditionTrue, maintenanceWindowOpenReason,
+			"Maintenance window is open")
+	default:
+		cond = apihelpers.NewMachineConfigPoolCondition(condType, corev1.ConditionFalse, maintenanceWindowClosedReason,
+			fmt.Sprintf("Maintenance window is closed; no new nodes will start updating until %s", mw.nextOpening.UTC().Format(time.RFC3339)))
+	}
+	apihelpers.SetMachineConfigPoolCondition(status, *cond)
+}
@@ -0,0 +1,166 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value     string
+		expectErr bool
+	}{
+		{value: "Sat 02:00-06:00 UTC"},
+		{value: "Sat 02:00-06:00"},
+		{value: "mon-fri 22:00-04:00 Europe/Berlin"},
+		{value: "Fri-Mon,Wed 01:00-02:00"},
+		{value: "* 00:00-01:00; Sat 02:00-06:00"},
+		{value: "", expectErr: true},
+		{value: "Sat", expectErr: true},
+		{value: "Someday 02:00-06:00", expectErr: true},
+		{value: "Sat 02:00", expectErr: true},
+		{value: "Sat 25:00-26:00", expectErr: true},
+		{value: "Sat 02:00-02:00", expectErr: true},
+		{value: "Sat 02:00-06:00 Mars/Olympus", expectErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+			_, err := parseMaintenanceWindows(test.value)
+			if test.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetMaintenanceWindowStatus(t *testing.T) {
+	t.Parallel()
+
+	// 2024-06-01 is a Saturday.
+	sat := func(hour, minute int) time.Time {
+		return time.Date(2024, time.June, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name         string
+		window       string
+		now          time.Time
+		expectOpen   bool
+		expectNext   time.Time
+		expectErr    bool
+		noAnnotation bool
+	}{
+		{
+			name:         "no window",
+			noAnnotation: true,
+			now:          sat(12, 0),
+			expectOpen:   true,
+		},
+		{
+			name:       "inside window",
+			window:     "Sat 02:00-06:00 UTC",
+			now:        sat(3, 0),
+			expectOpen: true,
+		},
+		{
+			name:       "at window end",
+			window:     "Sat 02:00-06:00 UTC",
+			now:        sat(6, 0),
+			expectNext: sat(2, 0).AddDate(0, 0, 7),
+		},
+		{
+			name:       "before window",
+			window:     "Sat 02:00-06:00 UTC",
+			now:        sat(1, 0),
+			expectNext: sat(2, 0),
+		},
+		{
+			name:       "window past midnight from the previous day",
+			window:     "Fri 22:00-04:00",
+			now:        sat(3, 59),
+			expectOpen: true,
+		},
+		{
+			name:       "window past midnight closed",
+			window:     "Fri 22:00-04:00",
+			now:        sat(4, 0),
+			expectNext: sat(22, 0).AddDate(0, 0, 6),
+		},
+		{
+			name:       "earliest of several windows",
+			window:     "Mon 01:00-02:00; Sun 05:00-06:00",
+			now:        sat(12, 0),
+			expectNext: sat(5, 0).AddDate(0, 0, 1),
+		},
+		{
+			name:       "timezone",
+			window:     "Sat 02:00-06:00 America/New_York",
+			now:        sat(7, 0),
+			expectOpen: true,
+		},
+		{
+			name:      "invalid window is closed",
+			window:    "Sat",
+			now:       sat(3, 0),
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+			if !test.noAnnotation {
+				pool.Annotations = map[string]string{ctrlcommon.MaintenanceWindowAnnotationKey: test.window}
+			}
+
+			status := getMaintenanceWindowStatus(pool, test.now)
+			assert.Equal(t, !test.noAnnotation, status.configured)
+			assert.Equal(t, test.expectOpen, status.open)
+			assert.Equal(t, test.expectErr, status.err != nil)
+			assert.True(t, test.expectNext.Equal(status.nextOpening), "expected next opening %s, got %s", test.expectNext, status.nextOpening)
+		})
+	}
+}
+
+func TestSetMaintenanceWindowCondition(t *testing.T) {
+	t.Parallel()
+
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolMaintenanceWindow)
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	pool.Annotations = map[string]string{ctrlcommon.MaintenanceWindowAnnotationKey: "Sat 02:00-06:00 UTC"}
+	status := mcfgv1.MachineConfigPoolStatus{}
+
+	setMaintenanceWindowCondition(&status, pool, time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC))
+	cond := apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1.ConditionFalse, cond.Status)
+	assert.Equal(t, maintenanceWindowClosedReason, cond.Reason)
+	assert.Contains(t, cond.Message, "2024-06-08T02:00:00Z")
+
+	setMaintenanceWindowCondition(&status, pool, time.Date(2024, time.June, 8, 3, 0, 0, 0, time.UTC))
+	cond = apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+
+	delete(pool.Annotations, ctrlcommon.MaintenanceWindowAnnotationKey)
+	setMaintenanceWindowCondition(&status, pool, time.Now())
+	assert.Nil(t, apihelpers.GetMachineConfigPoolCondition(status, condType))
+}
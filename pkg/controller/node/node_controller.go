@@ -133,6 +133,9 @@ type Controller struct {
 
 	// osStreamsFgEnabled caches whether the OSStreams feature gate is enabled
 	osStreamsFgEnabled bool
+
+	// canaryHealthCheckers are consulted while a pool's canary nodes are soaking
+	canaryHealthCheckers []CanaryHealthChecker
 }
 
 func New(
@@ -1328,6 +1331,17 @@ func (ctrl *Controller) syncMachineConfigPool(key string) error {
 		}
 		return err
 	}
+	if !layered {
+		// From here on, a rolled back pool targets the config it was rolled back to.
+		pool, err = ctrl.reconcileRollback(pool, nodes)
+		if err != nil {
+			if syncErr := ctrl.syncStatusOnly(pool); syncErr != nil {
+				errs := kubeErrs.NewAggregate([]error{syncErr, err})
+				return fmt.Errorf("error rolling back pool %q, sync error: %w", pool.Name, errs)
+			}
+			return err
+		}
+	}
 	maxunavail, err := maxUnavailable(pool, nodes)
 	if err != nil {
 		if syncErr := ctrl.syncStatusOnly(pool); syncErr != nil {
@@ -1367,8 +1381,41 @@ func (ctrl *Controller) syncMachineConfigPool(key string) error {
 			}
 		}
 	}
-	candidates, capacity := getAllCandidateMachines(layered, mosc, mosb, pool, nodes, maxunavail)
-	if len(candidates) > 0 {
+	// Nodes that are already updating are allowed to finish, but no new nodes
+	// are started outside of the pool's maintenance window.
+	if mw := getMaintenanceWindowStatus(pool, time.Now()); !mw.open {
+		if mw.err != nil {
+			klog.Warningf("Pool %s: not updating nodes: %v", pool.Name, mw.err)
+		} else {
+			klog.V(4).Infof("Pool %s: maintenance window is closed, holding back updates until %s", pool.Name, mw.nextOpening.UTC().Format(time.RFC3339))
+			ctrl.enqueueAfter(pool, time.Until(mw.nextOpening))
+		}
+		if err := ctrl.syncStatusOnly(pool); err != nil {
+			return err
+		}
+		return ctrl.syncMetrics()
+	}
+
+	canary := ctrl.evaluateCanary(pool, nodes, layered, mosc, mosb, time.Now())
+	switch canary.phase {
+	case canaryPhaseFailed:
+		if err := ctrl.haltCanaryRollout(pool, canary); err != nil {
+			return err
+		}
+		return ctrl.syncStatus(pool, &canary)
+	case canaryPhaseInvalid:
+		klog.Warningf("Pool %s: not updating nodes: %s", pool.Name, canary.message)
+	case canaryPhaseSoaking:
+		ctrl.enqueueAfter(pool, canary.soakRemaining)
+	}
+
+	strategy := newNodeUpdateStrategy(pool, ctrl.podLister)
+	candidates, capacity := getAllCandidateMachines(layered, mosc, mosb, pool, nodes, maxunavail, strategy)
+	if canary.configured && canary.maxNewNodes >= 0 && capacity > uint(canary.maxNewNodes) {
+		// During the canary stage only the canary nodes may start updating.
+		capacity = uint(canary.maxNewNodes)
+	}
+	if len(candidates) > 0 && capacity > 0 {
 		zones := make(map[string]bool)
 		for _, candidate := range candidates {
 			zone, ok := candidate.Labels[zoneLabel]
@@ -1377,8 +1424,8 @@ func (ctrl *Controller) syncMachineConfigPool(key string) error {
 			}
 		}
 		ctrl.logPool(pool, "%d candidate nodes in %d zones for update, capacity: %d", len(candidates), len(zones), capacity)
-		if err := ctrl.updateCandidateMachines(layered, mosc, mosb, pool, candidates, capacity); err != nil {
-			if syncErr := ctrl.syncStatusOnly(pool); syncErr != nil {
+		if err := ctrl.updateCandidateMachines(layered, mosc, mosb, pool, candidates, capacity, strategy); err != nil {
+			if syncErr := ctrl.syncStatus(pool, &canary); syncErr != nil {
 				errs := kubeErrs.NewAggregate([]error{syncErr, err})
 				return fmt.Errorf("error setting annotations for pool %q, sync error: %w", pool.Name, errs)
 			}
@@ -1387,7 +1434,7 @@ func (ctrl *Controller) syncMachineConfigPool(key string) error {
 		ctrlcommon.UpdateStateMetric(ctrlcommon.MCCSubControllerState, "machine-config-controller-node", "Sync Machine Config Pool", pool.Name)
 	}
 
-	if err := ctrl.syncStatusOnly(pool); err != nil {
+	if err := ctrl.syncStatus(pool, &canary); err != nil {
 		return err
 	}
 
@@ -1513,7 +1560,8 @@ func (ctrl *Controller) updateCandidateNode(mosc *mcfgv1.MachineOSConfig, mosb *
 
 // getAllCandidateMachines returns all possible nodes which can be updated to the target config, along with a maximum
 // capacity.  It is the reponsibility of the caller to choose a subset of the nodes given the capacity.
-func getAllCandidateMachines(layered bool, config *mcfgv1.MachineOSConfig, build *mcfgv1.MachineOSBuild, pool *mcfgv1.MachineConfigPool, nodesInPool []*corev1.Node, maxUnavailable int) ([]*corev1.Node, uint) {
+// The update strategy may further restrict which nodes are eligible at this point in the rollout.
+func getAllCandidateMachines(layered bool, config *mcfgv1.MachineOSConfig, build *mcfgv1.MachineOSBuild, pool *mcfgv1.MachineConfigPool, nodesInPool []*corev1.Node, maxUnavailable int, strategy nodeUpdateStrategy) ([]*corev1.Node, uint) {
 	unavail := getUnavailableMachines(nodesInPool)
 	if len(unavail) >= maxUnavailable {
 		klog.V(4).Infof("getAllCandidateMachines: No capacity left for pool %s (unavail=%d >= maxUnavailable=%d)",
@@ -1553,6 +1601,10 @@ func getAllCandidateMachines(layered bool, config *mcfgv1.MachineOSConfig, build
 	if capacity < 0 {
 		return nil, 0
 	}
+
+	nodes = strategy.filterCandidates(nodesInPool, nodes, func(node *corev1.Node) bool {
+		return ctrlcommon.NewLayeredNodeState(node).IsDone(pool, layered, config, build)
+	})
 	return nodes, uint(capacity)
 }
 
@@ -1698,7 +1750,7 @@ func (ctrl *Controller) applyCustomPoolLabels(node *corev1.Node, poolName string
 // SetDesiredStateFromPool in old mco explains how this works. Somehow you need to NOT FAIL if the mosb doesn't exist. So
 // we still need to base this whole things on pools but isLayeredPool == does mosb exist
 // updateCandidateMachines sets the desiredConfig annotation the candidate machines
-func (ctrl *Controller) updateCandidateMachines(layered bool, mosc *mcfgv1.MachineOSConfig, mosb *mcfgv1.MachineOSBuild, pool *mcfgv1.MachineConfigPool, candidates []*corev1.Node, capacity uint) error {
+func (ctrl *Controller) updateCandidateMachines(layered bool, mosc *mcfgv1.MachineOSConfig, mosb *mcfgv1.MachineOSBuild, pool *mcfgv1.MachineConfigPool, candidates []*corev1.Node, capacity uint, strategy nodeUpdateStrategy) error {
 	if pool.Name == ctrlcommon.MachineConfigPoolMaster {
 		var err error
 		candidates, capacity, err = ctrl.filterControlPlaneCandidateNodes(pool, candidates, capacity)
@@ -1714,10 +1766,11 @@ func (ctrl *Controller) updateCandidateMachines(layered bool, mosc *mcfgv1.Machi
 		return nil
 	}
 	if capacity < uint(len(candidates)) {
-		// when list is longer than maxUnavailable, rollout nodes in zone order, zones without zone label
-		// are done last from oldest to youngest. this reduces likelihood of randomly picking nodes
-		// across multiple zones that run the same types of pods resulting in an outage in HA clusters
-		candidates = sortNodeList(candidates)
+		// when list is longer than maxUnavailable, rollout nodes in the order given by the pool's
+		// update strategy. by default this is zone order, zones without zone label are done last
+		// from oldest to youngest. this reduces likelihood of randomly picking nodes across
+		// multiple zones that run the same types of pods resulting in an outage in HA clusters
+		candidates = strategy.sortCandidates(candidates)
 
 		candidates = candidates[:capacity]
 	}
@@ -40,6 +40,7 @@ import (
 	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
 	"github.com/openshift/machine-config-operator/pkg/version"
 	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
@@ -875,7 +876,7 @@ func TestGetCandidateMachines(t *testing.T) {
 
 			pool := helpers.NewMachineConfigPoolBuilder("").WithMachineConfig(machineConfigV1).MachineConfigPool()
 
-			allCandidates, capacity := getAllCandidateMachines(test.layered, test.mosc, test.mosb, pool, test.nodes, test.progress)
+			allCandidates, capacity := getAllCandidateMachines(test.layered, test.mosc, test.mosb, pool, test.nodes, test.progress, &zoneStrategy{})
 			assert.Equal(t, test.capacity, capacity)
 
 			var candidates, currentCandidates, otherCandidates []string
@@ -1324,7 +1325,7 @@ func TestShouldMakeProgress(t *testing.T) {
 				t.Logf("not expecting annotation")
 			}
 			c := f.newController()
-			expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, test.mosc, test.mosb)
+			expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, test.mosc, test.mosb, nil)
 			expMcp := mcp.DeepCopy()
 			expMcp.Status = expStatus
 			f.expectUpdateMachineConfigPoolStatus(expMcp)
@@ -1355,6 +1356,40 @@ func TestEmptyCurrentMachineConfig(t *testing.T) {
 	f.run(getKey(mcp, t))
 }
 
+func TestMaintenanceWindowClosed(t *testing.T) {
+	t.Parallel()
+	f := newFixture(t)
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, configv1.TopologyMode(""))
+	mcp := helpers.NewMachineConfigPool("maintenance-window-infra", nil, helpers.InfraSelector, machineConfigV1)
+	mcpWorker := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	mcp.Spec.MaxUnavailable = intStrPtr(intstr.FromInt(1))
+	// A window on another day of the week is closed now.
+	otherDay := (time.Now().UTC().Weekday() + 3) % 7
+	mcp.Annotations = map[string]string{ctrlcommon.MaintenanceWindowAnnotationKey: otherDay.String()[:3] + " 02:00-03:00 UTC"}
+	mcp.Status.MachineCount = 2
+	nodes := []*corev1.Node{
+		newNodeWithLabel("node-0", machineConfigV1, machineConfigV1, map[string]string{"node-role/worker": "", "node-role/infra": ""}),
+		newNodeWithLabel("node-1", machineConfigV1, machineConfigV1, map[string]string{"node-role/worker": "", "node-role/infra": ""}),
+	}
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcpWorker)
+	f.objects = append(f.objects, mcp, mcpWorker)
+	f.nodeLister = append(f.nodeLister, nodes...)
+	for idx := range nodes {
+		f.kubeobjects = append(f.kubeobjects, nodes[idx])
+	}
+	c := f.newController()
+	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
+	expMcp := mcp.DeepCopy()
+	expMcp.Status = expStatus
+	// The status and the metrics of the pool are updated while the window is closed.
+	f.expectUpdateMachineConfigPoolStatus(expMcp)
+	ctrlcommon.MCCMachineCount.WithLabelValues(mcp.Name).Set(0)
+	f.run(getKey(mcp, t))
+	assert.Equal(t, float64(2), testutil.ToFloat64(ctrlcommon.MCCMachineCount.WithLabelValues(mcp.Name)))
+}
+
 func TestPaused(t *testing.T) {
 	t.Parallel()
 	f := newFixture(t)
@@ -1376,7 +1411,7 @@ func TestPaused(t *testing.T) {
 		f.kubeobjects = append(f.kubeobjects, nodes[idx])
 	}
 	c := f.newController()
-	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	expMcp := mcp.DeepCopy()
 	expMcp.Status = expStatus
 	f.expectUpdateMachineConfigPoolStatus(expMcp)
@@ -1403,7 +1438,7 @@ func TestShouldUpdateStatusOnlyUpdated(t *testing.T) {
 		f.kubeobjects = append(f.kubeobjects, nodes[idx])
 	}
 	c := f.newController()
-	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	expMcp := mcp.DeepCopy()
 	expMcp.Status = expStatus
 	f.expectUpdateMachineConfigPoolStatus(expMcp)
@@ -1431,7 +1466,7 @@ func TestShouldUpdateStatusOnlyNoProgress(t *testing.T) {
 		f.kubeobjects = append(f.kubeobjects, nodes[idx])
 	}
 	c := f.newController()
-	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	expMcp := mcp.DeepCopy()
 	expMcp.Status = expStatus
 	f.expectUpdateMachineConfigPoolStatus(expMcp)
@@ -1464,7 +1499,7 @@ func TestCertStatus(t *testing.T) {
 		f.kubeobjects = append(f.kubeobjects, nodes[idx])
 	}
 	c := f.newController()
-	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	expStatus := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	expMcp := mcp.DeepCopy()
 	expMcp.Status = expStatus
 
@@ -1485,7 +1520,7 @@ func TestShouldDoNothing(t *testing.T) {
 		newNodeWithLabel("node-1", machineConfigV1, machineConfigV1, map[string]string{"node-role/worker": "", "node-role/infra": ""}),
 	}
 	c := f.newController()
-	status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	mcp.Status = status
 
 	f.ccLister = append(f.ccLister, cc)
@@ -1577,7 +1612,7 @@ func TestControlPlaneTopology(t *testing.T) {
 		addNodeAnnotations(node, annotations)
 	}
 	c := f.newController()
-	status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil)
+	status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, cc, mcp, nodes, nil, nil, nil)
 	mcp.Status = status
 
 	f.ccLister = append(f.ccLister, cc)
@@ -0,0 +1,170 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const rollbackDegradedNodesReason = "DegradedNodes"
+
+// isRolledBack returns true if the node controller rolled the pool back from
+// the config it currently targets.
+func isRolledBack(pool *mcfgv1.MachineConfigPool) bool {
+	from, ok := pool.Annotations[ctrlcommon.RolledBackFromAnnotationKey]
+	return ok && from == pool.Spec.Configuration.Name && pool.Status.Configuration.Name != ""
+}
+
+// getRollbackTargetPool returns the pool the node controller should roll out.
+// While a pool is rolled back, that is a copy of the pool targeting the last
+// config all of its nodes reached instead of its rendered config. The copy
+// must never be written back to the API.
+func getRollbackTargetPool(pool *mcfgv1.MachineConfigPool) *mcfgv1.MachineConfigPool {
+	if !isRolledBack(pool) {
+		return pool
+	}
+	target := pool.DeepCopy()
+	target.Spec.Configuration = pool.Status.Configuration
+	return target
+}
+
+// getDegradedNodesForConfig returns the nodes that are degraded or
+// unreconcilable while applying the given rendered config.
+func getDegradedNodesForConfig(nodes []*corev1.Node, config string) []*corev1.Node {
+	var degraded []*corev1.Node
+	for _, node := range nodes {
+		if node.Annotations[daemonconsts.DesiredMachineConfigAnnotationKey] != config {
+			continue
+		}
+		if ctrlcommon.NewLayeredNodeState(node).IsNodeMCDFailing() {
+			degraded = append(degraded, node)
+		}
+	}
+	return degraded
+}
+
+// getRollbackThreshold returns the number of degraded nodes at which the pool
+// is rolled back, or 0 if automatic rollback is disabled.
+func getRollbackThreshold(pool *mcfgv1.MachineConfigPool) int {
+	value, ok := pool.Annotations[ctrlcommon.RollbackDegradedNodesAnnotationKey]
+	if !ok {
+		return 0
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 1 {
+		klog.Warningf("Pool %s: ignoring invalid %s annotation %q, expected a positive integer", pool.Name, ctrlcommon.RollbackDegradedNodesAnnotationKey, value)
+		return 0
+	}
+	return threshold
+}
+
+// reconcileRollback rolls the pool back to the last config all of its nodes
+// reached once too many nodes went degraded applying its rendered config. It
+// returns the pool the rest of the sync should roll out.
+//
+// The nodes stuck on the bad config are moved back right away, since they
+// would otherwise block the rollout by counting against maxUnavailable; nodes
+// which already applied it are moved back as regular update candidates.
+func (ctrl *Controller) reconcileRollback(pool *mcfgv1.MachineConfigPool, nodes []*corev1.Node) (*mcfgv1.MachineConfigPool, error) {
+	if from, ok := pool.Annotations[ctrlcommon.RolledBackFromAnnotationKey]; ok && from != pool.Spec.Configuration.Name {
+		// The pool has moved on to a new config, so the rollback no longer applies.
+		klog.Infof("Pool %s: now targeting %s, clearing rollback from %s", pool.Name, pool.Spec.Configuration.Name, from)
+		return pool, ctrl.updateRollbackAnnotations(pool, "", "")
+	}
+	if isRolledBack(pool) {
+		return getRollbackTargetPool(pool), nil
+	}
+
+	threshold := getRollbackThreshold(pool)
+	previous := pool.Status.Configuration.Name
+	if threshold == 0 || previous == "" || previous == pool.Spec.Configuration.Name {
+		return pool, nil
+	}
+
+	degraded := getDegradedNodesForConfig(nodes, pool.Spec.Configuration.Name)
+	if len(degraded) < threshold {
+		return pool, nil
+	}
+
+	if _, err := ctrl.mcLister.Get(previous); err != nil {
+		return pool, fmt.Errorf("could not roll pool %q back to %s: %w", pool.Name, previous, err)
+	}
+
+	reasons := make([]string, 0, len(degraded))
+	for _, node := range degraded {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", node.Name, node.Annotations[daemonconsts.MachineConfigDaemonReasonAnnotationKey]))
+	}
+	sort.Strings(reasons)
+	reason := fmt.Sprintf("%d nodes degraded applying %s (%s)", len(degraded), pool.Spec.Configuration.Name, strings.Join(reasons, "; "))
+
+	klog.Warningf("Pool %s: rolling back to %s: %s", pool.Name, previous, reason)
+	if err := ctrl.updateRollbackAnnotations(pool, pool.Spec.Configuration.Name, reason); err != nil {
+		return pool, err
+	}
+	ctrl.eventRecorder.Eventf(pool, corev1.EventTypeWarning, "RolledBack", "Rolled back from %s to %s: %s", pool.Spec.Configuration.Name, previous, reason)
+
+	target := pool.DeepCopy()
+	target.Annotations[ctrlcommon.RolledBackFromAnnotationKey] = pool.Spec.Configuration.Name
+	target.Annotations[ctrlcommon.RollbackReasonAnnotationKey] = reason
+	target = getRollbackTargetPool(target)
+
+	var inProgress []*corev1.Node
+	for _, node := range nodes {
+		lns := ctrlcommon.NewLayeredNodeState(node)
+		if node.Annotations[daemonconsts.DesiredMachineConfigAnnotationKey] == pool.Spec.Configuration.Name && !lns.IsNodeDone() {
+			inProgress = append(inProgress, node)
+		}
+	}
+	if len(inProgress) > 0 {
+		if err := ctrl.setDesiredAnnotations(false, nil, nil, target, inProgress); err != nil {
+			return target, err
+		}
+	}
+	return target, nil
+}
+
+// updateRollbackAnnotations records the config the pool was rolled back from
+// and why, or clears the record if from is empty.
+func (ctrl *Controller) updateRollbackAnnotations(pool *mcfgv1.MachineConfigPool, from, reason string) error {
+	// Re-fetch the pool so that nothing but the annotations is written back.
+	newPool, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), pool.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		delete(newPool.Annotations, ctrlcommon.RolledBackFromAnnotationKey)
+		delete(newPool.Annotations, ctrlcommon.RollbackReasonAnnotationKey)
+	} else {
+		if newPool.Annotations == nil {
+			newPool.Annotations = map[string]string{}
+		}
+		newPool.Annotations[ctrlcommon.RolledBackFromAnnotationKey] = from
+		newPool.Annotations[ctrlcommon.RollbackReasonAnnotationKey] = reason
+	}
+	if _, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Update(context.TODO(), newPool, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update rollback annotations on MachineConfigPool %q: %w", pool.Name, err)
+	}
+	return nil
+}
+
+// setRolledBackCondition reports a rollback of the pool in its status, or
+// removes the condition if the pool is not rolled back.
+func setRolledBackCondition(status *mcfgv1.MachineConfigPoolStatus, pool *mcfgv1.MachineConfigPool) {
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolRolledBack)
+	if !isRolledBack(pool) {
+		apihelpers.RemoveMachineConfigPoolCondition(status, condType)
+		return
+	}
+	msg := fmt.Sprintf("Rolled back from %s to %s: %s", pool.Spec.Configuration.Name, pool.Status.Configuration.Name, pool.Annotations[ctrlcommon.RollbackReasonAnnotationKey])
+	apihelpers.SetMachineConfigPoolCondition(status, *apihelpers.NewMachineConfigPoolCondition(condType, corev1.ConditionTrue, rollbackDegradedNodesReason, msg))
+}
@@ -0,0 +1,160 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	informers "github.com/openshift/client-go/machineconfiguration/informers/externalversions"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func newRollbackTestPool(annotations map[string]string) *mcfgv1.MachineConfigPool {
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	pool.Status.Configuration.Name = machineConfigV0
+	pool.Annotations = annotations
+	return pool
+}
+
+func newRollbackTestController(t *testing.T, pool *mcfgv1.MachineConfigPool, nodes []*corev1.Node) *Controller {
+	t.Helper()
+
+	kubeObjects := make([]runtime.Object, 0, len(nodes))
+	for _, node := range nodes {
+		kubeObjects = append(kubeObjects, node)
+	}
+
+	client := fake.NewSimpleClientset(pool)
+	i := informers.NewSharedInformerFactory(client, noResyncPeriodFunc())
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigs().Informer().GetIndexer().Add(helpers.NewMachineConfig(machineConfigV0, nil, "", nil)))
+
+	return &Controller{
+		client:        client,
+		kubeClient:    k8sfake.NewSimpleClientset(kubeObjects...),
+		eventRecorder: record.NewFakeRecorder(10),
+		mcLister:      i.Machineconfiguration().V1().MachineConfigs().Lister(),
+	}
+}
+
+func newDegradedNode(name string) *corev1.Node {
+	node := newNodeWithReadyAndDaemonState(name, machineConfigV0, machineConfigV1, corev1.ConditionTrue, daemonconsts.MachineConfigDaemonStateDegraded)
+	node.Annotations[daemonconsts.MachineConfigDaemonReasonAnnotationKey] = "failed to write file"
+	return node
+}
+
+func TestReconcileRollback(t *testing.T) {
+	t.Parallel()
+
+	done := daemonconsts.MachineConfigDaemonStateDone
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		pool := newRollbackTestPool(nil)
+		nodes := []*corev1.Node{newDegradedNode("node-0"), newDegradedNode("node-1")}
+		ctrl := newRollbackTestController(t, pool, nodes)
+
+		target, err := ctrl.reconcileRollback(pool, nodes)
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV1, target.Spec.Configuration.Name)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		t.Parallel()
+		pool := newRollbackTestPool(map[string]string{ctrlcommon.RollbackDegradedNodesAnnotationKey: "2"})
+		nodes := []*corev1.Node{
+			newDegradedNode("node-0"),
+			newNodeWithReadyAndDaemonState("node-1", machineConfigV0, machineConfigV0, corev1.ConditionTrue, done),
+		}
+		ctrl := newRollbackTestController(t, pool, nodes)
+
+		target, err := ctrl.reconcileRollback(pool, nodes)
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV1, target.Spec.Configuration.Name)
+	})
+
+	t.Run("rolls back", func(t *testing.T) {
+		t.Parallel()
+		pool := newRollbackTestPool(map[string]string{ctrlcommon.RollbackDegradedNodesAnnotationKey: "2"})
+		nodes := []*corev1.Node{
+			newDegradedNode("node-0"),
+			newDegradedNode("node-1"),
+			newNodeWithReadyAndDaemonState("node-2", machineConfigV1, machineConfigV1, corev1.ConditionTrue, done),
+			newNodeWithReadyAndDaemonState("node-3", machineConfigV0, machineConfigV0, corev1.ConditionTrue, done),
+		}
+		ctrl := newRollbackTestController(t, pool, nodes)
+
+		target, err := ctrl.reconcileRollback(pool, nodes)
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV0, target.Spec.Configuration.Name)
+
+		updated, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), pool.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV1, updated.Spec.Configuration.Name)
+		assert.Equal(t, machineConfigV1, updated.Annotations[ctrlcommon.RolledBackFromAnnotationKey])
+		assert.Contains(t, updated.Annotations[ctrlcommon.RollbackReasonAnnotationKey], "node-0: failed to write file")
+
+		// The degraded nodes are moved back right away; the updated node is
+		// left for the regular rollout.
+		for name, expected := range map[string]string{"node-0": machineConfigV0, "node-1": machineConfigV0, "node-2": machineConfigV1} {
+			node, err := ctrl.kubeClient.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, expected, node.Annotations[daemonconsts.DesiredMachineConfigAnnotationKey], name)
+		}
+
+		// Subsequent syncs keep targeting the previous config.
+		target, err = ctrl.reconcileRollback(updated, nodes)
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV0, target.Spec.Configuration.Name)
+	})
+
+	t.Run("new config clears the rollback", func(t *testing.T) {
+		t.Parallel()
+		pool := newRollbackTestPool(map[string]string{
+			ctrlcommon.RollbackDegradedNodesAnnotationKey: "2",
+			ctrlcommon.RolledBackFromAnnotationKey:        machineConfigV2,
+			ctrlcommon.RollbackReasonAnnotationKey:        "2 nodes degraded",
+		})
+		ctrl := newRollbackTestController(t, pool, nil)
+
+		target, err := ctrl.reconcileRollback(pool, nil)
+		require.NoError(t, err)
+		assert.Equal(t, machineConfigV1, target.Spec.Configuration.Name)
+
+		updated, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), pool.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.NotContains(t, updated.Annotations, ctrlcommon.RolledBackFromAnnotationKey)
+		assert.NotContains(t, updated.Annotations, ctrlcommon.RollbackReasonAnnotationKey)
+	})
+}
+
+func TestSetRolledBackCondition(t *testing.T) {
+	t.Parallel()
+
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolRolledBack)
+	pool := newRollbackTestPool(map[string]string{
+		ctrlcommon.RolledBackFromAnnotationKey: machineConfigV1,
+		ctrlcommon.RollbackReasonAnnotationKey: "2 nodes degraded",
+	})
+	status := mcfgv1.MachineConfigPoolStatus{}
+
+	setRolledBackCondition(&status, pool)
+	cond := apihelpers.GetMachineConfigPoolCondition(status, condType)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+	assert.Equal(t, "Rolled back from rendered-machine-config-v1 to rendered-machine-config-v0: 2 nodes degraded", cond.Message)
+
+	pool.Spec.Configuration.Name = machineConfigV2
+	setRolledBackCondition(&status, pool)
+	assert.Nil(t, apihelpers.GetMachineConfigPoolCondition(status, condType))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -21,6 +22,13 @@ import (
 
 // syncStatusOnly for MachineConfigNode
 func (ctrl *Controller) syncStatusOnly(pool *mcfgv1.MachineConfigPool) error {
+	return ctrl.syncStatus(pool, nil)
+}
+
+// syncStatus updates the status of the pool. canary is the state of the
+// pool's canary stage if this sync evaluated it; only syncMachineConfigPool
+// does, so that its health checks are not run by every status sync.
+func (ctrl *Controller) syncStatus(pool *mcfgv1.MachineConfigPool, canary *canaryStatus) error {
 	cc, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
 	if err != nil {
 		return err
@@ -52,7 +60,7 @@ func (ctrl *Controller) syncStatusOnly(pool *mcfgv1.MachineConfigPool) error {
 		return fmt.Errorf("could get MachineOSConfig or MachineOSBuild: %w", err)
 	}
 
-	newStatus := ctrl.calculateStatus(machineConfigStates, cc, freshPool, nodes, mosc, mosb)
+	newStatus := ctrl.calculateStatus(machineConfigStates, cc, freshPool, nodes, mosc, mosb, canary)
 	if equality.Semantic.DeepEqual(freshPool.Status, newStatus) {
 		return nil
 	}
@@ -73,10 +81,12 @@ func (ctrl *Controller) syncStatusOnly(pool *mcfgv1.MachineConfigPool) error {
 	return err
 }
 
-// `calculateStatus` calculates the MachineConfigPoolStatus object for the desired MCP
+// `calculateStatus` calculates the MachineConfigPoolStatus object for the desired MCP.
+// The canary condition is only updated if canary is given, as evaluating the
+// canary stage runs its health checks; otherwise the last one is kept.
 //
 //nolint:gocyclo,gosec
-func (ctrl *Controller) calculateStatus(mcns []*mcfgv1.MachineConfigNode, cconfig *mcfgv1.ControllerConfig, pool *mcfgv1.MachineConfigPool, nodes []*corev1.Node, mosc *mcfgv1.MachineOSConfig, mosb *mcfgv1.MachineOSBuild) mcfgv1.MachineConfigPoolStatus {
+func (ctrl *Controller) calculateStatus(mcns []*mcfgv1.MachineConfigNode, cconfig *mcfgv1.ControllerConfig, pool *mcfgv1.MachineConfigPool, nodes []*corev1.Node, mosc *mcfgv1.MachineOSConfig, mosb *mcfgv1.MachineOSBuild, canary *canaryStatus) mcfgv1.MachineConfigPoolStatus {
 	// Get the `CertExpiry` details for the MCP status
 	certExpirys := []mcfgv1.CertExpiry{}
 	if cconfig != nil {
@@ -92,6 +102,11 @@ func (ctrl *Controller) calculateStatus(mcns []*mcfgv1.MachineConfigNode, cconfi
 		}
 	}
 
+	// While the pool is rolled back, report progress towards the config it was
+	// rolled back to rather than towards its rendered config.
+	rolledBackPool := pool
+	pool = getRollbackTargetPool(pool)
+
 	// Get total machine count and initialize pool synchronizer for MCP
 	totalMachineCount := int32(len(nodes))
 	poolSynchronizer := newPoolSynchronizer(totalMachineCount)
@@ -231,6 +246,13 @@ func (ctrl *Controller) calculateStatus(mcns []*mcfgv1.MachineConfigNode, cconfi
 	status.Configuration = pool.Status.Configuration
 	conditions := pool.Status.Conditions
 	status.Conditions = append(status.Conditions, conditions...)
+	setMaintenanceWindowCondition(&status, pool, time.Now())
+	setRolledBackCondition(&status, rolledBackPool)
+	if canary != nil {
+		setCanaryCondition(&status, *canary)
+	} else if _, ok := pool.Annotations[ctrlcommon.CanaryNodesAnnotationKey]; !ok {
+		setCanaryCondition(&status, canaryStatus{maxNewNodes: -1})
+	}
 
 	// Determine if all machines are updated and
 	// 	- If all machines are updated, set "Updated" condition to true and "Updating" condition to false
@@ -1092,7 +1092,7 @@ func TestCalculateStatus(t *testing.T) {
 				c = f.newController()
 			}
 
-			status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, nil, pool, test.nodes, nil, nil)
+			status := c.calculateStatus([]*mcfgv1.MachineConfigNode{}, nil, pool, test.nodes, nil, nil, nil)
 			test.verify(status, t)
 		})
 	}
@@ -1521,7 +1521,7 @@ func TestCalculateStatusWithImageModeReporting(t *testing.T) {
 			mosb := helpers.NewMachineOSBuildBuilder("mosb-1").WithDesiredConfig(test.currentConfig).MachineOSBuild()
 
 			c := f.newController()
-			status := c.calculateStatus(test.mcns, nil, pool, test.nodes, mosc, mosb)
+			status := c.calculateStatus(test.mcns, nil, pool, test.nodes, mosc, mosb, nil)
 			test.verify(status, t)
 		})
 	}
@@ -0,0 +1,258 @@
+package node
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisterv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// updateStrategyType is the value of the UpdateStrategyAnnotationKey annotation on a MachineConfigPool.
+type updateStrategyType string
+
+const (
+	// updateStrategyZone rolls out nodes in zone order, then from oldest to youngest. Multiple
+	// zones may be updating at the same time. This is the default.
+	updateStrategyZone updateStrategyType = "Zone"
+	// updateStrategyZoneByZone only starts updating nodes in a zone once every node in the
+	// previous zones has finished updating.
+	updateStrategyZoneByZone updateStrategyType = "ZoneByZone"
+	// updateStrategyLeastLoaded updates the nodes running the fewest non-daemonset pods first.
+	updateStrategyLeastLoaded updateStrategyType = "LeastLoaded"
+	// updateStrategyNodeLabelPriority updates nodes by the integer priority found in a node
+	// label, lowest first.
+	updateStrategyNodeLabelPriority updateStrategyType = "NodeLabelPriority"
+	// updateStrategyOrdered updates the nodes listed in the UpdateOrderAnnotationKey annotation
+	// first, in the given order.
+	updateStrategyOrdered updateStrategyType = "Ordered"
+)
+
+// nodeUpdateStrategy decides which of a pool's candidate nodes may be moved to
+// the new config and in which order.
+type nodeUpdateStrategy interface {
+	// filterCandidates narrows down the candidates that may start updating right
+	// now. isDone reports whether a node in the pool has finished updating.
+	filterCandidates(nodesInPool, candidates []*corev1.Node, isDone func(*corev1.Node) bool) []*corev1.Node
+	// sortCandidates orders the candidates so that the nodes which should be
+	// updated first come first.
+	sortCandidates(candidates []*corev1.Node) []*corev1.Node
+}
+
+// newNodeUpdateStrategy returns the nodeUpdateStrategy selected by the pool's
+// UpdateStrategyAnnotationKey annotation, falling back to the zone strategy.
+func newNodeUpdateStrategy(pool *mcfgv1.MachineConfigPool, podLister corelisterv1.PodLister) nodeUpdateStrategy {
+	strategy := updateStrategyType(pool.Annotations[ctrlcommon.UpdateStrategyAnnotationKey])
+	switch strategy {
+	case "", updateStrategyZone:
+		return &zoneStrategy{}
+	case updateStrategyZoneByZone:
+		return &zoneByZoneStrategy{}
+	case updateStrategyLeastLoaded:
+		return &leastLoadedStrategy{podLister: podLister}
+	case updateStrategyNodeLabelPriority:
+		label := pool.Annotations[ctrlcommon.UpdatePriorityLabelAnnotationKey]
+		if label == "" {
+			label = ctrlcommon.UpdatePriorityNodeLabel
+		}
+		return &nodeLabelPriorityStrategy{label: label}
+	case updateStrategyOrdered:
+		return newOrderedStrategy(pool.Annotations[ctrlcommon.UpdateOrderAnnotationKey])
+	default:
+		klog.Warningf("Pool %s: unknown update strategy %q, using %s", pool.Name, strategy, updateStrategyZone)
+		return &zoneStrategy{}
+	}
+}
+
+// zoneStrategy is the historical ordering of the node controller; see sortNodeList.
+type zoneStrategy struct{}
+
+func (s *zoneStrategy) filterCandidates(_, candidates []*corev1.Node, _ func(*corev1.Node) bool) []*corev1.Node {
+	return candidates
+}
+
+func (s *zoneStrategy) sortCandidates(candidates []*corev1.Node) []*corev1.Node {
+	return sortNodeList(candidates)
+}
+
+// zoneByZoneStrategy finishes a full zone before starting the next one. Zones
+// are processed in name order, and nodes without a zone label are handled last.
+type zoneByZoneStrategy struct{}
+
+func (s *zoneByZoneStrategy) filterCandidates(nodesInPool, candidates []*corev1.Node, isDone func(*corev1.Node) bool) []*corev1.Node {
+	pending := map[string]bool{}
+	for _, node := range nodesInPool {
+		if !isDone(node) {
+			pending[node.Labels[zoneLabel]] = true
+		}
+	}
+	if len(pending) == 0 {
+		return candidates
+	}
+
+	zones := make([]string, 0, len(pending))
+	for zone := range pending {
+		zones = append(zones, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zoneLess(zones[i], zones[j]) })
+	activeZone := zones[0]
+
+	var out []*corev1.Node
+	for _, node := range candidates {
+		if node.Labels[zoneLabel] == activeZone {
+			out = append(out, node)
+		}
+	}
+	klog.V(4).Infof("Updating zone %q: %d of %d candidates selected", activeZone, len(out), len(candidates))
+	return out
+}
+
+func (s *zoneByZoneStrategy) sortCandidates(candidates []*corev1.Node) []*corev1.Node {
+	return sortNodeList(candidates)
+}
+
+// zoneLess orders zone names alphabetically, with the empty (unlabeled) zone last.
+func zoneLess(i, j string) bool {
+	if i == "" || j == "" {
+		return j == "" && i != ""
+	}
+	return i < j
+}
+
+// leastLoadedStrategy updates the nodes running the fewest non-daemonset pods
+// first, so that the fewest workloads are disrupted by each batch.
+type leastLoadedStrategy struct {
+	podLister corelisterv1.PodLister
+}
+
+func (s *leastLoadedStrategy) filterCandidates(_, candidates []*corev1.Node, _ func(*corev1.Node) bool) []*corev1.Node {
+	return candidates
+}
+
+func (s *leastLoadedStrategy) sortCandidates(candidates []*corev1.Node) []*corev1.Node {
+	load, err := s.getNodeLoad()
+	if err != nil {
+		klog.Warningf("Could not determine node load, falling back to zone ordering: %v", err)
+		return sortNodeList(candidates)
+	}
+
+	// Start from the zone ordering so that ties are broken deterministically.
+	candidates = sortNodeList(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return load[candidates[i].Name] < load[candidates[j].Name]
+	})
+	return candidates
+}
+
+// getNodeLoad returns the number of running non-daemonset pods per node name.
+func (s *leastLoadedStrategy) getNodeLoad() (map[string]int, error) {
+	pods, err := s.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	load := map[string]int{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if isDaemonSetPod(pod) {
+			continue
+		}
+		load[pod.Spec.NodeName]++
+	}
+	return load, nil
+}
+
+// isDaemonSetPod returns true if the pod is controlled by a DaemonSet; those
+// pods are not evicted by the drain so they do not count towards the load.
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeLabelPriorityStrategy updates nodes by the integer value of a node
+// label, lowest first. Nodes without a valid priority are updated last.
+type nodeLabelPriorityStrategy struct {
+	label string
+}
+
+func (s *nodeLabelPriorityStrategy) filterCandidates(_, candidates []*corev1.Node, _ func(*corev1.Node) bool) []*corev1.Node {
+	return candidates
+}
+
+func (s *nodeLabelPriorityStrategy) sortCandidates(candidates []*corev1.Node) []*corev1.Node {
+	candidates = sortNodeList(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iPriority, iOk := s.getPriority(candidates[i])
+		jPriority, jOk := s.getPriority(candidates[j])
+		switch {
+		case iOk && jOk:
+			return iPriority < jPriority
+		default:
+			return iOk && !jOk
+		}
+	})
+	return candidates
+}
+
+func (s *nodeLabelPriorityStrategy) getPriority(node *corev1.Node) (int, bool) {
+	val, ok := node.Labels[s.label]
+	if !ok {
+		return 0, false
+	}
+	priority, err := strconv.Atoi(val)
+	if err != nil {
+		klog.V(4).Infof("Ignoring invalid update priority %q on node %s: %v", val, node.Name, err)
+		return 0, false
+	}
+	return priority, true
+}
+
+// orderedStrategy updates the listed nodes first, in the given order. Nodes
+// that are not listed are updated afterwards in zone order.
+type orderedStrategy struct {
+	order map[string]int
+}
+
+func newOrderedStrategy(order string) *orderedStrategy {
+	s := &orderedStrategy{order: map[string]int{}}
+	for _, name := range strings.Split(order, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := s.order[name]; !ok {
+			s.order[name] = len(s.order)
+		}
+	}
+	return s
+}
+
+func (s *orderedStrategy) filterCandidates(_, candidates []*corev1.Node, _ func(*corev1.Node) bool) []*corev1.Node {
+	return candidates
+}
+
+func (s *orderedStrategy) sortCandidates(candidates []*corev1.Node) []*corev1.Node {
+	candidates = sortNodeList(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iPos, iOk := s.order[candidates[i].Name]
+		jPos, jOk := s.order[candidates[j].Name]
+		switch {
+		case iOk && jOk:
+			return iPos < jPos
+		default:
+			return iOk && !jOk
+		}
+	})
+	return candidates
+}
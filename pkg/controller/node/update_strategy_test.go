@@ -0,0 +1,172 @@
+package node
+
+import (
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func newStrategyPool(annotations map[string]string) *mcfgv1.MachineConfigPool {
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, machineConfigV1)
+	pool.Annotations = annotations
+	return pool
+}
+
+func TestNewNodeUpdateStrategy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    nodeUpdateStrategy
+	}{
+		{
+			name:     "default",
+			expected: &zoneStrategy{},
+		},
+		{
+			name:        "zone by zone",
+			annotations: map[string]string{ctrlcommon.UpdateStrategyAnnotationKey: "ZoneByZone"},
+			expected:    &zoneByZoneStrategy{},
+		},
+		{
+			name:        "node label priority with default label",
+			annotations: map[string]string{ctrlcommon.UpdateStrategyAnnotationKey: "NodeLabelPriority"},
+			expected:    &nodeLabelPriorityStrategy{label: ctrlcommon.UpdatePriorityNodeLabel},
+		},
+		{
+			name: "node label priority with custom label",
+			annotations: map[string]string{
+				ctrlcommon.UpdateStrategyAnnotationKey:      "NodeLabelPriority",
+				ctrlcommon.UpdatePriorityLabelAnnotationKey: "example.com/priority",
+			},
+			expected: &nodeLabelPriorityStrategy{label: "example.com/priority"},
+		},
+		{
+			name: "ordered",
+			annotations: map[string]string{
+				ctrlcommon.UpdateStrategyAnnotationKey: "Ordered",
+				ctrlcommon.UpdateOrderAnnotationKey:    "node-2, node-0,,node-2",
+			},
+			expected: &orderedStrategy{order: map[string]int{"node-2": 0, "node-0": 1}},
+		},
+		{
+			name:        "unknown falls back to zone",
+			annotations: map[string]string{ctrlcommon.UpdateStrategyAnnotationKey: "Random"},
+			expected:    &zoneStrategy{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, newNodeUpdateStrategy(newStrategyPool(test.annotations), nil))
+		})
+	}
+}
+
+func TestZoneByZoneStrategy(t *testing.T) {
+	t.Parallel()
+
+	nodeA0 := newNodeWithLabel("node-a0", machineConfigV1, machineConfigV1, map[string]string{zoneLabel: "a"})
+	nodeA1 := newNodeWithLabel("node-a1", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "a"})
+	nodeB0 := newNodeWithLabel("node-b0", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "b"})
+	nodeNone := newNode("node-none", machineConfigV0, machineConfigV0)
+
+	done := map[string]bool{"node-a0": true}
+	isDone := func(node *corev1.Node) bool { return done[node.Name] }
+	nodes := []*corev1.Node{nodeNone, nodeB0, nodeA1, nodeA0}
+
+	s := &zoneByZoneStrategy{}
+
+	// Zone "a" is not finished yet, so only its nodes are eligible.
+	assert.Equal(t, []string{"node-a1"}, helpers.GetNamesFromNodes(s.filterCandidates(nodes, []*corev1.Node{nodeNone, nodeB0, nodeA1}, isDone)))
+
+	// Once zone "a" is done, zone "b" is next.
+	done["node-a1"] = true
+	assert.Equal(t, []string{"node-b0"}, helpers.GetNamesFromNodes(s.filterCandidates(nodes, []*corev1.Node{nodeNone, nodeB0}, isDone)))
+
+	// Unlabeled nodes come last.
+	done["node-b0"] = true
+	assert.Equal(t, []string{"node-none"}, helpers.GetNamesFromNodes(s.filterCandidates(nodes, []*corev1.Node{nodeNone}, isDone)))
+}
+
+func TestLeastLoadedStrategy(t *testing.T) {
+	t.Parallel()
+
+	newPod := func(name, nodeName string, phase corev1.PodPhase, ownerKind string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: ptr.To(true)}}
+		}
+		return pod
+	}
+
+	pods := []*corev1.Pod{
+		newPod("p0", "node-0", corev1.PodRunning, "ReplicaSet"),
+		newPod("p1", "node-0", corev1.PodRunning, ""),
+		newPod("p2", "node-1", corev1.PodRunning, "ReplicaSet"),
+		newPod("p3", "node-2", corev1.PodRunning, "DaemonSet"),
+		newPod("p4", "node-2", corev1.PodSucceeded, ""),
+		newPod("p5", "node-2", corev1.PodFailed, ""),
+	}
+
+	kubeInformer := informers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), noResyncPeriodFunc())
+	for _, pod := range pods {
+		assert.NoError(t, kubeInformer.Core().V1().Pods().Informer().GetIndexer().Add(pod))
+	}
+
+	s := &leastLoadedStrategy{podLister: kubeInformer.Core().V1().Pods().Lister()}
+	nodes := []*corev1.Node{
+		newNode("node-0", machineConfigV0, machineConfigV0),
+		newNode("node-1", machineConfigV0, machineConfigV0),
+		newNode("node-2", machineConfigV0, machineConfigV0),
+	}
+
+	assert.Equal(t, []string{"node-2", "node-1", "node-0"}, helpers.GetNamesFromNodes(s.sortCandidates(nodes)))
+}
+
+func TestNodeLabelPriorityStrategy(t *testing.T) {
+	t.Parallel()
+
+	label := ctrlcommon.UpdatePriorityNodeLabel
+	nodes := []*corev1.Node{
+		newNodeWithLabel("node-0", machineConfigV0, machineConfigV0, map[string]string{label: "10"}),
+		newNode("node-1", machineConfigV0, machineConfigV0),
+		newNodeWithLabel("node-2", machineConfigV0, machineConfigV0, map[string]string{label: "-1"}),
+		newNodeWithLabel("node-3", machineConfigV0, machineConfigV0, map[string]string{label: "high"}),
+		newNodeWithLabel("node-4", machineConfigV0, machineConfigV0, map[string]string{label: "5"}),
+	}
+
+	s := &nodeLabelPriorityStrategy{label: label}
+	sorted := helpers.GetNamesFromNodes(s.sortCandidates(nodes))
+	assert.Equal(t, []string{"node-2", "node-4", "node-0"}, sorted[:3])
+	assert.ElementsMatch(t, []string{"node-1", "node-3"}, sorted[3:])
+}
+
+func TestOrderedStrategy(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*corev1.Node{
+		newNodeWithLabel("node-0", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "a"}),
+		newNodeWithLabel("node-1", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "b"}),
+		newNodeWithLabel("node-2", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "c"}),
+		newNodeWithLabel("node-3", machineConfigV0, machineConfigV0, map[string]string{zoneLabel: "d"}),
+	}
+
+	s := newOrderedStrategy("node-3,node-1,node-unknown")
+	assert.Equal(t, []string{"node-3", "node-1", "node-0", "node-2"}, helpers.GetNamesFromNodes(s.sortCandidates(nodes)))
+}
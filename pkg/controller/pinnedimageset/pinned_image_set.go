@@ -13,8 +13,10 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformersv1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -25,6 +27,8 @@ import (
 	"github.com/openshift/client-go/machineconfiguration/clientset/versioned/scheme"
 	mcfginformersv1 "github.com/openshift/client-go/machineconfiguration/informers/externalversions/machineconfiguration/v1"
 	mcfglistersv1 "github.com/openshift/client-go/machineconfiguration/listers/machineconfiguration/v1"
+	mcopinformersv1 "github.com/openshift/client-go/operator/informers/externalversions/operator/v1"
+	mcoplistersv1 "github.com/openshift/client-go/operator/listers/operator/v1"
 	"github.com/openshift/machine-config-operator/pkg/apihelpers"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
 )
@@ -44,6 +48,7 @@ const (
 // Controller defines the pinned image set controller.
 type Controller struct {
 	client        mcfgclientset.Interface
+	kubeClient    clientset.Interface
 	eventRecorder record.EventRecorder
 
 	syncHandler              func(mcp string) error
@@ -55,6 +60,12 @@ type Controller struct {
 	imageSetLister mcfglistersv1.PinnedImageSetLister
 	imageSetSynced cache.InformerSynced
 
+	nodeLister       corev1listers.NodeLister
+	nodeListerSynced cache.InformerSynced
+
+	mcopLister       mcoplistersv1.MachineConfigurationLister
+	mcopListerSynced cache.InformerSynced
+
 	queue workqueue.TypedRateLimitingInterface[string]
 }
 
@@ -62,6 +73,8 @@ type Controller struct {
 func New(
 	imageSetInformer mcfginformersv1.PinnedImageSetInformer,
 	mcpInformer mcfginformersv1.MachineConfigPoolInformer,
+	nodeInformer coreinformersv1.NodeInformer,
+	mcopInformer mcopinformersv1.MachineConfigurationInformer,
 	kubeClient clientset.Interface,
 	mcfgClient mcfgclientset.Interface,
 ) *Controller {
@@ -71,13 +84,14 @@ func New(
 
 	ctrl := &Controller{
 		client:        mcfgClient,
+		kubeClient:    kubeClient,
 		eventRecorder: ctrlcommon.NamespacedEventRecorder(eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "machineconfigcontroller-pinnedimagesetcontroller"})),
 		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
 			workqueue.DefaultTypedControllerRateLimiter[string](),
 			workqueue.TypedRateLimitingQueueConfig[string]{Name: "machineconfigcontroller-pinnedimagesetcontroller"}),
 	}
 
-	ctrl.syncHandler = ctrl.syncMachineConfigPool
+	ctrl.syncHandler = ctrl.sync
 	ctrl.enqueueMachineConfigPool = ctrl.enqueueDefault
 
 	// this must be done after the enqueueMachineConfigPool is configured to
@@ -97,9 +111,26 @@ func New(
 	ctrl.mcpLister = mcpInformer.Lister()
 	ctrl.mcpListerSynced = mcpInformer.Informer().HasSynced
 
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.addNode,
+		UpdateFunc: ctrl.updateNode,
+		DeleteFunc: ctrl.deleteNode,
+	})
+
+	mcopInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(_ interface{}) { ctrl.enqueuePrefetchSlots() },
+		UpdateFunc: ctrl.updateMachineConfiguration,
+	})
+
 	ctrl.imageSetLister = imageSetInformer.Lister()
 	ctrl.imageSetSynced = imageSetInformer.Informer().HasSynced
 
+	ctrl.nodeLister = nodeInformer.Lister()
+	ctrl.nodeListerSynced = nodeInformer.Informer().HasSynced
+
+	ctrl.mcopLister = mcopInformer.Lister()
+	ctrl.mcopListerSynced = mcopInformer.Informer().HasSynced
+
 	return ctrl
 }
 
@@ -108,7 +139,7 @@ func (ctrl *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer ctrl.queue.ShutDown()
 
-	if !cache.WaitForCacheSync(stopCh, ctrl.mcpListerSynced, ctrl.imageSetSynced) {
+	if !cache.WaitForCacheSync(stopCh, ctrl.mcpListerSynced, ctrl.imageSetSynced, ctrl.nodeListerSynced, ctrl.mcopListerSynced) {
 		return
 	}
 
@@ -307,6 +338,13 @@ func (ctrl *Controller) handleErr(err error, key string) {
 	ctrl.queue.AddAfter(key, 1*time.Minute)
 }
 
+func (ctrl *Controller) sync(key string) error {
+	if key == prefetchSlotsKey {
+		return ctrl.syncPrefetchSlots()
+	}
+	return ctrl.syncMachineConfigPool(key)
+}
+
 // syncMachineConfigPool will sync the machineconfig pool with the given key.
 // This function is not meant to be invoked concurrently with the same key.
 func (ctrl *Controller) syncMachineConfigPool(key string) error {
@@ -8,10 +8,13 @@ import (
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 
 	fakemco "github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
 	mcfginformers "github.com/openshift/client-go/machineconfiguration/informers/externalversions"
+	fakeoperatorclient "github.com/openshift/client-go/operator/clientset/versioned/fake"
+	operatorinformer "github.com/openshift/client-go/operator/informers/externalversions"
 	"github.com/openshift/machine-config-operator/test/helpers"
 	"github.com/stretchr/testify/require"
 )
@@ -78,7 +81,10 @@ func TestSyncHandler(t *testing.T) {
 				require.NoError(err)
 			}
 
-			c := New(imageSetInformer, mcpInformer, fakeClient, fakeMCOClient)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(fakeClient, noResyncPeriodFunc())
+			operatorInformers := operatorinformer.NewSharedInformerFactory(fakeoperatorclient.NewSimpleClientset(), noResyncPeriodFunc())
+
+			c := New(imageSetInformer, mcpInformer, kubeInformers.Core().V1().Nodes(), operatorInformers.Operator().V1().MachineConfigurations(), fakeClient, fakeMCOClient)
 			mcp, ok := tt.machineConfigPool.(*mcfgv1.MachineConfigPool)
 			require.True(ok)
 
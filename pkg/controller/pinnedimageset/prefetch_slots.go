@@ -0,0 +1,170 @@
+package pinnedimageset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/machine-config-operator/internal"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+// prefetchSlotsKey is the queue key for granting prefetch slots to the nodes
+// of the cluster. It can not clash with a pool name, which may not contain ':'.
+const prefetchSlotsKey = "prefetch-slots:"
+
+func (ctrl *Controller) enqueuePrefetchSlots() {
+	ctrl.queue.Add(prefetchSlotsKey)
+}
+
+func (ctrl *Controller) addNode(obj interface{}) {
+	node := obj.(*corev1.Node)
+	if _, ok := node.Annotations[daemonconsts.PrefetchRequestedAnnotationKey]; ok {
+		ctrl.enqueuePrefetchSlots()
+	}
+}
+
+func (ctrl *Controller) updateNode(old, cur interface{}) {
+	oldNode := old.(*corev1.Node)
+	curNode := cur.(*corev1.Node)
+
+	for _, key := range []string{daemonconsts.PrefetchRequestedAnnotationKey, daemonconsts.PrefetchGrantedAnnotationKey} {
+		if oldNode.Annotations[key] != curNode.Annotations[key] {
+			ctrl.enqueuePrefetchSlots()
+			return
+		}
+	}
+}
+
+func (ctrl *Controller) deleteNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("failed to get object from tombstone %#v", obj))
+			return
+		}
+		node, ok = tombstone.Obj.(*corev1.Node)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a Node %#v", obj))
+			return
+		}
+	}
+	// a deleted node may free a slot
+	if _, ok := node.Annotations[daemonconsts.PrefetchGrantedAnnotationKey]; ok {
+		ctrl.enqueuePrefetchSlots()
+	}
+}
+
+func (ctrl *Controller) updateMachineConfiguration(old, cur interface{}) {
+	oldMcop := old.(*opv1.MachineConfiguration)
+	curMcop := cur.(*opv1.MachineConfiguration)
+	if oldMcop.Annotations[ctrlcommon.PrefetchMaxNodesAnnotationKey] != curMcop.Annotations[ctrlcommon.PrefetchMaxNodesAnnotationKey] {
+		ctrl.enqueuePrefetchSlots()
+	}
+}
+
+// getMaxPrefetchNodes returns the maximum number of nodes which may prefetch
+// at once, 0 if unlimited.
+func (ctrl *Controller) getMaxPrefetchNodes() (int, error) {
+	mcop, err := ctrl.mcopLister.Get(ctrlcommon.MCOOperatorKnobsObjectName)
+	if errors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := mcop.Annotations[ctrlcommon.PrefetchMaxNodesAnnotationKey]
+	if !ok {
+		return 0, nil
+	}
+	maxNodes, err := strconv.Atoi(value)
+	if err != nil || maxNodes < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a non-negative integer", ctrlcommon.PrefetchMaxNodesAnnotationKey, value)
+	}
+	return maxNodes, nil
+}
+
+// getPrefetchSlotChanges returns the nodes to grant a prefetch slot to and the
+// nodes to revoke their slot from. Slots are granted in the order they were
+// requested, up to maxNodes at once, or to every node if maxNodes is 0. Nodes
+// which no longer request their slot lose it. Lowering maxNodes does not stop
+// nodes which were already granted a slot.
+func getPrefetchSlotChanges(nodes []*corev1.Node, maxNodes int) (grant, revoke []string) {
+	var waiting []*corev1.Node
+	active := 0
+	for _, node := range nodes {
+		_, requested := node.Annotations[daemonconsts.PrefetchRequestedAnnotationKey]
+		_, granted := node.Annotations[daemonconsts.PrefetchGrantedAnnotationKey]
+		switch {
+		case requested && granted:
+			active++
+		case requested:
+			waiting = append(waiting, node)
+		case granted:
+			revoke = append(revoke, node.Name)
+		}
+	}
+
+	// the daemon sets the request annotation to an RFC3339 timestamp, which
+	// sorts chronologically
+	sort.SliceStable(waiting, func(i, j int) bool {
+		ti := waiting[i].Annotations[daemonconsts.PrefetchRequestedAnnotationKey]
+		tj := waiting[j].Annotations[daemonconsts.PrefetchRequestedAnnotationKey]
+		if ti != tj {
+			return ti < tj
+		}
+		return waiting[i].Name < waiting[j].Name
+	})
+
+	available := len(waiting)
+	if maxNodes > 0 {
+		available = max(0, maxNodes-active)
+	}
+	for _, node := range waiting[:min(available, len(waiting))] {
+		grant = append(grant, node.Name)
+	}
+	return grant, revoke
+}
+
+// syncPrefetchSlots grants and revokes the prefetch slots of the nodes of the
+// cluster, limiting how many nodes prefetch PinnedImageSet images at once.
+func (ctrl *Controller) syncPrefetchSlots() error {
+	maxNodes, err := ctrl.getMaxPrefetchNodes()
+	if err != nil {
+		return err
+	}
+	nodes, err := ctrl.nodeLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	grant, revoke := getPrefetchSlotChanges(nodes, maxNodes)
+	for _, name := range revoke {
+		klog.V(4).Infof("Revoking prefetch slot of node %s", name)
+		if _, err := internal.UpdateNodeRetry(ctrl.kubeClient.CoreV1().Nodes(), ctrl.nodeLister, name, func(node *corev1.Node) {
+			delete(node.Annotations, daemonconsts.PrefetchGrantedAnnotationKey)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, name := range grant {
+		klog.Infof("Granting prefetch slot to node %s", name)
+		if _, err := internal.UpdateNodeRetry(ctrl.kubeClient.CoreV1().Nodes(), ctrl.nodeLister, name, func(node *corev1.Node) {
+			node.Annotations[daemonconsts.PrefetchGrantedAnnotationKey] = "true"
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
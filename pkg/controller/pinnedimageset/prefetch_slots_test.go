@@ -0,0 +1,131 @@
+package pinnedimageset
+
+import (
+	"context"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	fakemco "github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	mcfginformers "github.com/openshift/client-go/machineconfiguration/informers/externalversions"
+	fakeoperatorclient "github.com/openshift/client-go/operator/clientset/versioned/fake"
+	operatorinformer "github.com/openshift/client-go/operator/informers/externalversions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+func newPrefetchNode(name, requested string, granted bool) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+	if requested != "" {
+		node.Annotations[daemonconsts.PrefetchRequestedAnnotationKey] = requested
+	}
+	if granted {
+		node.Annotations[daemonconsts.PrefetchGrantedAnnotationKey] = "true"
+	}
+	return node
+}
+
+func TestGetPrefetchSlotChanges(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*corev1.Node{
+		newPrefetchNode("active", "2025-01-01T10:00:00Z", true),
+		newPrefetchNode("done", "", true),
+		newPrefetchNode("late", "2025-01-01T12:00:00Z", false),
+		newPrefetchNode("early", "2025-01-01T11:00:00Z", false),
+		newPrefetchNode("idle", "", false),
+		newPrefetchNode("b-tie", "2025-01-01T11:30:00Z", false),
+		newPrefetchNode("a-tie", "2025-01-01T11:30:00Z", false),
+	}
+
+	tests := []struct {
+		name          string
+		maxNodes      int
+		expectedGrant []string
+	}{
+		{
+			name:          "unlimited",
+			maxNodes:      0,
+			expectedGrant: []string{"early", "a-tie", "b-tie", "late"},
+		},
+		{
+			name:          "limited",
+			maxNodes:      3,
+			expectedGrant: []string{"early", "a-tie"},
+		},
+		{
+			name:     "full",
+			maxNodes: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			grant, revoke := getPrefetchSlotChanges(nodes, test.maxNodes)
+			assert.Equal(t, test.expectedGrant, grant)
+			assert.Equal(t, []string{"done"}, revoke)
+		})
+	}
+}
+
+func TestSyncPrefetchSlots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := []*corev1.Node{
+		newPrefetchNode("active", "2025-01-01T10:00:00Z", true),
+		newPrefetchNode("done", "", true),
+		newPrefetchNode("waiting-1", "2025-01-01T11:00:00Z", false),
+		newPrefetchNode("waiting-2", "2025-01-01T12:00:00Z", false),
+	}
+	mcop := &opv1.MachineConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ctrlcommon.MCOOperatorKnobsObjectName,
+			Annotations: map[string]string{ctrlcommon.PrefetchMaxNodesAnnotationKey: "2"},
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(nodes[0], nodes[1], nodes[2], nodes[3])
+	fakeMCOClient := fakemco.NewSimpleClientset()
+	sharedInformers := mcfginformers.NewSharedInformerFactory(fakeMCOClient, noResyncPeriodFunc())
+	kubeInformers := kubeinformers.NewSharedInformerFactory(fakeClient, noResyncPeriodFunc())
+	operatorInformers := operatorinformer.NewSharedInformerFactory(fakeoperatorclient.NewSimpleClientset(), noResyncPeriodFunc())
+	nodeInformer := kubeInformers.Core().V1().Nodes()
+	mcopInformer := operatorInformers.Operator().V1().MachineConfigurations()
+
+	c := New(
+		sharedInformers.Machineconfiguration().V1().PinnedImageSets(),
+		sharedInformers.Machineconfiguration().V1().MachineConfigPools(),
+		nodeInformer,
+		mcopInformer,
+		fakeClient,
+		fakeMCOClient,
+	)
+	for _, node := range nodes {
+		require.NoError(t, nodeInformer.Informer().GetIndexer().Add(node))
+	}
+	require.NoError(t, mcopInformer.Informer().GetIndexer().Add(mcop))
+
+	require.NoError(t, c.syncHandler(prefetchSlotsKey))
+
+	granted := map[string]bool{}
+	for _, node := range nodes {
+		updated, err := fakeClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		granted[node.Name] = updated.Annotations[daemonconsts.PrefetchGrantedAnnotationKey] == "true"
+	}
+	assert.Equal(t, map[string]bool{"active": true, "done": false, "waiting-1": true, "waiting-2": false}, granted)
+
+	mcop.Annotations[ctrlcommon.PrefetchMaxNodesAnnotationKey] = "-1"
+	require.NoError(t, mcopInformer.Informer().GetIndexer().Update(mcop))
+	assert.Error(t, c.syncHandler(prefetchSlotsKey))
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// conflictsReason is the reason of the ConflictingMachineConfigs
+	// condition when conflicts are reported but the pool is still rendered.
+	conflictsReason = "ConflictingDefinitions"
+	// conflictsStrictReason is the reason of the ConflictingMachineConfigs
+	// condition when the pool is not rendered because of the conflicts.
+	conflictsStrictReason = "ConflictingDefinitionsNotRendered"
+	// conflictsOverridesGeneratedReason is the reason of the
+	// ConflictingMachineConfigs condition when the only conflicts are user
+	// MachineConfigs overriding generated ones.
+	conflictsOverridesGeneratedReason = "OverridesGeneratedDefinitions"
+)
+
+// conflictsMessage summarizes the conflicts for a condition or error message.
+func conflictsMessage(conflicts []ctrlcommon.MachineConfigConflict) string {
+	messages := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// userConflicts returns the conflicts which are not only a user MachineConfig
+// overriding generated ones. Only those fail the render in strict mode.
+func userConflicts(conflicts []ctrlcommon.MachineConfigConflict) []ctrlcommon.MachineConfigConflict {
+	var user []ctrlcommon.MachineConfigConflict
+	for _, conflict := range conflicts {
+		if !conflict.OverridesGenerated {
+			user = append(user, conflict)
+		}
+	}
+	return user
+}
+
+// setConflictsCondition reports the conflicts between the pool's
+// MachineConfigs in the pool status, or removes the condition if there are
+// none.
+func setConflictsCondition(pool *mcfgv1.MachineConfigPool, conflicts []ctrlcommon.MachineConfigConflict) {
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolConflictingMachineConfigs)
+	if len(conflicts) == 0 {
+		if apihelpers.GetMachineConfigPoolCondition(pool.Status, condType) != nil {
+			apihelpers.RemoveMachineConfigPoolCondition(&pool.Status, condType)
+		}
+		return
+	}
+
+	reason := conflictsReason
+	switch {
+	case len(userConflicts(conflicts)) == 0:
+		reason = conflictsOverridesGeneratedReason
+	case pool.Annotations[ctrlcommon.StrictConflictsAnnotationKey] == "true":
+		reason = conflictsStrictReason
+	}
+	cond := apihelpers.NewMachineConfigPoolCondition(condType, corev1.ConditionTrue, reason, conflictsMessage(conflicts))
+	apihelpers.SetMachineConfigPoolCondition(&pool.Status, *cond)
+}
+
+// syncConflictsStatus updates the pool status with its conflicts, if they
+// changed, and returns the updated pool.
+func (ctrl *Controller) syncConflictsStatus(pool *mcfgv1.MachineConfigPool, conflicts []ctrlcommon.MachineConfigConflict) (*mcfgv1.MachineConfigPool, error) {
+	conditions := pool.Status.Conditions
+	setConflictsCondition(pool, conflicts)
+	if reflect.DeepEqual(conditions, pool.Status.Conditions) {
+		return pool, nil
+	}
+
+	if len(conflicts) > 0 {
+		eventType := corev1.EventTypeWarning
+		if len(userConflicts(conflicts)) == 0 {
+			eventType = corev1.EventTypeNormal
+		}
+		ctrl.eventRecorder.Eventf(pool, eventType, "ConflictingMachineConfigs", "MachineConfigs of pool %s conflict: %s", pool.Name, conflictsMessage(conflicts))
+	}
+	updated, err := ctrl.client.MachineconfigurationV1().MachineConfigPools().UpdateStatus(context.TODO(), pool, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not update conflicts of MachineConfigPool %s: %w", pool.Name, err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,176 @@
+package render
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	configv1 "github.com/openshift/api/config/v1"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	kubeletconfig "github.com/openshift/machine-config-operator/pkg/controller/kubelet-config"
+	"github.com/openshift/machine-config-operator/pkg/controller/template"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestConflictingMachineConfigs(t *testing.T) {
+	t.Parallel()
+
+	condType := mcfgv1.MachineConfigPoolConditionType(ctrlcommon.MachineConfigPoolConflictingMachineConfigs)
+
+	tests := []struct {
+		name           string
+		strict         bool
+		conflicting    bool
+		generated      bool
+		expectRendered bool
+		expectReason   string
+	}{
+		{
+			name:           "no conflicts",
+			expectRendered: true,
+		},
+		{
+			name:           "conflicts are reported",
+			conflicting:    true,
+			expectRendered: true,
+			expectReason:   conflictsReason,
+		},
+		{
+			name:         "conflicts are not rendered in strict mode",
+			strict:       true,
+			conflicting:  true,
+			expectReason: conflictsStrictReason,
+		},
+		{
+			name:           "overriding generated MachineConfigs is rendered in strict mode",
+			strict:         true,
+			conflicting:    true,
+			generated:      true,
+			expectRendered: true,
+			expectReason:   conflictsOverridesGeneratedReason,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newFixture(t)
+			mcp := helpers.NewMachineConfigPool("test-cluster-master", helpers.MasterSelector, nil, "")
+			if test.strict {
+				mcp.Annotations = map[string]string{ctrlcommon.StrictConflictsAnnotationKey: "true"}
+			}
+			overrideContents := "base"
+			if test.conflicting {
+				overrideContents = "override"
+			}
+			mcs := []*mcfgv1.MachineConfig{
+				helpers.NewMachineConfig("00-test-cluster-master", map[string]string{"node-role/master": ""}, "dummy://", []ign3types.File{ctrlcommon.NewIgnFile("/etc/foo", "base")}),
+				helpers.NewMachineConfig("99-test-cluster-master-foo", map[string]string{"node-role/master": ""}, "dummy://", []ign3types.File{ctrlcommon.NewIgnFile("/etc/foo", overrideContents)}),
+			}
+			if test.generated {
+				mcs[0].OwnerReferences = []metav1.OwnerReference{{APIVersion: "machineconfiguration.openshift.io/v1", Kind: "KubeletConfig", Name: "owner", Controller: ptr.To(true)}}
+			}
+
+			f.ccLister = append(f.ccLister, newControllerConfig(ctrlcommon.ControllerConfigName))
+			f.crcLister = append(f.crcLister, &mcfgv1.ContainerRuntimeConfig{})
+			f.mckLister = append(f.mckLister, &mcfgv1.KubeletConfig{})
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.objects = append(f.objects, mcp)
+			f.mcLister = append(f.mcLister, mcs...)
+			for idx := range mcs {
+				f.objects = append(f.objects, mcs[idx])
+			}
+
+			c := f.newController()
+			err := c.syncHandler(getKey(mcp, t))
+			if test.expectRendered {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+
+			pool, err := f.client.MachineconfigurationV1().MachineConfigPools().Get(context.TODO(), mcp.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, test.expectRendered, pool.Spec.Configuration.Name != "")
+
+			cond := apihelpers.GetMachineConfigPoolCondition(pool.Status, condType)
+			if test.expectReason == "" {
+				assert.Nil(t, cond)
+				return
+			}
+			require.NotNil(t, cond)
+			assert.Equal(t, corev1.ConditionTrue, cond.Status)
+			assert.Equal(t, test.expectReason, cond.Reason)
+			assert.Contains(t, cond.Message, "file /etc/foo is defined differently by 00-test-cluster-master, 99-test-cluster-master-foo")
+			assert.Equal(t, !test.expectRendered, apihelpers.IsMachineConfigPoolConditionTrue(pool.Status.Conditions, mcfgv1.MachineConfigPoolRenderDegraded))
+		})
+	}
+}
+
+func TestGeneratedMachineConfigConflicts(t *testing.T) {
+	templatesDir := "../../../templates"
+	ccBytes, err := os.ReadFile("../template/test_data/controller_config_aws.yaml")
+	require.NoError(t, err)
+	cc := &mcfgv1.ControllerConfig{}
+	require.NoError(t, yaml.Unmarshal(ccBytes, cc))
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+
+	templateConfigs, err := template.RunBootstrap(templatesDir, cc, []byte(`{"dummy":"dummy"}`), nil)
+	require.NoError(t, err)
+	featureConfigs, err := kubeletconfig.RunFeatureGateBootstrap(templatesDir, ctrlcommon.NewFeatureGatesHardcodedHandler([]configv1.FeatureGateName{"CSIMigration"}, nil), nil, cc, []*mcfgv1.MachineConfigPool{pool}, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, featureConfigs)
+
+	var configs []*mcfgv1.MachineConfig
+	for _, config := range append(templateConfigs, featureConfigs...) {
+		if config.Labels[mcfgv1.MachineConfigRoleLabelKey] == pool.Name {
+			configs = append(configs, config)
+		}
+	}
+
+	// The generated kubelet config overrides the one from the templates.
+	var kubeletConfigDefinitions []string
+	for _, config := range configs {
+		ignConfig, err := ctrlcommon.ParseAndConvertConfig(config.Spec.Config.Raw)
+		require.NoError(t, err)
+		for _, file := range ignConfig.Storage.Files {
+			if file.Path == "/etc/kubernetes/kubelet.conf" {
+				kubeletConfigDefinitions = append(kubeletConfigDefinitions, config.Name)
+			}
+		}
+	}
+	require.Len(t, kubeletConfigDefinitions, 2)
+
+	conflicts, err := ctrlcommon.GetMachineConfigConflicts(configs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	// User MachineConfigs overriding the generated ones are reported as such,
+	// unless they also override each other.
+	override := helpers.NewMachineConfig("99-worker-kubelet", map[string]string{mcfgv1.MachineConfigRoleLabelKey: pool.Name}, "",
+		[]ign3types.File{ctrlcommon.NewIgnFile("/etc/kubernetes/kubelet.conf", "{}")})
+	conflicts, err = ctrlcommon.GetMachineConfigConflicts(append(configs, override))
+	require.NoError(t, err)
+	assert.Equal(t, []ctrlcommon.MachineConfigConflict{
+		{Kind: "file", Name: "/etc/kubernetes/kubelet.conf", MachineConfigs: append(kubeletConfigDefinitions, "99-worker-kubelet"), OverridesGenerated: true},
+	}, conflicts)
+
+	otherOverride := helpers.NewMachineConfig("50-worker-kubelet", map[string]string{mcfgv1.MachineConfigRoleLabelKey: pool.Name}, "",
+		[]ign3types.File{ctrlcommon.NewIgnFile("/etc/kubernetes/kubelet.conf", "{\"maxPods\": 500}")})
+	conflicts, err = ctrlcommon.GetMachineConfigConflicts(append(configs, override, otherOverride))
+	require.NoError(t, err)
+	assert.Equal(t, []ctrlcommon.MachineConfigConflict{
+		{Kind: "file", Name: "/etc/kubernetes/kubelet.conf", MachineConfigs: append([]string{kubeletConfigDefinitions[0], "50-worker-kubelet"}, kubeletConfigDefinitions[1], "99-worker-kubelet")},
+	}, conflicts)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,10 +32,14 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformersv1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisterv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -52,6 +57,11 @@ const (
 	// renderDelay is a pause to avoid churn in MachineConfigs; see
 	// https://github.com/openshift/machine-config-operator/issues/301
 	renderDelay = 5 * time.Second
+
+	// defaultRenderedConfigsToKeep is the number of the most recent unreferenced rendered
+	// MachineConfigs retained for a pool when garbage collecting; it can be overridden per
+	// pool with the RenderedConfigRetentionAnnotationKey annotation.
+	defaultRenderedConfigsToKeep = 5
 )
 
 var (
@@ -89,6 +99,15 @@ type Controller struct {
 	mcopLister       mcoplistersv1.MachineConfigurationLister
 	mcopListerSynced cache.InformerSynced
 
+	nodeLister       corelisterv1.NodeLister
+	nodeListerSynced cache.InformerSynced
+
+	mcnLister       mcfglistersv1.MachineConfigNodeLister
+	mcnListerSynced cache.InformerSynced
+
+	mosbLister       mcfglistersv1.MachineOSBuildLister
+	mosbListerSynced cache.InformerSynced
+
 	fgHandler ctrlcommon.FeatureGatesHandler
 
 	queue workqueue.TypedRateLimitingInterface[string]
@@ -103,6 +122,9 @@ func New(
 	mckInformer mcfginformersv1.KubeletConfigInformer,
 	mcopInformer mcopinformersv1.MachineConfigurationInformer,
 	osImageStreamInformer mcfginformersv1alpha1.OSImageStreamInformer,
+	nodeInformer coreinformersv1.NodeInformer,
+	mcnInformer mcfginformersv1.MachineConfigNodeInformer,
+	mosbInformer mcfginformersv1.MachineOSBuildInformer,
 	kubeClient clientset.Interface,
 	mcfgClient mcfgclientset.Interface,
 	featureGatesHandler ctrlcommon.FeatureGatesHandler,
@@ -146,6 +168,12 @@ func New(
 	ctrl.mckListerSynced = mckInformer.Informer().HasSynced
 	ctrl.mcopLister = mcopInformer.Lister()
 	ctrl.mcopListerSynced = mcopInformer.Informer().HasSynced
+	ctrl.nodeLister = nodeInformer.Lister()
+	ctrl.nodeListerSynced = nodeInformer.Informer().HasSynced
+	ctrl.mcnLister = mcnInformer.Lister()
+	ctrl.mcnListerSynced = mcnInformer.Informer().HasSynced
+	ctrl.mosbLister = mosbInformer.Lister()
+	ctrl.mosbListerSynced = mosbInformer.Informer().HasSynced
 
 	if osImageStreamInformer != nil && osimagestream.IsFeatureEnabled(ctrl.fgHandler) {
 		ctrl.osImageStreamLister = osImageStreamInformer.Lister()
@@ -162,7 +190,10 @@ func (ctrl *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer ctrl.queue.ShutDown()
 
-	listerCaches := []cache.InformerSynced{ctrl.mcpListerSynced, ctrl.mcListerSynced, ctrl.ccListerSynced}
+	listerCaches := []cache.InformerSynced{
+		ctrl.mcpListerSynced, ctrl.mcListerSynced, ctrl.ccListerSynced,
+		ctrl.nodeListerSynced, ctrl.mcnListerSynced, ctrl.mosbListerSynced,
+	}
 
 	// OSImageStreams and MCPs fetched only if FeatureGateOSStreams active
 	if ctrl.osImageStreamListerSynced != nil {
@@ -541,11 +572,36 @@ func (ctrl *Controller) syncMachineConfigPool(key string) error {
 		return ctrl.syncFailingStatus(pool, fmt.Errorf("no MachineConfigs found matching selector %v", selector))
 	}
 
-	if err := ctrl.syncGeneratedMachineConfig(pool, mcs); err != nil {
+	// Invalid MachineConfigs are reported by the render itself, so conflict
+	// detection errors are not fatal.
+	conflicts, err := ctrlcommon.GetMachineConfigConflicts(mcs)
+	if err != nil {
+		klog.Warningf("Could not check MachineConfigs of pool %s for conflicts: %v", pool.Name, err)
+	}
+	if pool, err = ctrl.syncConflictsStatus(pool, conflicts); err != nil {
+		return err
+	}
+	if user := userConflicts(conflicts); len(user) > 0 && pool.Annotations[ctrlcommon.StrictConflictsAnnotationKey] == "true" {
+		return ctrl.syncFailingStatus(pool, fmt.Errorf("refusing to render conflicting MachineConfigs: %s", conflictsMessage(user)))
+	}
+
+	rendered, err := ctrl.syncGeneratedMachineConfig(pool, mcs)
+	if err != nil {
 		klog.Errorf("Error syncing Generated MCFG: %v", err)
 		return ctrl.syncFailingStatus(pool, err)
 	}
-	return ctrl.syncAvailableStatus(pool)
+	if err := ctrl.syncAvailableStatus(pool); err != nil {
+		return err
+	}
+
+	// Rendered configs stop being in use as nodes move off them, not only when
+	// the pool moves to a new one, so they are collected on every sync. The
+	// render itself succeeded, so failing to collect them only requeues the
+	// pool.
+	if err := ctrl.garbageCollectRenderedConfigs(rendered); err != nil {
+		return fmt.Errorf("failed to garbage collect rendered MachineConfigs for pool %s: %w", pool.Name, err)
+	}
+	return nil
 }
 
 func (ctrl *Controller) syncAvailableStatus(pool *mcfgv1.MachineConfigPool) error {
@@ -569,16 +625,143 @@ func (ctrl *Controller) syncFailingStatus(pool *mcfgv1.MachineConfigPool, err er
 	return err
 }
 
-// This function will eventually contain a sane garbage collection policy for rendered MachineConfigs;
-// see https://github.com/openshift/machine-config-operator/issues/301
-// It will probably involve making sure we're only GCing a config after all nodes don't have it
-// in either desired or current config.
-func (ctrl *Controller) garbageCollectRenderedConfigs(_ *mcfgv1.MachineConfigPool) error {
-	// Temporarily until https://github.com/openshift/machine-config-operator/pull/318
-	// which depends on the strategy for https://github.com/openshift/machine-config-operator/issues/301
+// garbageCollectRenderedConfigs deletes the rendered MachineConfigs owned by the
+// pool that are no longer in use. A rendered MachineConfig is considered in use
+// while it is referenced by the pool spec or status, by the current or desired
+// config annotations of any node, by any MachineConfigNode or by any
+// MachineOSBuild. On top of those, the most recently created unreferenced
+// rendered MachineConfigs are retained so that they remain available for
+// inspection; see getRenderedConfigsToKeep.
+// See https://github.com/openshift/machine-config-operator/issues/301
+func (ctrl *Controller) garbageCollectRenderedConfigs(pool *mcfgv1.MachineConfigPool) error {
+	inUse, err := ctrl.getRenderedConfigsInUse(pool)
+	if err != nil {
+		return fmt.Errorf("could not determine rendered MachineConfigs in use for pool %s: %w", pool.Name, err)
+	}
+
+	rendered, err := ctrl.getRenderedConfigsForPool(pool)
+	if err != nil {
+		return err
+	}
+
+	// Newest first, so that the retained configs are the most recent ones.
+	sort.SliceStable(rendered, func(i, j int) bool {
+		return rendered[j].CreationTimestamp.Before(&rendered[i].CreationTimestamp)
+	})
+
+	toKeep := getRenderedConfigsToKeep(pool)
+	kept := 0
+	errs := []error{}
+	for _, mc := range rendered {
+		if inUse.Has(mc.Name) {
+			continue
+		}
+		if kept < toKeep {
+			kept++
+			continue
+		}
+		if err := ctrl.deleteRenderedConfig(pool, mc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// deleteRenderedConfig deletes a single rendered MachineConfig, recording an event and a metric for it.
+func (ctrl *Controller) deleteRenderedConfig(pool *mcfgv1.MachineConfigPool, mc *mcfgv1.MachineConfig) error {
+	// Guard against deleting a config that was recreated with the same name since our lister observed it.
+	preconditions := metav1.Preconditions{UID: &mc.UID}
+	err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mc.Name, metav1.DeleteOptions{Preconditions: &preconditions})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not delete rendered MachineConfig %s: %w", mc.Name, err)
+	}
+
+	klog.V(2).Infof("Pool %s: garbage collected rendered MachineConfig %s", pool.Name, mc.Name)
+	ctrl.eventRecorder.Eventf(pool, corev1.EventTypeNormal, "RenderedConfigGarbageCollected", "Deleted unused rendered MachineConfig %s", mc.Name)
+	ctrlcommon.MCCRenderedConfigsGarbageCollected.WithLabelValues(pool.Name).Inc()
 	return nil
 }
 
+// getRenderedConfigsForPool returns the rendered MachineConfigs controlled by the given pool.
+func (ctrl *Controller) getRenderedConfigsForPool(pool *mcfgv1.MachineConfigPool) ([]*mcfgv1.MachineConfig, error) {
+	mcs, err := ctrl.mcLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("%s%s-", ctrlcommon.RenderedMachineConfigPrefix, pool.Name)
+	out := []*mcfgv1.MachineConfig{}
+	for _, mc := range mcs {
+		if !strings.HasPrefix(mc.Name, prefix) {
+			continue
+		}
+		controllerRef := metav1.GetControllerOf(mc)
+		if controllerRef == nil || controllerRef.Kind != controllerKind.Kind || controllerRef.UID != pool.UID {
+			continue
+		}
+		out = append(out, mc)
+	}
+	return out, nil
+}
+
+// getRenderedConfigsInUse returns the names of all rendered MachineConfigs which
+// are still referenced by the pool, nodes, MachineConfigNodes or MachineOSBuilds.
+// Nodes, MachineConfigNodes and MachineOSBuilds are considered regardless of the
+// pool they belong to since a node may still be moving between pools.
+func (ctrl *Controller) getRenderedConfigsInUse(pool *mcfgv1.MachineConfigPool) (sets.Set[string], error) {
+	inUse := sets.New[string](pool.Spec.Configuration.Name, pool.Status.Configuration.Name)
+
+	nodes, err := ctrl.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("could not list nodes: %w", err)
+	}
+	for _, node := range nodes {
+		inUse.Insert(node.Annotations[daemonconsts.CurrentMachineConfigAnnotationKey], node.Annotations[daemonconsts.DesiredMachineConfigAnnotationKey])
+	}
+
+	mcns, err := ctrl.mcnLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("could not list MachineConfigNodes: %w", err)
+	}
+	for _, mcn := range mcns {
+		inUse.Insert(mcn.Spec.ConfigVersion.Desired)
+		if mcn.Status.ConfigVersion != nil {
+			inUse.Insert(mcn.Status.ConfigVersion.Current, mcn.Status.ConfigVersion.Desired)
+		}
+	}
+
+	mosbs, err := ctrl.mosbLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("could not list MachineOSBuilds: %w", err)
+	}
+	for _, mosb := range mosbs {
+		inUse.Insert(mosb.Spec.MachineConfig.Name)
+	}
+
+	inUse.Delete("")
+	return inUse, nil
+}
+
+// getRenderedConfigsToKeep returns how many unreferenced rendered MachineConfigs
+// should be retained for the pool, honoring RenderedConfigRetentionAnnotationKey.
+func getRenderedConfigsToKeep(pool *mcfgv1.MachineConfigPool) int {
+	val, ok := pool.Annotations[ctrlcommon.RenderedConfigRetentionAnnotationKey]
+	if !ok {
+		return defaultRenderedConfigsToKeep
+	}
+
+	toKeep, err := strconv.Atoi(val)
+	if err != nil || toKeep < 0 {
+		klog.Warningf("Pool %s: ignoring invalid %s annotation value %q, keeping %d rendered configs", pool.Name, ctrlcommon.RenderedConfigRetentionAnnotationKey, val, defaultRenderedConfigsToKeep)
+		return defaultRenderedConfigsToKeep
+	}
+	return toKeep
+}
+
 func (ctrl *Controller) getRenderedMachineConfig(pool *mcfgv1.MachineConfigPool, configs []*mcfgv1.MachineConfig, cc *mcfgv1.ControllerConfig, osImageStreamSet *v1alpha1.OSImageStreamSet) (*mcfgv1.MachineConfig, error) {
 	// If we don't yet have a rendered MachineConfig on the pool, we cannot
 	// perform reconciliation. So we must solely generate the rendered
@@ -666,29 +849,31 @@ func (ctrl *Controller) getOSImageStreamForPool(pool *mcfgv1.MachineConfigPool)
 	return imageStreamSet, nil
 }
 
-func (ctrl *Controller) syncGeneratedMachineConfig(pool *mcfgv1.MachineConfigPool, configs []*mcfgv1.MachineConfig) error {
+// syncGeneratedMachineConfig renders the pool's MachineConfigs and points the
+// pool at the result, returning the updated pool.
+func (ctrl *Controller) syncGeneratedMachineConfig(pool *mcfgv1.MachineConfigPool, configs []*mcfgv1.MachineConfig) (*mcfgv1.MachineConfigPool, error) {
 	if len(configs) == 0 {
-		return nil
+		return pool, nil
 	}
 
 	cc, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	osImageStreamSet, err := ctrl.getOSImageStreamForPool(pool)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	generated, err := ctrl.getRenderedMachineConfig(pool, configs, cc, osImageStreamSet)
 	if err != nil {
-		return fmt.Errorf("could not generate rendered MachineConfig: %w", err)
+		return nil, fmt.Errorf("could not generate rendered MachineConfig: %w", err)
 	}
 
 	// Validate that the generated MachineConfig does not exceed etcd size limits
 	if err := ctrlcommon.ValidateMachineConfigSize(generated); err != nil {
-		return fmt.Errorf("size validation failed: %w", err)
+		return nil, fmt.Errorf("size validation failed: %w", err)
 	}
 
 	// Collect metric when OSImageURL was overridden
@@ -707,7 +892,7 @@ func (ctrl *Controller) syncGeneratedMachineConfig(pool *mcfgv1.MachineConfigPoo
 	if apierrors.IsNotFound(err) {
 		_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Create(context.TODO(), generated, metav1.CreateOptions{})
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if isOSImageURLOverridden {
 			ctrl.eventRecorder.Eventf(generated, corev1.EventTypeNormal, "OSImageURLOverridden", "OSImageURL was overridden via machineconfig in %s (was: %s is: %s)", generated.Name, cc.Spec.OSImageURL, generated.Spec.OSImageURL)
@@ -717,7 +902,7 @@ func (ctrl *Controller) syncGeneratedMachineConfig(pool *mcfgv1.MachineConfigPoo
 			generated.Name, generated.Annotations[ctrlcommon.ReleaseImageVersionAnnotationKey], generated.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey])
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	newPool := pool.DeepCopy()
@@ -726,21 +911,20 @@ func (ctrl *Controller) syncGeneratedMachineConfig(pool *mcfgv1.MachineConfigPoo
 	if pool.Spec.Configuration.Name == generated.Name {
 		_, _, err = mcoResourceApply.ApplyMachineConfig(ctrl.client.MachineconfigurationV1(), generated)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		_, err = ctrl.client.MachineconfigurationV1().MachineConfigPools().Update(context.TODO(), newPool, metav1.UpdateOptions{})
-		return err
+		return ctrl.client.MachineconfigurationV1().MachineConfigPools().Update(context.TODO(), newPool, metav1.UpdateOptions{})
 	}
 
 	newPool.Spec.Configuration.Name = generated.Name
 	// TODO(walters) Use subresource or JSON patch, but the latter isn't supported by the unit test mocks
 	pool, err = ctrl.client.MachineconfigurationV1().MachineConfigPools().Update(context.TODO(), newPool, metav1.UpdateOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	klog.V(2).Infof("Pool %s: now targeting: %s", pool.Name, pool.Spec.Configuration.Name)
 	ctrlcommon.UpdateStateMetric(ctrlcommon.MCCSubControllerState, "machine-config-controller-render", "Sync Machine Config Pool with new MC", pool.Name)
-	return ctrl.garbageCollectRenderedConfigs(pool)
+	return pool, nil
 }
 
 // generateRenderedMachineConfig takes all MCs for a given pool and returns a single rendered MC. For ex master-XXXX or worker-XXXX
@@ -794,6 +978,12 @@ func generateRenderedMachineConfig(pool *mcfgv1.MachineConfigPool, configs []*mc
 	merged.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey] = version.Hash
 	merged.Annotations[ctrlcommon.ReleaseImageVersionAnnotationKey] = cconfig.Annotations[ctrlcommon.ReleaseImageVersionAnnotationKey]
 
+	// Record which MachineConfig each file and unit comes from, so that the
+	// daemon can point at the owning MachineConfig when reporting drift.
+	if err := ctrlcommon.SetMachineConfigProvenanceAnnotation(merged, configs); err != nil {
+		return nil, err
+	}
+
 	// The operator needs to know the user overrode this, so it knows if it needs to skip the
 	// OSImageURL check during upgrade -- if the user took over managing OS upgrades this way,
 	// the operator shouldn't stop the rest of the upgrade from progressing/completing.
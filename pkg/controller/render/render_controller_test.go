@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
@@ -53,6 +54,10 @@ type fixture struct {
 	crcLister []*mcfgv1.ContainerRuntimeConfig
 	mckLister []*mcfgv1.KubeletConfig
 
+	nodeLister []*corev1.Node
+	mcnLister  []*mcfgv1.MachineConfigNode
+	mosbLister []*mcfgv1.MachineOSBuild
+
 	actions []core.Action
 
 	objects   []runtime.Object
@@ -77,17 +82,24 @@ func (f *fixture) newController() *Controller {
 	f.oclient = mcopfake.NewSimpleClientset(f.oObjects...)
 	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
 	oi := operatorinformer.NewSharedInformerFactory(f.oclient, noResyncPeriodFunc())
+	kubeClient := k8sfake.NewSimpleClientset()
+	ki := kubeinformers.NewSharedInformerFactory(kubeClient, noResyncPeriodFunc())
 
 	c := New(i.Machineconfiguration().V1().MachineConfigPools(), i.Machineconfiguration().V1().MachineConfigs(),
 		i.Machineconfiguration().V1().ControllerConfigs(), i.Machineconfiguration().V1().ContainerRuntimeConfigs(),
 		i.Machineconfiguration().V1().KubeletConfigs(), oi.Operator().V1().MachineConfigurations(),
-		i.Machineconfiguration().V1alpha1().OSImageStreams(), k8sfake.NewSimpleClientset(), f.client, f.fgHandler)
+		i.Machineconfiguration().V1alpha1().OSImageStreams(), ki.Core().V1().Nodes(),
+		i.Machineconfiguration().V1().MachineConfigNodes(), i.Machineconfiguration().V1().MachineOSBuilds(),
+		kubeClient, f.client, f.fgHandler)
 
 	c.mcpListerSynced = alwaysReady
 	c.mcListerSynced = alwaysReady
 	c.ccListerSynced = alwaysReady
 	c.crcListerSynced = alwaysReady
 	c.mckListerSynced = alwaysReady
+	c.nodeListerSynced = alwaysReady
+	c.mcnListerSynced = alwaysReady
+	c.mosbListerSynced = alwaysReady
 	c.eventRecorder = ctrlcommon.NamespacedEventRecorder(&record.FakeRecorder{})
 
 	stopCh := make(chan struct{})
@@ -117,6 +129,18 @@ func (f *fixture) newController() *Controller {
 		i.Machineconfiguration().V1().KubeletConfigs().Informer().GetIndexer().Add(m)
 	}
 
+	for _, n := range f.nodeLister {
+		ki.Core().V1().Nodes().Informer().GetIndexer().Add(n)
+	}
+
+	for _, m := range f.mcnLister {
+		i.Machineconfiguration().V1().MachineConfigNodes().Informer().GetIndexer().Add(m)
+	}
+
+	for _, m := range f.mosbLister {
+		i.Machineconfiguration().V1().MachineOSBuilds().Informer().GetIndexer().Add(m)
+	}
+
 	return c
 }
 
@@ -214,7 +238,11 @@ func filterInformerActions(actions []core.Action) []core.Action {
 				action.Matches("list", "kubeletconfigs") ||
 				action.Matches("watch", "kubeletconfigs") ||
 				action.Matches("list", "containerruntimeconfigs") ||
-				action.Matches("watch", "containerruntimeconfigs")) {
+				action.Matches("watch", "containerruntimeconfigs") ||
+				action.Matches("list", "machineconfignodes") ||
+				action.Matches("watch", "machineconfignodes") ||
+				action.Matches("list", "machineosbuilds") ||
+				action.Matches("watch", "machineosbuilds")) {
 			continue
 		}
 		ret = append(ret, action)
@@ -239,6 +267,10 @@ func (f *fixture) expectUpdateMachineConfigAction(config *mcfgv1.MachineConfig)
 	f.actions = append(f.actions, core.NewRootUpdateAction(schema.GroupVersionResource{Resource: "machineconfigs"}, config))
 }
 
+func (f *fixture) expectDeleteMachineConfigAction(config *mcfgv1.MachineConfig) {
+	f.actions = append(f.actions, core.NewRootDeleteAction(schema.GroupVersionResource{Resource: "machineconfigs"}, config.Name))
+}
+
 func (f *fixture) expectUpdateMachineConfigPool(pool *mcfgv1.MachineConfigPool) {
 	f.actions = append(f.actions, core.NewRootUpdateAction(schema.GroupVersionResource{Resource: "machineconfigpools"}, pool))
 }
@@ -442,6 +474,23 @@ func TestGenerateMachineConfigOverrideOSImageURL(t *testing.T) {
 	assert.Equal(t, "dummy-change-2", gmc.Spec.OSImageURL)
 }
 
+func TestGenerateMachineConfigProvenance(t *testing.T) {
+	mcp := helpers.NewMachineConfigPool("test-cluster-worker", helpers.WorkerSelector, nil, "")
+	mcs := []*mcfgv1.MachineConfig{
+		helpers.NewMachineConfig("00-test-cluster-worker", map[string]string{"node-role/worker": ""}, "", []ign3types.File{ctrlcommon.NewIgnFile("/etc/foo", "base")}),
+		helpers.NewMachineConfig("99-test-cluster-worker-foo", map[string]string{"node-role/worker": ""}, "", []ign3types.File{ctrlcommon.NewIgnFile("/etc/foo", "override")}),
+	}
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName)
+
+	gmc, err := generateRenderedMachineConfig(mcp, mcs, cc, nil)
+	require.NoError(t, err)
+
+	provenance, err := ctrlcommon.GetMachineConfigProvenanceFromAnnotation(gmc)
+	require.NoError(t, err)
+	assert.Equal(t, "99-test-cluster-worker-foo", provenance.FileSource("/etc/foo"))
+}
+
 func TestGenerateMachineConfigCannotOverrideOSImageURLWhenOSImageStreamSet(t *testing.T) {
 	mcp := helpers.NewMachineConfigPool("test-cluster-master", helpers.MasterSelector, nil, "")
 	mcs := []*mcfgv1.MachineConfig{
@@ -923,3 +972,147 @@ func TestWorkerPoolOtherChangeDoesNotEnqueueCustomPools(t *testing.T) {
 	assert.NotContains(t, enqueuedPools, "infra", "Infra pool should NOT be enqueued when osImageStream unchanged")
 	assert.Len(t, enqueuedPools, 1, "Only worker pool should be enqueued")
 }
+
+func TestGarbageCollectRenderedConfigs(t *testing.T) {
+	f := newFixture(t)
+	mcp := helpers.NewMachineConfigPool("test-cluster-master", helpers.MasterSelector, nil, "rendered-test-cluster-master-spec")
+	mcp.Annotations = map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "1"}
+	otherPool := helpers.NewMachineConfigPool("test-cluster-master-other", helpers.MasterSelector, nil, "")
+
+	now := time.Now()
+	newRendered := func(name string, owner *mcfgv1.MachineConfigPool, age time.Duration) *mcfgv1.MachineConfig {
+		mc := helpers.NewMachineConfig(name, nil, "", nil)
+		mc.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		mc.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(owner, controllerKind)})
+		return mc
+	}
+
+	mcs := []*mcfgv1.MachineConfig{
+		newRendered("rendered-test-cluster-master-spec", mcp, time.Minute),
+		newRendered("rendered-test-cluster-master-node", mcp, 2*time.Hour),
+		newRendered("rendered-test-cluster-master-mcn", mcp, 3*time.Hour),
+		newRendered("rendered-test-cluster-master-mosb", mcp, 4*time.Hour),
+		newRendered("rendered-test-cluster-master-recent", mcp, 5*time.Hour),
+		newRendered("rendered-test-cluster-master-old", mcp, 6*time.Hour),
+		newRendered("rendered-test-cluster-master-oldest", mcp, 7*time.Hour),
+		// Owned by a different pool whose name shares our prefix.
+		newRendered("rendered-test-cluster-master-other-unused", otherPool, 8*time.Hour),
+		// Not a rendered config at all.
+		helpers.NewMachineConfig("00-test-cluster-master", map[string]string{"node-role/master": ""}, "dummy://", nil),
+	}
+
+	mcn := helpers.NewMachineConfigNode("node-1", mcp.Name, "rendered-test-cluster-master-spec", "", true, false)
+	mcn.Status.ConfigVersion = &mcfgv1.MachineConfigNodeStatusMachineConfigVersion{
+		Current: "rendered-test-cluster-master-mcn",
+		Desired: "rendered-test-cluster-master-spec",
+	}
+
+	mosb := &mcfgv1.MachineOSBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-master-build"},
+		Spec: mcfgv1.MachineOSBuildSpec{
+			MachineConfig: mcfgv1.MachineConfigReference{Name: "rendered-test-cluster-master-mosb"},
+		},
+	}
+
+	f.mcpLister = append(f.mcpLister, mcp, otherPool)
+	f.objects = append(f.objects, mcp, otherPool)
+	f.mcLister = append(f.mcLister, mcs...)
+	for idx := range mcs {
+		f.objects = append(f.objects, mcs[idx])
+	}
+	f.nodeLister = append(f.nodeLister, helpers.NewNodeWithReady("node-1", "rendered-test-cluster-master-node", "rendered-test-cluster-master-spec", corev1.ConditionTrue))
+	f.mcnLister = append(f.mcnLister, mcn)
+	f.mosbLister = append(f.mosbLister, mosb)
+
+	// Unreferenced configs beyond the retention are deleted, oldest last.
+	f.expectDeleteMachineConfigAction(mcs[5])
+	f.expectDeleteMachineConfigAction(mcs[6])
+
+	c := f.newController()
+	require.NoError(t, c.garbageCollectRenderedConfigs(mcp))
+
+	actions := filterInformerActions(f.client.Actions())
+	require.Len(t, actions, len(f.actions))
+	for i, action := range actions {
+		checkAction(f.actions[i], action, t)
+		assert.Equal(t, f.actions[i].(core.DeleteAction).GetName(), action.(core.DeleteAction).GetName())
+	}
+}
+
+func TestGarbageCollectRenderedConfigsOnSync(t *testing.T) {
+	f := newFixture(t)
+	mcp := helpers.NewMachineConfigPool("test-cluster-master", helpers.MasterSelector, nil, "")
+	mcp.Annotations = map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "0"}
+	mcs := []*mcfgv1.MachineConfig{
+		helpers.NewMachineConfig("00-test-cluster-master", map[string]string{"node-role/master": ""}, "dummy://", nil),
+	}
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName)
+
+	gmc, err := generateRenderedMachineConfig(mcp, mcs, cc, nil)
+	require.NoError(t, err)
+	mcp.Spec.Configuration.Name = gmc.Name
+	mcp.Status.Configuration.Name = gmc.Name
+
+	// The pool moved off this config before, but it was still in use then.
+	unused := helpers.NewMachineConfig("rendered-test-cluster-master-unused", nil, "", nil)
+	unused.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(mcp, controllerKind)})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.crcLister = append(f.crcLister, &mcfgv1.ContainerRuntimeConfig{})
+	f.mckLister = append(f.mckLister, &mcfgv1.KubeletConfig{})
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.objects = append(f.objects, mcp)
+	f.mcLister = append(f.mcLister, mcs[0], gmc, unused)
+	f.objects = append(f.objects, mcs[0], gmc, unused)
+
+	mcpNew := mcp.DeepCopy()
+	mcpNew.Spec.Configuration.Source = getMachineConfigRefs(mcs)
+
+	// The unused config is collected although the pool keeps its config.
+	f.expectGetMachineConfigAction(gmc)
+	f.expectUpdateMachineConfigPool(mcpNew)
+	f.expectDeleteMachineConfigAction(unused)
+
+	f.run(getKey(mcp, t))
+}
+
+func TestGetRenderedConfigsToKeep(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    int
+	}{
+		{
+			name:     "No annotation uses default",
+			expected: defaultRenderedConfigsToKeep,
+		},
+		{
+			name:        "Valid annotation",
+			annotations: map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "10"},
+			expected:    10,
+		},
+		{
+			name:        "Zero keeps only referenced configs",
+			annotations: map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "0"},
+			expected:    0,
+		},
+		{
+			name:        "Negative value uses default",
+			annotations: map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "-1"},
+			expected:    defaultRenderedConfigsToKeep,
+		},
+		{
+			name:        "Invalid value uses default",
+			annotations: map[string]string{ctrlcommon.RenderedConfigRetentionAnnotationKey: "lots"},
+			expected:    defaultRenderedConfigsToKeep,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pool := helpers.NewMachineConfigPool("worker", helpers.WorkerSelector, nil, "")
+			pool.Annotations = testCase.annotations
+			assert.Equal(t, testCase.expected, getRenderedConfigsToKeep(pool))
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package daemon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+const (
+	// How often the Config Drift Monitor checks the node state which cannot
+	// be watched for changes.
+	defaultConfigDriftCheckInterval = 5 * time.Minute
+
+	// The file holding the password hashes of the node's users.
+	shadowFilePath = "/etc/shadow"
+)
+
+// getConfigDriftChecks returns the periodic config drift checks for this
+// node. currentImage is the layered image the node should be booted into, if
+// any.
+func (dn *Daemon) getConfigDriftChecks(currentImage string) []ConfigDriftCheck {
+	checks := []ConfigDriftCheck{
+		dn.checkSSHKeysDrift,
+		dn.checkPasswordHashDrift,
+	}
+
+	if dn.os.IsCoreOSVariant() {
+		checks = append(checks,
+			dn.checkKernelArgumentsDrift,
+			func(mc *mcfgv1.MachineConfig) error {
+				return dn.checkOSImageDrift(mc, currentImage)
+			},
+			dn.checkExtensionsDrift,
+		)
+	}
+
+	return checks
+}
+
+// checkKernelArgumentsDrift checks that the kernel arguments of the
+// MachineConfig are set.
+func (dn *Daemon) checkKernelArgumentsDrift(mc *mcfgv1.MachineConfig) error {
+	coreOSDaemon := CoreOSDaemon{dn}
+	err := coreOSDaemon.validateKernelArguments(mc)
+	var kargsErr *kargsConfigDriftErr
+	if errors.As(err, &kargsErr) {
+		return &configDriftErr{kargsErr}
+	}
+	return err
+}
+
+// checkOSImageDrift checks that the node is booted into the OS image of the
+// MachineConfig, or the given layered image if set.
+func (dn *Daemon) checkOSImageDrift(mc *mcfgv1.MachineConfig, currentImage string) error {
+	expected := mc.Spec.OSImageURL
+	if currentImage != "" {
+		expected = currentImage
+	}
+
+	booted, _, _, err := dn.NodeUpdaterClient.GetBootedOSImageURL()
+	if err != nil {
+		return fmt.Errorf("could not get booted OS image: %w", err)
+	}
+	if booted != expected {
+		return &configDriftErr{&osImageConfigDriftErr{fmt.Errorf("expected target osImageURL %q, have %q", expected, booted)}}
+	}
+	return nil
+}
+
+// checkExtensionsDrift checks that the packages of the extensions of the
+// MachineConfig are installed.
+func (dn *Daemon) checkExtensionsDrift(mc *mcfgv1.MachineConfig) error {
+	return checkExtensionPackages(dn.cmdRunner, mc.Spec.Extensions)
+}
+
+func checkExtensionPackages(cmdRunner CommandRunner, extensions []string) error {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	pkgs, err := ctrlcommon.GetPackagesForSupportedExtensions(extensions)
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, pkg := range pkgs {
+		if _, err := cmdRunner.RunGetOut("rpm", "-q", pkg); err != nil {
+			missing = append(missing, pkg)
+		}
+	}
+	if len(missing) > 0 {
+		return &configDriftErr{&extensionsConfigDriftErr{fmt.Errorf("missing packages of extensions %v: %v", extensions, missing)}}
+	}
+	return nil
+}
+
+// coreUserExists determines whether the core user, the only user the MCD
+// configures, exists.
+func coreUserExists() (bool, error) {
+	var uErr user.UnknownUserError
+	switch _, err := user.Lookup(constants.CoreUserName); {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &uErr):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check if user core exists: %w", err)
+	}
+}
+
+// checkSSHKeysDrift checks that the authorized SSH keys of the core user are
+// the ones of the MachineConfig.
+func (dn *Daemon) checkSSHKeysDrift(mc *mcfgv1.MachineConfig) error {
+	if exists, err := coreUserExists(); !exists || err != nil {
+		return err
+	}
+
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse Ignition for config drift: %w", err)
+	}
+
+	authKeyPath := constants.RHCOS8SSHKeyPath
+	if dn.useNewSSHKeyPath() {
+		authKeyPath = constants.RHCOS9SSHKeyPath
+	}
+	return checkSSHKeys(authKeyPath, ignConfig.Passwd.Users)
+}
+
+func checkSSHKeys(authKeyPath string, users []ign3types.PasswdUser) error {
+	expected := concatSSHKeys(users)
+
+	contents, err := os.ReadFile(authKeyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read SSH keys: %w", err)
+	}
+	if string(contents) != expected {
+		return &configDriftErr{&sshKeysConfigDriftErr{fmt.Errorf("SSH keys in %q do not match the MachineConfig", authKeyPath)}}
+	}
+	return nil
+}
+
+// checkPasswordHashDrift checks that the password hashes of the users of the
+// MachineConfig are set.
+func (dn *Daemon) checkPasswordHashDrift(mc *mcfgv1.MachineConfig) error {
+	if exists, err := coreUserExists(); !exists || err != nil {
+		return err
+	}
+
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse Ignition for config drift: %w", err)
+	}
+	return checkPasswordHashes(shadowFilePath, ignConfig.Passwd.Users)
+}
+
+// checkPasswordHashes compares the password hashes in the given shadow file
+// with those of the users. Users without a password hash are not checked, as
+// the MCD only resets their password when the users change.
+func checkPasswordHashes(shadowPath string, users []ign3types.PasswdUser) error {
+	f, err := os.Open(shadowPath)
+	if err != nil {
+		return fmt.Errorf("could not read password hashes: %w", err)
+	}
+	defer f.Close()
+
+	hashes := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) > 1 {
+			hashes[fields[0]] = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read password hashes: %w", err)
+	}
+
+	for _, u := range users {
+		if u.PasswordHash == nil || *u.PasswordHash == "" {
+			continue
+		}
+		hash, ok := hashes[u.Name]
+		if !ok {
+			// The MCD does not create users.
+			continue
+		}
+		// The hashes themselves are not logged to avoid exposing them.
+		if hash != *u.PasswordHash {
+			return &configDriftErr{&passwordHashConfigDriftErr{fmt.Errorf("password hash of user %q does not match the MachineConfig", u.Name)}}
+		}
+	}
+	return nil
+}
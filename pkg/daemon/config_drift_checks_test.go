@@ -0,0 +1,211 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	"github.com/fsnotify/fsnotify"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+func TestCheckSSHKeys(t *testing.T) {
+	t.Parallel()
+
+	users := []ign3types.PasswdUser{{Name: "core", SSHAuthorizedKeys: []ign3types.SSHAuthorizedKey{"key1", "key2"}}}
+
+	dir := t.TempDir()
+	authKeyPath := filepath.Join(dir, "authorized_keys")
+
+	var cdErr *configDriftErr
+	var sshErr *sshKeysConfigDriftErr
+	err := checkSSHKeys(authKeyPath, users)
+	require.True(t, errors.As(err, &cdErr), "expected config drift for missing file, got: %v", err)
+	assert.True(t, errors.As(cdErr.error, &sshErr))
+
+	// A missing file matches no keys.
+	assert.NoError(t, checkSSHKeys(authKeyPath, []ign3types.PasswdUser{{Name: "core"}}))
+
+	require.NoError(t, os.WriteFile(authKeyPath, []byte("key1\nkey2\n"), 0o600))
+	assert.NoError(t, checkSSHKeys(authKeyPath, users))
+
+	require.NoError(t, os.WriteFile(authKeyPath, []byte("key1\nkey2\nadded\n"), 0o600))
+	err = checkSSHKeys(authKeyPath, users)
+	require.True(t, errors.As(err, &cdErr), "expected config drift for added key, got: %v", err)
+	assert.True(t, errors.As(cdErr.error, &sshErr))
+}
+
+func TestCheckPasswordHashes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	shadowPath := filepath.Join(dir, "shadow")
+	require.NoError(t, os.WriteFile(shadowPath, []byte("root:!locked::0:99999:7:::\ncore:$6$expected:19000:0:99999:7:::\n"), 0o600))
+
+	tests := []struct {
+		name        string
+		users       []ign3types.PasswdUser
+		expectDrift bool
+	}{
+		{
+			name:  "matching hash",
+			users: []ign3types.PasswdUser{{Name: "core", PasswordHash: ptr.To("$6$expected")}},
+		},
+		{
+			name:  "no hash is not checked",
+			users: []ign3types.PasswdUser{{Name: "core"}, {Name: "root", PasswordHash: ptr.To("")}},
+		},
+		{
+			name:  "unknown user is not checked",
+			users: []ign3types.PasswdUser{{Name: "other", PasswordHash: ptr.To("$6$other")}},
+		},
+		{
+			name:        "changed hash",
+			users:       []ign3types.PasswdUser{{Name: "core", PasswordHash: ptr.To("$6$changed")}},
+			expectDrift: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkPasswordHashes(shadowPath, test.users)
+			if !test.expectDrift {
+				assert.NoError(t, err)
+				return
+			}
+
+			var cdErr *configDriftErr
+			var pwErr *passwordHashConfigDriftErr
+			require.True(t, errors.As(err, &cdErr), "expected config drift, got: %v", err)
+			assert.True(t, errors.As(cdErr.error, &pwErr))
+			assert.NotContains(t, err.Error(), "$6$")
+		})
+	}
+
+	assert.Error(t, checkPasswordHashes(filepath.Join(dir, "missing"), nil))
+}
+
+func TestCheckExtensionPackages(t *testing.T) {
+	t.Parallel()
+
+	cmdRunner := &MockCommandRunner{
+		outputs: map[string][]byte{
+			"rpm -q usbguard":                 []byte("usbguard-1.0-1.x86_64"),
+			"rpm -q NetworkManager-libreswan": []byte("NetworkManager-libreswan-1.0-1.x86_64"),
+		},
+		errors: map[string]error{
+			"rpm -q libreswan": fmt.Errorf("package libreswan is not installed"),
+		},
+	}
+
+	assert.NoError(t, checkExtensionPackages(cmdRunner, nil))
+	assert.NoError(t, checkExtensionPackages(cmdRunner, []string{"usbguard"}))
+
+	err := checkExtensionPackages(cmdRunner, []string{"usbguard", "ipsec"})
+	var cdErr *configDriftErr
+	var extErr *extensionsConfigDriftErr
+	require.True(t, errors.As(err, &cdErr), "expected config drift, got: %v", err)
+	assert.True(t, errors.As(cdErr.error, &extErr))
+	assert.Contains(t, err.Error(), "[libreswan]")
+
+	err = checkExtensionPackages(cmdRunner, []string{"unknown"})
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &cdErr))
+}
+
+func TestRunPeriodicChecks(t *testing.T) {
+	t.Parallel()
+
+	var reported []error
+	resolved := 0
+	drift := &configDriftErr{&sshKeysConfigDriftErr{fmt.Errorf("SSH keys drifted")}}
+	var checkErr error
+
+	c := &configDriftWatcher{
+		ConfigDriftMonitorOpts: ConfigDriftMonitorOpts{
+			OnDrift:         func(err error) { reported = append(reported, err) },
+			OnDriftResolved: func() { resolved++ },
+			MachineConfig:   &mcfgv1.MachineConfig{},
+			PeriodicChecks: []ConfigDriftCheck{
+				func(*mcfgv1.MachineConfig) error { return checkErr },
+			},
+		},
+		reportedDrift: map[int]string{},
+	}
+
+	c.runPeriodicChecks()
+	assert.Empty(t, reported)
+	assert.Equal(t, 0, resolved)
+
+	// Unchanged drift is reported once.
+	checkErr = drift
+	c.runPeriodicChecks()
+	c.runPeriodicChecks()
+	assert.Equal(t, []error{drift}, reported)
+
+	// Failing checks are not drift.
+	checkErr = fmt.Errorf("could not check")
+	c.runPeriodicChecks()
+	assert.Len(t, reported, 1)
+
+	// Drift is reported again once it was fixed.
+	checkErr = nil
+	c.runPeriodicChecks()
+	c.runPeriodicChecks()
+	assert.Equal(t, 1, resolved, "fixed drift is reported as resolved once")
+	checkErr = drift
+	c.runPeriodicChecks()
+	assert.Len(t, reported, 2)
+}
+
+func TestHandleFileEventResolvesDrift(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a-config-file")
+	require.NoError(t, os.WriteFile(path, []byte("thefilecontents"), defaultFilePermissions))
+	mc := helpers.CreateMachineConfigFromIgnition(ign3types.Config{
+		Ignition: ign3types.Ignition{Version: ign3types.MaxVersion.String()},
+		Storage:  ign3types.Storage{Files: []ign3types.File{helpers.CreateEncodedIgn3File(path, "thefilecontents", int(defaultFilePermissions))}},
+	})
+
+	drifted, resolved := 0, 0
+	c := &configDriftWatcher{
+		ConfigDriftMonitorOpts: ConfigDriftMonitorOpts{
+			OnDrift:         func(error) { drifted++ },
+			OnDriftResolved: func() { resolved++ },
+			MachineConfig:   mc,
+			SystemdPath:     dir,
+		},
+		filePaths: sets.New(path),
+	}
+	event := fsnotify.Event{Name: path, Op: fsnotify.Write}
+
+	require.NoError(t, c.handleFileEvent(event))
+	assert.Equal(t, 0, resolved, "files without drift are not resolved")
+
+	require.NoError(t, os.WriteFile(path, []byte("notthecontents"), defaultFilePermissions))
+	require.NoError(t, c.handleFileEvent(event))
+	assert.Equal(t, 1, drifted)
+
+	// Events for files outside the config do not resolve the drift.
+	require.NoError(t, c.handleFileEvent(fsnotify.Event{Name: filepath.Join(dir, "other"), Op: fsnotify.Write}))
+	assert.Equal(t, 0, resolved)
+
+	// Fixing the file by hand resolves the drift.
+	require.NoError(t, os.WriteFile(path, []byte("thefilecontents"), defaultFilePermissions))
+	require.NoError(t, c.handleFileEvent(event))
+	require.NoError(t, c.handleFileEvent(event))
+	assert.Equal(t, 1, resolved)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	ign2types "github.com/coreos/ignition/config/v2_2/types"
 	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
@@ -31,6 +32,37 @@ type unitConfigDriftErr struct {
 	error
 }
 
+// Error type for kernel argument config drifts
+type kargsConfigDriftErr struct {
+	error
+}
+
+// Error type for SSH authorized key config drifts
+type sshKeysConfigDriftErr struct {
+	error
+}
+
+// Error type for user password hash config drifts
+type passwordHashConfigDriftErr struct {
+	error
+}
+
+// Error type for booted OS image config drifts
+type osImageConfigDriftErr struct {
+	error
+}
+
+// Error type for extension config drifts
+type extensionsConfigDriftErr struct {
+	error
+}
+
+// A ConfigDriftCheck checks part of the node state which cannot be watched
+// for changes against a MachineConfig. It returns a *configDriftErr if the
+// node drifted from the MachineConfig; any other error means the check could
+// not be done.
+type ConfigDriftCheck func(*mcfgv1.MachineConfig) error
+
 type ConfigDriftMonitor interface {
 	Start(ConfigDriftMonitorOpts) error
 	Done() <-chan struct{}
@@ -41,6 +73,9 @@ type ConfigDriftMonitor interface {
 type ConfigDriftMonitorOpts struct {
 	// Called whenever a config drift is detected.
 	OnDrift func(error)
+	// Called, if set, whenever a check which found config drift passes again,
+	// e.g. because the drifted file was fixed by hand.
+	OnDriftResolved func()
 	// The currently applied MachineConfig.
 	MachineConfig *mcfgv1.MachineConfig
 	// The Systemd dropin path location.
@@ -48,6 +83,12 @@ type ConfigDriftMonitorOpts struct {
 	SystemdPath string
 	// Channel to report unknown errors
 	ErrChan chan<- error
+	// Checks run periodically for drift of node state other than files and
+	// units.
+	PeriodicChecks []ConfigDriftCheck
+	// How often the PeriodicChecks run.
+	// Defaults to 5 minutes.
+	PeriodicCheckInterval time.Duration
 }
 
 // Holds the Config Drift Watcher and ensures we only have a single instance
@@ -65,6 +106,10 @@ type configDriftWatcher struct {
 	filePaths sets.Set[string]
 	wg        sync.WaitGroup
 	stopCh    chan struct{}
+	// The last drift reported by each periodic check, by index.
+	reportedDrift map[int]string
+	// Whether the last file event found drift.
+	fileDrift bool
 }
 
 // Holds a single Config Drift Watcher and starts / stops it as necessary while
@@ -157,9 +202,14 @@ func newConfigDriftWatcher(opts ConfigDriftMonitorOpts) (*configDriftWatcher, er
 		opts.SystemdPath = pathSystemd
 	}
 
+	if opts.PeriodicCheckInterval == 0 {
+		opts.PeriodicCheckInterval = defaultConfigDriftCheckInterval
+	}
+
 	c := &configDriftWatcher{
 		ConfigDriftMonitorOpts: opts,
 		stopCh:                 make(chan struct{}),
+		reportedDrift:          map[int]string{},
 	}
 
 	if err := c.initialize(); err != nil {
@@ -222,10 +272,23 @@ func (c *configDriftWatcher) start() {
 	c.wg = sync.WaitGroup{}
 	c.wg.Add(1)
 
+	// Without periodic checks, the ticker channel stays nil and never fires.
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if len(c.PeriodicChecks) > 0 {
+		ticker = time.NewTicker(c.PeriodicCheckInterval)
+		tick = ticker.C
+	}
+
 	go func() {
 		defer c.wg.Done()
+		if ticker != nil {
+			defer ticker.Stop()
+		}
 		for {
 			select {
+			case <-tick:
+				c.runPeriodicChecks()
 			case event := <-c.watcher.Events:
 				// Our watcher is reporting an event that we should look at.
 				if err := c.handleFileEvent(event); err != nil {
@@ -261,11 +324,16 @@ func (c *configDriftWatcher) handleFileEvent(event fsnotify.Event) error {
 	err := c.checkMachineConfigForEvent(event)
 
 	if err == nil {
+		if c.fileDrift && c.filePaths.Has(event.Name) {
+			c.fileDrift = false
+			c.onDriftResolved()
+		}
 		return nil
 	}
 
 	var cdErr *configDriftErr
 	if errors.As(err, &cdErr) {
+		c.fileDrift = true
 		c.OnDrift(cdErr)
 		// Don't bubble this error up further since it's handled by OnDrift.
 		return nil
@@ -274,6 +342,42 @@ func (c *configDriftWatcher) handleFileEvent(event fsnotify.Event) error {
 	return fmt.Errorf("unknown config drift error: %w", err)
 }
 
+// Runs the periodic checks, filtering drift to the provided callback. Drift is
+// only reported again once it changes, so that an unchanged drift does not
+// cause an event on every check. Checks which fail are only logged since they
+// are retried on the next tick.
+func (c *configDriftWatcher) runPeriodicChecks() {
+	resolved := false
+	for i, check := range c.PeriodicChecks {
+		err := check(c.MachineConfig)
+
+		var cdErr *configDriftErr
+		switch {
+		case err == nil:
+			if _, ok := c.reportedDrift[i]; ok {
+				delete(c.reportedDrift, i)
+				resolved = true
+			}
+		case errors.As(err, &cdErr):
+			if c.reportedDrift[i] != cdErr.Error() {
+				c.reportedDrift[i] = cdErr.Error()
+				c.OnDrift(cdErr)
+			}
+		default:
+			klog.Warningf("Could not check for config drift: %v", err)
+		}
+	}
+	if resolved {
+		c.onDriftResolved()
+	}
+}
+
+func (c *configDriftWatcher) onDriftResolved() {
+	if c.OnDriftResolved != nil {
+		c.OnDriftResolved()
+	}
+}
+
 // Validates on disk state for potential config drift.
 func (c *configDriftWatcher) checkMachineConfigForEvent(event fsnotify.Event) error {
 	// Ignore events for files not found in the MachineConfig.
@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// configDriftPolicy is what the daemon does when it detects config drift.
+type configDriftPolicy string
+
+const (
+	// configDriftPolicyReport only reports the drift through an event.
+	configDriftPolicyReport configDriftPolicy = "Report"
+	// configDriftPolicyDegrade marks the node degraded until the drift is
+	// fixed by hand.
+	configDriftPolicyDegrade configDriftPolicy = "Degrade"
+	// configDriftPolicyRestore rewrites the drifted files and units from the
+	// current config, falling back to degrading the node if that fails.
+	configDriftPolicyRestore configDriftPolicy = "Restore"
+)
+
+// getConfigDriftPolicy returns the config drift policy of a pool.
+func getConfigDriftPolicy(pool *mcfgv1.MachineConfigPool) (configDriftPolicy, error) {
+	value, ok := pool.Annotations[ctrlcommon.ConfigDriftPolicyAnnotationKey]
+	if !ok {
+		return configDriftPolicyDegrade, nil
+	}
+	switch policy := configDriftPolicy(value); policy {
+	case configDriftPolicyReport, configDriftPolicyDegrade, configDriftPolicyRestore:
+		return policy, nil
+	default:
+		return configDriftPolicyDegrade, fmt.Errorf("invalid %s %q on pool %s: must be one of %s, %s or %s",
+			ctrlcommon.ConfigDriftPolicyAnnotationKey, value, pool.Name, configDriftPolicyReport, configDriftPolicyDegrade, configDriftPolicyRestore)
+	}
+}
+
+// getNodeConfigDriftPolicy returns the config drift policy of the node's
+// primary pool, defaulting to degrading the node.
+func (dn *Daemon) getNodeConfigDriftPolicy() configDriftPolicy {
+	if dn.mcpLister == nil || dn.node == nil {
+		return configDriftPolicyDegrade
+	}
+	pool, err := helpers.GetPrimaryPoolForNode(dn.mcpLister, dn.node)
+	if err != nil {
+		klog.Warningf("Could not get pool to determine config drift policy: %v", err)
+		return configDriftPolicyDegrade
+	}
+	if pool == nil {
+		return configDriftPolicyDegrade
+	}
+	policy, err := getConfigDriftPolicy(pool)
+	if err != nil {
+		klog.Warningf("Falling back to config drift policy %s: %v", policy, err)
+	}
+	return policy
+}
+
+// findConfigDrift returns the files and units of a MachineConfig whose
+// on-disk state does not match it.
+func findConfigDrift(mc *mcfgv1.MachineConfig, systemdPath string) ([]ign3types.File, []ign3types.Unit, error) {
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Ignition for config drift: %w", err)
+	}
+
+	var files []ign3types.File
+	for _, file := range ignConfig.Storage.Files {
+		if err := checkV3Files([]ign3types.File{file}, nil); err != nil {
+			files = append(files, file)
+		}
+	}
+	var units []ign3types.Unit
+	for _, unit := range ignConfig.Systemd.Units {
+		if err := checkV3Unit(unit, systemdPath, nil); err != nil {
+			units = append(units, unit)
+		}
+	}
+	return files, units, nil
+}
+
+// hashOnDiskContents returns the SHA256 of a file's contents, or "absent" if
+// it does not exist.
+func hashOnDiskContents(path string) string {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "absent"
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(contents))
+}
+
+// restoreConfigDrift rewrites the files and units of the given MachineConfig
+// which drifted and runs the node disruption policy actions for them. Drift
+// which needs a drain or reboot to restore is not restored.
+func (dn *Daemon) restoreConfigDrift(mc *mcfgv1.MachineConfig) error {
+	files, units, err := findConfigDrift(mc, pathSystemd)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 && len(units) == 0 {
+		return fmt.Errorf("could not find the drifted files or units")
+	}
+
+	if dn.mcopLister == nil {
+		return fmt.Errorf("node disruption policies are not available")
+	}
+	mcop, err := dn.mcopLister.Get(ctrlcommon.MCOOperatorKnobsObjectName)
+	if err != nil {
+		return fmt.Errorf("could not get node disruption policies: %w", err)
+	}
+
+	var paths, filePaths, unitNames []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+		filePaths = append(filePaths, file.Path)
+	}
+	for _, unit := range units {
+		unitNames = append(unitNames, unit.Name)
+		if unitHasContent(unit) {
+			paths = append(paths, getIgn3SystemdUnitPath(pathSystemd, unit))
+		}
+		for _, dropin := range unit.Dropins {
+			paths = append(paths, getIgn3SystemdDropinPath(pathSystemd, unit, dropin))
+		}
+	}
+
+	actions := calculatePostConfigChangeNodeDisruptionActionFromMCDiffs(false, filePaths, unitNames, mcop.Status.NodeDisruptionPolicyStatus.ClusterPolicies)
+	if apihelpers.CheckNodeDisruptionActionsForTargetActions(actions, opv1.RebootStatusAction, opv1.DrainStatusAction) {
+		return fmt.Errorf("restoring %s needs a drain or reboot", strings.Join(paths, ", "))
+	}
+
+	before := map[string]string{}
+	for _, path := range paths {
+		before[path] = hashOnDiskContents(path)
+	}
+
+	logSystem("Restoring config drift of %s from %s", strings.Join(paths, ", "), mc.Name)
+	if err := dn.writeFiles(files, false); err != nil {
+		return fmt.Errorf("could not restore files: %w", err)
+	}
+	if err := dn.writeUnits(units); err != nil {
+		return fmt.Errorf("could not restore units: %w", err)
+	}
+
+	for _, action := range actions {
+		if err := dn.performNodeDisruptionServiceAction(action); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range paths {
+		dn.nodeWriter.Eventf(corev1.EventTypeNormal, "ConfigDriftRestored", "Restored %s from %s (sha256 before: %s, after: %s)",
+			path, mc.Name, before[path], hashOnDiskContents(path))
+	}
+	return nil
+}
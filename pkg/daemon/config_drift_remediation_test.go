@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetConfigDriftPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    configDriftPolicy
+		expectErr   bool
+	}{
+		{
+			name:     "defaults to degrade",
+			expected: configDriftPolicyDegrade,
+		},
+		{
+			name:        "report",
+			annotations: map[string]string{ctrlcommon.ConfigDriftPolicyAnnotationKey: "Report"},
+			expected:    configDriftPolicyReport,
+		},
+		{
+			name:        "restore",
+			annotations: map[string]string{ctrlcommon.ConfigDriftPolicyAnnotationKey: "Restore"},
+			expected:    configDriftPolicyRestore,
+		},
+		{
+			name:        "invalid falls back to degrade",
+			annotations: map[string]string{ctrlcommon.ConfigDriftPolicyAnnotationKey: "restore"},
+			expected:    configDriftPolicyDegrade,
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+			pool.Annotations = test.annotations
+
+			policy, err := getConfigDriftPolicy(pool)
+			if test.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, policy)
+		})
+	}
+}
+
+func TestFindConfigDrift(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	systemdPath := filepath.Join(dir, "systemd")
+	require.NoError(t, os.MkdirAll(filepath.Join(systemdPath, "foo.service.d"), 0o755))
+
+	matching := ctrlcommon.NewIgnFile(filepath.Join(dir, "matching"), "matching\n")
+	drifted := ctrlcommon.NewIgnFile(filepath.Join(dir, "drifted"), "expected\n")
+	require.NoError(t, os.WriteFile(matching.Path, []byte("matching\n"), 0o644))
+	require.NoError(t, os.WriteFile(drifted.Path, []byte("edited\n"), 0o644))
+
+	unit := ign3types.Unit{Name: "foo.service", Contents: ptr.To("[Unit]\n")}
+	driftedUnit := ign3types.Unit{
+		Name:     "bar.service",
+		Contents: ptr.To("[Unit]\n"),
+		Dropins:  []ign3types.Dropin{{Name: "10-bar.conf", Contents: ptr.To("[Service]\n")}},
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(systemdPath, "foo.service"), []byte("[Unit]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(systemdPath, "bar.service"), []byte("[Unit]\n"), 0o644))
+
+	mc := helpers.NewMachineConfigExtended("rendered-worker-0", nil, nil, []ign3types.File{matching, drifted},
+		[]ign3types.Unit{unit, driftedUnit}, []ign3types.SSHAuthorizedKey{}, nil, false, nil, "", "dummy://")
+
+	files, units, err := findConfigDrift(mc, systemdPath)
+	require.NoError(t, err)
+	assert.Equal(t, []ign3types.File{drifted}, files)
+	assert.Equal(t, []ign3types.Unit{driftedUnit}, units)
+
+	assert.Equal(t, "absent", hashOnDiskContents(filepath.Join(dir, "missing")))
+	assert.Len(t, hashOnDiskContents(drifted.Path), 64)
+
+	_, _, err = findConfigDrift(&mcfgv1.MachineConfig{}, systemdPath)
+	assert.Error(t, err)
+}
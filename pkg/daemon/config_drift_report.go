@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+	"k8s.io/klog/v2"
+)
+
+// inspectConfigDrift returns how each file, unit and dropin of a
+// MachineConfig drifted from it on disk. The checks are the same as
+// validateOnDiskState, but all the drift is collected instead of only the
+// first one.
+func inspectConfigDrift(mc *mcfgv1.MachineConfig, systemdPath string, cmdRunner CommandRunner) ([]upgrademonitor.ConfigDriftEntry, error) {
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Ignition for config drift: %w", err)
+	}
+
+	var entries []upgrademonitor.ConfigDriftEntry
+	filesToIgnore := getFilesToIgnore()
+	for _, f := range ignConfig.Storage.Files {
+		if filesToIgnore.Has(f.Path) {
+			continue
+		}
+		mode := defaultFilePermissions
+		if f.Mode != nil {
+			mode = os.FileMode(*f.Mode) //nolint:gosec
+		}
+		contents, err := ctrlcommon.DecodeIgnitionFileContents(f.Contents.Source, f.Contents.Compression)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode file %q: %w", f.Path, err)
+		}
+		if entry := inspectFileDrift(f.Path, contents, mode); entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	for _, unit := range ignConfig.Systemd.Units {
+		entries = append(entries, inspectUnitDrift(unit, systemdPath, cmdRunner)...)
+	}
+	return entries, nil
+}
+
+// inspectUnitDrift is inspectConfigDrift for a single unit and its dropins.
+func inspectUnitDrift(unit ign3types.Unit, systemdPath string, cmdRunner CommandRunner) []upgrademonitor.ConfigDriftEntry {
+	var entries []upgrademonitor.ConfigDriftEntry
+	for _, dropin := range unit.Dropins {
+		path := getIgn3SystemdDropinPath(systemdPath, unit, dropin)
+		content := ""
+		if dropin.Contents != nil {
+			content = *dropin.Contents
+		}
+		// Empty dropins are removed, as in checkV3Dropin.
+		if _, err := os.Stat(path); content == "" && os.IsNotExist(err) {
+			continue
+		}
+		if entry := inspectFileDrift(path, []byte(content), defaultFilePermissions); entry != nil {
+			entry.Unit = unit.Name
+			entries = append(entries, *entry)
+		}
+	}
+
+	if unit.Contents == nil || *unit.Contents == "" {
+		return entries
+	}
+
+	path := getIgn3SystemdUnitPath(systemdPath, unit)
+	if unit.Mask != nil && *unit.Mask {
+		if link, err := filepath.EvalSymlinks(path); err != nil || link != pathDevNull {
+			entries = append(entries, upgrademonitor.ConfigDriftEntry{
+				Path:     path,
+				Unit:     unit.Name,
+				Kind:     upgrademonitor.ConfigDriftKindEnabledState,
+				Expected: "masked",
+				Actual:   getUnitEnabledState(cmdRunner, unit.Name),
+			})
+		}
+		return entries
+	}
+
+	if entry := inspectFileDrift(path, []byte(*unit.Contents), defaultFilePermissions); entry != nil {
+		entry.Unit = unit.Name
+		return append(entries, *entry)
+	}
+
+	if unit.Enabled != nil {
+		if state := getUnitEnabledState(cmdRunner, unit.Name); !unitEnabledStateMatches(*unit.Enabled, state) {
+			expected := "disabled"
+			if *unit.Enabled {
+				expected = "enabled"
+			}
+			entries = append(entries, upgrademonitor.ConfigDriftEntry{
+				Path:     path,
+				Unit:     unit.Name,
+				Kind:     upgrademonitor.ConfigDriftKindEnabledState,
+				Expected: expected,
+				Actual:   state,
+			})
+		}
+	}
+	return entries
+}
+
+// inspectFileDrift returns how a file drifted from its expected contents and
+// mode, in the order checkFileContentsAndMode checks them, or nil if it did
+// not. Drift which cannot be inspected is reported as missing.
+func inspectFileDrift(path string, expectedContent []byte, mode os.FileMode) *upgrademonitor.ConfigDriftEntry {
+	entry := &upgrademonitor.ConfigDriftEntry{
+		Path:         path,
+		ExpectedHash: fmt.Sprintf("%x", sha256.Sum256(expectedContent)),
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		entry.Kind = upgrademonitor.ConfigDriftKindMissing
+		return entry
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		entry.Kind = upgrademonitor.ConfigDriftKindMissing
+		return entry
+	}
+	entry.ActualHash = fmt.Sprintf("%x", sha256.Sum256(contents))
+
+	switch {
+	case fi.Mode() != mode:
+		entry.Kind = upgrademonitor.ConfigDriftKindMode
+		entry.Expected = mode.String()
+		entry.Actual = fi.Mode().String()
+	case !bytes.Equal(contents, expectedContent):
+		entry.Kind = upgrademonitor.ConfigDriftKindContent
+	default:
+		return nil
+	}
+	return entry
+}
+
+// reportConfigDrift publishes the config drift of the node from the given
+// MachineConfig on its MachineConfigNode. Failures are only logged since the
+// drift is also reported through events and the node state.
+func (dn *Daemon) reportConfigDrift(mc *mcfgv1.MachineConfig) {
+	if dn.node == nil {
+		return
+	}
+	entries, err := inspectConfigDrift(mc, pathSystemd, dn.cmdRunner)
+	if err != nil {
+		klog.Warningf("Could not inspect config drift: %v", err)
+		return
+	}
+	if err := upgrademonitor.UpdateMachineConfigNodeConfigDrift(dn.fgHandler, dn.mcfgClient, dn.node.Name, entries); err != nil {
+		klog.Warningf("Could not report config drift on MachineConfigNode: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestInspectConfigDrift(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	systemdPath := filepath.Join(dir, "systemd")
+	require.NoError(t, os.MkdirAll(filepath.Join(systemdPath, "foo.service.d"), 0o755))
+
+	matching := ctrlcommon.NewIgnFile(filepath.Join(dir, "matching"), "matching\n")
+	content := ctrlcommon.NewIgnFile(filepath.Join(dir, "content"), "expected\n")
+	mode := ctrlcommon.NewIgnFile(filepath.Join(dir, "mode"), "mode\n")
+	missing := ctrlcommon.NewIgnFile(filepath.Join(dir, "missing"), "missing\n")
+	require.NoError(t, os.WriteFile(matching.Path, []byte("matching\n"), 0o644))
+	require.NoError(t, os.WriteFile(content.Path, []byte("edited\n"), 0o644))
+	require.NoError(t, os.WriteFile(mode.Path, []byte("mode\n"), 0o644))
+	require.NoError(t, os.Chmod(mode.Path, 0o755))
+
+	units := []ign3types.Unit{
+		{
+			Name:     "foo.service",
+			Contents: ptr.To("[Unit]\n"),
+			Enabled:  ptr.To(true),
+			Dropins:  []ign3types.Dropin{{Name: "10-foo.conf", Contents: ptr.To("[Service]\n")}, {Name: "20-empty.conf"}},
+		},
+		{Name: "bar.service", Contents: ptr.To("[Unit]\n"), Enabled: ptr.To(false)},
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(systemdPath, "foo.service"), []byte("[Unit]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(systemdPath, "bar.service"), []byte("[Unit]\n"), 0o644))
+
+	mc := helpers.NewMachineConfigExtended("rendered-worker-0", nil, nil, []ign3types.File{matching, content, mode, missing},
+		units, []ign3types.SSHAuthorizedKey{}, nil, false, nil, "", "dummy://")
+
+	cmdRunner := &MockCommandRunner{
+		outputs: map[string][]byte{
+			"systemctl is-enabled foo.service": []byte("disabled\n"),
+			"systemctl is-enabled bar.service": []byte("disabled\n"),
+		},
+	}
+
+	entries, err := inspectConfigDrift(mc, systemdPath, cmdRunner)
+	require.NoError(t, err)
+
+	byPath := map[string]upgrademonitor.ConfigDriftEntry{}
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	assert.Len(t, entries, 5)
+
+	assert.Equal(t, upgrademonitor.ConfigDriftKindContent, byPath[content.Path].Kind)
+	assert.NotEmpty(t, byPath[content.Path].ActualHash)
+	assert.NotEqual(t, byPath[content.Path].ExpectedHash, byPath[content.Path].ActualHash)
+
+	assert.Equal(t, upgrademonitor.ConfigDriftKindMode, byPath[mode.Path].Kind)
+	assert.Equal(t, "-rw-r--r--", byPath[mode.Path].Expected)
+	assert.Equal(t, "-rwxr-xr-x", byPath[mode.Path].Actual)
+
+	assert.Equal(t, upgrademonitor.ConfigDriftKindMissing, byPath[missing.Path].Kind)
+	assert.Empty(t, byPath[missing.Path].ActualHash)
+
+	dropin := byPath[filepath.Join(systemdPath, "foo.service.d", "10-foo.conf")]
+	assert.Equal(t, upgrademonitor.ConfigDriftKindMissing, dropin.Kind)
+	assert.Equal(t, "foo.service", dropin.Unit)
+
+	unit := byPath[filepath.Join(systemdPath, "foo.service")]
+	assert.Equal(t, upgrademonitor.ConfigDriftKindEnabledState, unit.Kind)
+	assert.Equal(t, "enabled", unit.Expected)
+	assert.Equal(t, "disabled", unit.Actual)
+}
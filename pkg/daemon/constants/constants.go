@@ -17,6 +17,22 @@ const (
 	CurrentMachineConfigAnnotationKey = "machineconfiguration.openshift.io/currentConfig"
 	// DesiredMachineConfigAnnotationKey is used to specify the desired MachineConfig for a machine
 	DesiredMachineConfigAnnotationKey = "machineconfiguration.openshift.io/desiredConfig"
+	// UpdatePlanAnnotationKey is set by the daemon on its MachineConfigNode to describe what moving to the
+	// pool's pending config would do to the node, before the node is asked to update.
+	UpdatePlanAnnotationKey = "machineconfiguration.openshift.io/updatePlan"
+	// ConfigDriftReportAnnotationKey is set by the daemon on its MachineConfigNode to list the files and units
+	// which drifted from the current config. It is removed once the drift is gone.
+	ConfigDriftReportAnnotationKey = "machineconfiguration.openshift.io/configDriftReport"
+	// PinnedImageGCReportAnnotationKey is set by the daemon on its MachineConfigNode to report the images it removed
+	// after they were dropped from the node's PinnedImageSets, and the bytes this reclaimed.
+	PinnedImageGCReportAnnotationKey = "machineconfiguration.openshift.io/pinnedImageGCReport"
+	// PinnedImageSetProgressAnnotationKey is set by the daemon on its MachineConfigNode to report, for each of the
+	// node's PinnedImageSets, whether each image is pending, pulling, pulled or failed, and the bytes downloaded.
+	// Images are left out when the report grows too large, and counted in the truncated field of their set.
+	PinnedImageSetProgressAnnotationKey = "machineconfiguration.openshift.io/pinnedImageSetProgress"
+	// InitialConfigSelectionAnnotationKey is set by the machine-config-server in the initial node annotations to record
+	// whether the node was served the current or the target config of its pool, and under which new node config policy.
+	InitialConfigSelectionAnnotationKey = "machineconfiguration.openshift.io/initialConfigSelection"
 	// FirstPivotMachineConfigAnnotationKey is used to specify the MachineConfig the node pivoted to after firstboot.
 	FirstPivotMachineConfigAnnotationKey = "machineconfiguration.openshift.io/firstPivotConfig"
 	// CustomPoolLabelsAppliedAnnotationKey is set by the node controller to indicate custom pool labels were automatically applied
@@ -31,6 +47,10 @@ const (
 	DrainerStateDrain = "drain"
 	// DrainerStateUncordon is used for drainer annotation as a value to indicate needing an uncordon
 	DrainerStateUncordon = "uncordon"
+	// PrefetchRequestedAnnotationKey is set by the MCD to the time it asked to prefetch PinnedImageSet images
+	PrefetchRequestedAnnotationKey = "machineconfiguration.openshift.io/prefetchRequested"
+	// PrefetchGrantedAnnotationKey is set to "true" by the controller when a node requesting to prefetch may pull images
+	PrefetchGrantedAnnotationKey = "machineconfiguration.openshift.io/prefetchGranted"
 	// ClusterControlPlaneTopologyAnnotationKey is set by the node controller by reading value from
 	// controllerConfig. MCD uses the annotation value to decide drain action on the node.
 	ClusterControlPlaneTopologyAnnotationKey = "machineconfiguration.openshift.io/controlPlaneTopology"
@@ -65,6 +85,8 @@ const (
 	InitialNodeAnnotationsFilePath = "/etc/machine-config-daemon/node-annotations.json"
 	// InitialNodeAnnotationsBakPath defines the path of InitialNodeAnnotationsFilePath when the initial bootstrap is done. We leave it around for debugging and reconciling.
 	InitialNodeAnnotationsBakPath = "/etc/machine-config-daemon/node-annotation.json.bak"
+	// CurrentMachineConfigPath is where the daemon stores the MachineConfig the node is currently on.
+	CurrentMachineConfigPath = "/etc/machine-config-daemon/currentconfig"
 
 	// IgnitionSystemdPresetFile is where Ignition writes initial enabled/disabled systemd unit configs
 	// This should be removed on boot after MCO takes over, so if any of these are deleted we can go back
@@ -145,6 +167,22 @@ const (
 	// changes to this path. Note that other files added to the parent directory will not be handled specially
 	GPGNoRebootPath = "/etc/machine-config-daemon/no-reboot/containers-gpg.pub"
 
+	// ValidationHooksDir is where MachineConfigs may ship executable validation hooks. After every update, the MCD
+	// runs them in lexical order before uncordoning the node, and degrades the node if one fails.
+	ValidationHooksDir = "/etc/machine-config-daemon/validation-hooks.d"
+
+	// ValidationHookUnitOption marks a systemd unit of a MachineConfig as a validation hook when set to "yes" in the
+	// unit's [Unit] section. The MCD starts such units after the hooks in ValidationHooksDir.
+	ValidationHookUnitOption = "X-MachineConfigValidationHook"
+
+	// PreUpdateHooksDir is where MachineConfigs may ship executable pre-update hooks. Before draining a node for an
+	// update, the MCD runs them in lexical order and lets them proceed with, delay or reject the update.
+	PreUpdateHooksDir = "/etc/machine-config-daemon/pre-update-hooks.d"
+
+	// PinnedImagesPulledFilePath is where the MCD records the PinnedImageSet images it pulled, which it removes once
+	// they are no longer pinned. Images which were on the node before being pinned are left alone.
+	PinnedImagesPulledFilePath = "/etc/machine-config-daemon/pinned-images-pulled.json"
+
 	KubernetesCredentialProvidersDir = "/etc/kubernetes/credential-providers"
 
 	KubeletCrioImageCredProviderConfPath = "/etc/systemd/system/kubelet.service.d/40-kubelet-crio-image-credential-provider.conf"
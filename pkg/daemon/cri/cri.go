@@ -32,14 +32,16 @@ func NewClient(ctx context.Context, target string) (*Client, error) {
 		return nil, err
 	}
 	return &Client{
-		conn:  conn,
-		image: runtimeapi.NewImageServiceClient(conn),
+		conn:    conn,
+		image:   runtimeapi.NewImageServiceClient(conn),
+		runtime: runtimeapi.NewRuntimeServiceClient(conn),
 	}, nil
 }
 
 type Client struct {
-	conn  *grpc.ClientConn
-	image runtimeapi.ImageServiceClient
+	conn    *grpc.ClientConn
+	image   runtimeapi.ImageServiceClient
+	runtime runtimeapi.RuntimeServiceClient
 }
 
 // PullImage pulls the image from the container runtime. The auth parameter can
@@ -69,18 +71,23 @@ func (c *Client) PullImage(ctx context.Context, image string, auth *runtimeapi.A
 
 // ImageStatus returns true if the image exists in the container runtime.
 func (c *Client) ImageStatus(ctx context.Context, image string) (bool, error) {
+	img, err := c.GetImage(ctx, image)
+	if err != nil {
+		return false, err
+	}
+	return img != nil, nil
+}
+
+// GetImage returns the image from the container runtime, or nil if it does
+// not exist.
+func (c *Client) GetImage(ctx context.Context, image string) (*runtimeapi.Image, error) {
 	resp, err := c.image.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{
 		Image: &runtimeapi.ImageSpec{Image: image},
 	})
 	if err != nil {
-		return false, fmt.Errorf("failed to get image status for %q: %w", image, err)
-	}
-
-	if resp.Image != nil {
-		return true, nil
+		return nil, fmt.Errorf("failed to get image status for %q: %w", image, err)
 	}
-
-	return false, nil
+	return resp.Image, nil
 }
 
 // RemoveImage removes the image from the container runtime.
@@ -108,6 +115,24 @@ func (c *Client) ListImages(ctx context.Context, filter string) ([]*runtimeapi.I
 	return resp.Images, nil
 }
 
+// ListContainers returns the containers of the container runtime which are not
+// exited.
+func (c *Client) ListContainers(ctx context.Context) ([]*runtimeapi.Container, error) {
+	var containers []*runtimeapi.Container
+	for _, state := range []runtimeapi.ContainerState{runtimeapi.ContainerState_CONTAINER_CREATED, runtimeapi.ContainerState_CONTAINER_RUNNING} {
+		resp, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+			Filter: &runtimeapi.ContainerFilter{
+				State: &runtimeapi.ContainerStateValue{State: state},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, resp.Containers...)
+	}
+	return containers, nil
+}
+
 // ImageFsInfo returns information about the filesystem that is used to store images.
 func (c *Client) ImageFsInfo(ctx context.Context) (*runtimeapi.ImageFsInfoResponse, error) {
 	return c.image.ImageFsInfo(ctx, &runtimeapi.ImageFsInfoRequest{})
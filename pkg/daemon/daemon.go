@@ -159,6 +159,10 @@ type Daemon struct {
 
 	irreconcilableReporter IrreconcilableReporter
 
+	// publishedUpdatePlan is what the update plan last published on the
+	// node's MachineConfigNode was computed for.
+	publishedUpdatePlan *updatePlanState
+
 	// Ensures that only a single syncOSImagePullSecrets call can run at a time.
 	osImageMux *sync.Mutex
 
@@ -197,7 +201,7 @@ const (
 	pathDevNull = "/dev/null"
 	// currentConfigPath is where we store the current config on disk to validate
 	// against annotations changes
-	currentConfigPath = "/etc/machine-config-daemon/currentconfig"
+	currentConfigPath = constants.CurrentMachineConfigPath
 	// bootstrapConfigDiffPath is where we store the current config on disk to validate
 	// against annotations changes
 	bootstrapConfigDiffPath = "/etc/machine-config-daemon/bootstrapconfigdiff"
@@ -404,6 +408,9 @@ func (dn *Daemon) ClusterConnect(
 	})
 	dn.ccLister = ccInformer.Lister()
 	dn.ccListerSynced = ccInformer.Informer().HasSynced
+	mcpInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: dn.handleMachineConfigPoolUpdate,
+	})
 	dn.mcpLister = mcpInformer.Lister()
 	dn.mcpListerSynced = mcpInformer.Informer().HasSynced
 	dn.mcopLister = mcopInformer.Lister()
@@ -630,6 +637,15 @@ func (dn *Daemon) handleErr(err error, key string) {
 		return
 	}
 
+	// A pre-update hook delayed the update; retry once the delay is over.
+	var delayErr *preUpdateHookDelayErr
+	if errors.As(err, &delayErr) {
+		klog.Infof("Retrying node sync %v after delay: %v", key, err)
+		dn.queue.Forget(key)
+		dn.queue.AddAfter(key, time.Until(delayErr.until))
+		return
+	}
+
 	// Exit if nodewriter is not initialized, used for Hypershift
 	if dn.nodeWriter == nil {
 		dn.updateErrorStateHypershift(err)
@@ -870,6 +886,12 @@ func (dn *Daemon) syncNode(key string) error {
 		return err
 	}
 
+	// Let users preview the pool's pending config before the node is asked to
+	// update to it, e.g. while the pool is paused.
+	if err := dn.reportUpdatePlan(dn.node, pool); err != nil {
+		klog.Errorf("Error reporting update plan on MCN: %v", err)
+	}
+
 	if ufc != nil {
 		err = upgrademonitor.GenerateAndApplyMachineConfigNodes(
 			&upgrademonitor.Condition{State: mcfgv1.MachineConfigNodeUpdated, Reason: string(mcfgv1.MachineConfigNodeUpdated), Message: fmt.Sprintf("Node %s needs an update", dn.node.GetName())},
@@ -1452,15 +1474,56 @@ func (dn *Daemon) kubeletRebootstrap(ctx context.Context) error {
 }
 
 // Called whenever the on-disk config has drifted from the current machineconfig.
+// What happens next depends on the config drift policy of the node's pool.
 func (dn *Daemon) onConfigDrift(err error) {
 	mcdConfigDrift.SetToCurrentTime()
 	dn.nodeWriter.Eventf(corev1.EventTypeWarning, "ConfigDriftDetected", err.Error())
 	klog.Error(err)
+
+	odc, odcErr := dn.getCurrentConfigForConfigDrift()
+	if odcErr != nil {
+		klog.Errorf("Could not get current config for config drift: %v", odcErr)
+	} else {
+		// Publish what drifted once the policy was applied, so that restored
+		// drift is no longer listed.
+		defer dn.reportConfigDrift(odc.currentConfig)
+	}
+
+	switch policy := dn.getNodeConfigDriftPolicy(); policy {
+	case configDriftPolicyReport:
+		klog.Infof("Not degrading node for config drift: config drift policy is %s", policy)
+		return
+	case configDriftPolicyRestore:
+		restoreErr := odcErr
+		if odcErr == nil {
+			restoreErr = dn.restoreConfigDrift(odc.currentConfig)
+		}
+		if restoreErr == nil {
+			mcdConfigDrift.Set(0)
+			return
+		}
+		klog.Errorf("Could not restore config drift: %v", restoreErr)
+		err = fmt.Errorf("%w; could not restore: %v", err, restoreErr)
+	}
+
 	if err := dn.updateErrorState(err); err != nil {
 		klog.Errorf("Could not update annotation: %v", err)
 	}
 }
 
+// getCurrentConfigForConfigDrift gets the current config the Config Drift
+// Monitor watches.
+func (dn *Daemon) getCurrentConfigForConfigDrift() (*onDiskConfig, error) {
+	odc, err := dn.getCurrentConfigOnDisk()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not get current config from disk: %w", err)
+	}
+	if odc == nil {
+		return dn.getCurrentConfigFromNode()
+	}
+	return odc, nil
+}
+
 // getCurrentConfigFromNode fetch the current config through node annotations to respond to getCurrentConfigDisk
 // calls where the ODC is missing due to manual deletion and other reasons.
 func (dn *Daemon) getCurrentConfigFromNode() (*onDiskConfig, error) {
@@ -1531,11 +1594,16 @@ func (dn *Daemon) startConfigDriftMonitor() {
 		}
 	}
 
+	currentConfig := odc.currentConfig
 	opts := ConfigDriftMonitorOpts{
-		OnDrift:       dn.onConfigDrift,
-		SystemdPath:   pathSystemd,
-		ErrChan:       dn.exitCh,
-		MachineConfig: odc.currentConfig,
+		OnDrift: dn.onConfigDrift,
+		// Drop drift which is gone from the report, e.g. when fixed by hand
+		// under the Report policy.
+		OnDriftResolved: func() { dn.reportConfigDrift(currentConfig) },
+		SystemdPath:     pathSystemd,
+		ErrChan:         dn.exitCh,
+		MachineConfig:   odc.currentConfig,
+		PeriodicChecks:  dn.getConfigDriftChecks(odc.currentImage),
 	}
 
 	if err := dn.configDriftMonitor.Start(opts); err != nil {
@@ -1546,6 +1614,9 @@ func (dn *Daemon) startConfigDriftMonitor() {
 	dn.nodeWriter.Eventf(corev1.EventTypeNormal, "ConfigDriftMonitorStarted",
 		"Config Drift Monitor started, watching against %s", odc.currentConfig.Name)
 
+	// Replace any drift reported against a previous config.
+	dn.reportConfigDrift(odc.currentConfig)
+
 	go func() {
 		// Common shutdown function
 		shutdown := func() {
@@ -1620,7 +1691,7 @@ func (dn *Daemon) getHealth() error {
 
 	if string(respData) != "ok" {
 		klog.Warningf("Kubelet Healthz Endpoint returned: %s", string(respData))
-		return nil
+		return fmt.Errorf("kubelet healthz endpoint returned %q", string(respData))
 	}
 
 	klog.V(4).Info("Kubelet health ok")
@@ -2371,6 +2442,21 @@ func (dn *Daemon) updateConfigAndState(state *stateAndConfigs) (bool, bool, erro
 		} else {
 			klog.Infof("Completing update to target %s", state.getCurrentName())
 		}
+		// The node's annotations only move to the config written to disk once
+		// the update completes, so a difference means we're completing one.
+		if dn.node != nil && (state.currentConfig.GetName() != dn.node.Annotations[constants.CurrentMachineConfigAnnotationKey] ||
+			state.currentImage != dn.node.Annotations[constants.CurrentImageAnnotationKey]) {
+			// Keep the node cordoned and not done until it is healthy, so that a
+			// broken config stops the rollout.
+			if err := dn.checkNodeHealth(); err != nil {
+				UpdateStateMetric(mcdUpdateState, "", err.Error())
+				return missingODC, inDesiredConfig, err
+			}
+			if err := dn.runValidationHooks(state.currentConfig); err != nil {
+				UpdateStateMetric(mcdUpdateState, "", err.Error())
+				return missingODC, inDesiredConfig, err
+			}
+		}
 		if err := dn.completeUpdate(state.currentConfig.GetName()); err != nil {
 			UpdateStateMetric(mcdUpdateState, "", err.Error())
 			return missingODC, inDesiredConfig, err
@@ -2704,7 +2790,7 @@ func (dn *CoreOSDaemon) validateKernelArguments(currentConfig *mcfgv1.MachineCon
 		}
 		klog.Infof("Current ostree kargs: %s", rpmostreeKargs)
 		klog.Infof("Expected MachineConfig kargs: %v", expected)
-		return fmt.Errorf("missing expected kernel arguments: %v", missing)
+		return &kargsConfigDriftErr{fmt.Errorf("missing expected kernel arguments: %v", missing)}
 	}
 	return nil
 }
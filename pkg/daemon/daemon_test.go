@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,10 +79,18 @@ func TestValidateFiles(t *testing.T) {
 		},
 	}
 
-	if err := checkV3Files(filesV3); err != nil {
+	if err := checkV3Files(filesV3, nil); err != nil {
 		t.Errorf("Invalid files: %v", err)
 	}
 
+	// a mismatch names the MachineConfig the file comes from
+	filesV3[0].Contents.Source = helpers.StrToPtr(dataurl.EncodeBytes([]byte("goodbye world\n")))
+	provenance := &ctrlcommon.MachineConfigProvenance{Files: map[string]string{"fixtures/test1.txt": "99-worker-test"}}
+	err = checkV3Files(filesV3, provenance)
+	if err == nil || !strings.Contains(err.Error(), `content mismatch for file "fixtures/test1.txt" (from MachineConfig 99-worker-test)`) {
+		t.Errorf("Expected content mismatch attributed to 99-worker-test, got: %v", err)
+	}
+
 	// validate overwritten file in spec 2
 	filesV2 := []ign2types.File{
 		{
@@ -0,0 +1,236 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// HealthProbeType is the kind of check a HealthProbe does.
+type HealthProbeType string
+
+const (
+	// HealthProbeHTTP passes if a GET of the URL returns a 2xx status.
+	HealthProbeHTTP HealthProbeType = "HTTP"
+	// HealthProbeTCP passes if a TCP connection to the address succeeds.
+	HealthProbeTCP HealthProbeType = "TCP"
+	// HealthProbeExec passes if the command exits successfully.
+	HealthProbeExec HealthProbeType = "Exec"
+	// HealthProbeSystemdUnit passes if the systemd unit is active.
+	HealthProbeSystemdUnit HealthProbeType = "SystemdUnit"
+)
+
+const (
+	// How long a single probe may take by default.
+	defaultHealthProbeTimeout = 10 * time.Second
+	// How often failing probes are retried after an update or reboot.
+	healthProbesPollingInterval = 10 * time.Second
+	// How long the probes have to pass after an update or reboot, since the
+	// services they check may still be starting.
+	healthProbesTimeout = 5 * time.Minute
+)
+
+// HealthProbe is a check of a node's health, configured per pool through
+// ctrlcommon.HealthProbesAnnotationKey, e.g.:
+//
+//	[{"name": "crio", "type": "SystemdUnit", "unit": "crio.service"},
+//	 {"name": "kubelet", "type": "HTTP", "url": "http://localhost:10248/healthz"}]
+type HealthProbe struct {
+	Name string          `json:"name"`
+	Type HealthProbeType `json:"type"`
+	// URL is the URL an HTTP probe gets.
+	URL string `json:"url,omitempty"`
+	// Address is the host:port a TCP probe connects to.
+	Address string `json:"address,omitempty"`
+	// Command is the command and arguments an Exec probe runs.
+	Command []string `json:"command,omitempty"`
+	// Unit is the systemd unit a SystemdUnit probe checks.
+	Unit string `json:"unit,omitempty"`
+	// TimeoutSeconds limits how long the probe may take. Defaults to 10.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+func (p HealthProbe) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("health probe has no name")
+	}
+	if p.TimeoutSeconds < 0 {
+		return fmt.Errorf("health probe %s: timeoutSeconds must not be negative", p.Name)
+	}
+
+	var missing string
+	switch p.Type {
+	case HealthProbeHTTP:
+		if p.URL == "" {
+			missing = "url"
+		}
+	case HealthProbeTCP:
+		if p.Address == "" {
+			missing = "address"
+		}
+	case HealthProbeExec:
+		if len(p.Command) == 0 {
+			missing = "command"
+		}
+	case HealthProbeSystemdUnit:
+		if p.Unit == "" {
+			missing = "unit"
+		}
+	default:
+		return fmt.Errorf("health probe %s: invalid type %q: must be one of %s, %s, %s or %s",
+			p.Name, p.Type, HealthProbeHTTP, HealthProbeTCP, HealthProbeExec, HealthProbeSystemdUnit)
+	}
+	if missing != "" {
+		return fmt.Errorf("health probe %s: %s probes need a %s", p.Name, p.Type, missing)
+	}
+	return nil
+}
+
+// run runs the probe, returning why it failed if it did.
+func (p HealthProbe) run(ctx context.Context, cmdRunner CommandRunner) error {
+	timeout := defaultHealthProbeTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch p.Type {
+	case HealthProbeHTTP:
+		return runHTTPHealthProbe(ctx, p.URL)
+	case HealthProbeTCP:
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case HealthProbeExec:
+		out, err := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	case HealthProbeSystemdUnit:
+		// is-active exits non-zero for inactive units, so only go by its output.
+		out, _ := cmdRunner.RunGetOut("systemctl", "is-active", p.Unit)
+		if state := strings.TrimSpace(string(out)); state != "active" {
+			return fmt.Errorf("unit %s is %q", p.Unit, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid health probe type %q", p.Type)
+	}
+}
+
+func runHTTPHealthProbe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// getHealthProbes returns the health probes of a pool.
+func getHealthProbes(pool *mcfgv1.MachineConfigPool) ([]HealthProbe, error) {
+	value, ok := pool.Annotations[ctrlcommon.HealthProbesAnnotationKey]
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var probes []HealthProbe
+	if err := json.Unmarshal([]byte(value), &probes); err != nil {
+		return nil, fmt.Errorf("could not parse %s of pool %s: %w", ctrlcommon.HealthProbesAnnotationKey, pool.Name, err)
+	}
+	names := map[string]bool{}
+	for _, probe := range probes {
+		if err := probe.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s of pool %s: %w", ctrlcommon.HealthProbesAnnotationKey, pool.Name, err)
+		}
+		if names[probe.Name] {
+			return nil, fmt.Errorf("invalid %s of pool %s: duplicate health probe %s", ctrlcommon.HealthProbesAnnotationKey, pool.Name, probe.Name)
+		}
+		names[probe.Name] = true
+	}
+	return probes, nil
+}
+
+// runHealthProbes runs all the probes once, returning the failures.
+func runHealthProbes(ctx context.Context, probes []HealthProbe, cmdRunner CommandRunner) error {
+	var errs []error
+	for _, probe := range probes {
+		if err := probe.run(ctx, cmdRunner); err != nil {
+			errs = append(errs, fmt.Errorf("health probe %s failed: %w", probe.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkNodeHealth runs the health probes of the node's pool until they all
+// pass, giving up after healthProbesTimeout. It is called when an update is
+// being completed, before the node is marked done, so that a node whose probes
+// fail goes degraded and holds up the rollout of its pool. Nodes which are
+// already done are not probed again when the daemon restarts.
+func (dn *Daemon) checkNodeHealth() error {
+	if dn.mcpLister == nil || dn.node == nil {
+		return nil
+	}
+	pool, err := helpers.GetPrimaryPoolForNode(dn.mcpLister, dn.node)
+	if err != nil {
+		return fmt.Errorf("could not get pool to determine health probes: %w", err)
+	}
+	if pool == nil {
+		return nil
+	}
+	probes, err := getHealthProbes(pool)
+	if err != nil || len(probes) == 0 {
+		return err
+	}
+
+	klog.Infof("Running %d health probes", len(probes))
+	var probeErr error
+	if err := wait.PollUntilContextTimeout(context.TODO(), healthProbesPollingInterval, healthProbesTimeout, true, func(ctx context.Context) (bool, error) {
+		probeErr = runHealthProbes(ctx, probes, dn.cmdRunner)
+		if probeErr != nil {
+			klog.Warningf("Health probes failing, retrying: %v", probeErr)
+		}
+		return probeErr == nil, nil
+	}); err != nil {
+		if probeErr == nil {
+			probeErr = err
+		}
+		if dn.nodeWriter != nil {
+			dn.nodeWriter.Eventf(corev1.EventTypeWarning, "HealthProbesFailed", probeErr.Error())
+		}
+		return fmt.Errorf("node health probes did not pass within %v: %w", healthProbesTimeout, probeErr)
+	}
+
+	logSystem("All %d health probes passed", len(probes))
+	if dn.nodeWriter != nil {
+		dn.nodeWriter.Eventf(corev1.EventTypeNormal, "HealthProbesPassed", "All %d health probes passed", len(probes))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetHealthProbes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		value     *string
+		expected  []HealthProbe
+		expectErr bool
+	}{
+		{
+			name: "no probes",
+		},
+		{
+			name:  "valid probes",
+			value: ptr.To(`[{"name": "crio", "type": "SystemdUnit", "unit": "crio.service"}, {"name": "kubelet", "type": "HTTP", "url": "http://localhost:10248/healthz", "timeoutSeconds": 5}]`),
+			expected: []HealthProbe{
+				{Name: "crio", Type: HealthProbeSystemdUnit, Unit: "crio.service"},
+				{Name: "kubelet", Type: HealthProbeHTTP, URL: "http://localhost:10248/healthz", TimeoutSeconds: 5},
+			},
+		},
+		{
+			name:      "invalid JSON",
+			value:     ptr.To(`{`),
+			expectErr: true,
+		},
+		{
+			name:      "invalid type",
+			value:     ptr.To(`[{"name": "ping", "type": "ICMP"}]`),
+			expectErr: true,
+		},
+		{
+			name:      "missing field",
+			value:     ptr.To(`[{"name": "sshd", "type": "TCP"}]`),
+			expectErr: true,
+		},
+		{
+			name:      "duplicate names",
+			value:     ptr.To(`[{"name": "a", "type": "Exec", "command": ["true"]}, {"name": "a", "type": "Exec", "command": ["true"]}]`),
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+			if test.value != nil {
+				pool.Annotations = map[string]string{ctrlcommon.HealthProbesAnnotationKey: *test.value}
+			}
+
+			probes, err := getHealthProbes(pool)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, probes)
+		})
+	}
+}
+
+func TestRunHealthProbes(t *testing.T) {
+	t.Parallel()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	cmdRunner := &MockCommandRunner{
+		outputs: map[string][]byte{
+			"systemctl is-active crio.service":    []byte("active\n"),
+			"systemctl is-active kubelet.service": []byte("failed\n"),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		probe     HealthProbe
+		expectErr bool
+	}{
+		{
+			name:  "HTTP success",
+			probe: HealthProbe{Name: "http", Type: HealthProbeHTTP, URL: healthy.URL},
+		},
+		{
+			name:      "HTTP failure",
+			probe:     HealthProbe{Name: "http", Type: HealthProbeHTTP, URL: unhealthy.URL},
+			expectErr: true,
+		},
+		{
+			name:  "TCP success",
+			probe: HealthProbe{Name: "tcp", Type: HealthProbeTCP, Address: listener.Addr().String()},
+		},
+		{
+			name:      "TCP failure",
+			probe:     HealthProbe{Name: "tcp", Type: HealthProbeTCP, Address: closedAddress},
+			expectErr: true,
+		},
+		{
+			name:  "Exec success",
+			probe: HealthProbe{Name: "exec", Type: HealthProbeExec, Command: []string{"true"}},
+		},
+		{
+			name:      "Exec failure",
+			probe:     HealthProbe{Name: "exec", Type: HealthProbeExec, Command: []string{"false"}},
+			expectErr: true,
+		},
+		{
+			name:  "SystemdUnit active",
+			probe: HealthProbe{Name: "crio", Type: HealthProbeSystemdUnit, Unit: "crio.service"},
+		},
+		{
+			name:      "SystemdUnit failed",
+			probe:     HealthProbe{Name: "kubelet", Type: HealthProbeSystemdUnit, Unit: "kubelet.service"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		// Not parallel, as the servers are closed once the test returns.
+		t.Run(test.name, func(t *testing.T) {
+			err := runHealthProbes(context.TODO(), []HealthProbe{test.probe}, cmdRunner)
+			if test.expectErr {
+				assert.ErrorContains(t, err, "health probe "+test.probe.Name+" failed")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
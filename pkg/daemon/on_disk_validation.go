@@ -25,12 +25,19 @@ func validateOnDiskState(currentConfig *mcfgv1.MachineConfig, systemdPath string
 		return fmt.Errorf("failed to parse Ignition for validation: %w", err)
 	}
 
+	// Provenance is only used to make mismatches easier to act on, so a bad
+	// annotation should not fail the validation.
+	provenance, err := ctrlcommon.GetMachineConfigProvenanceFromAnnotation(currentConfig)
+	if err != nil {
+		klog.Warningf("Ignoring MachineConfig provenance: %v", err)
+	}
+
 	switch typedConfig := ignconfigi.(type) {
 	case ign3types.Config:
-		if err := checkV3Files(ignconfigi.(ign3types.Config).Storage.Files); err != nil {
+		if err := checkV3Files(ignconfigi.(ign3types.Config).Storage.Files, provenance); err != nil {
 			return &fileConfigDriftErr{err}
 		}
-		if err := checkV3Units(ignconfigi.(ign3types.Config).Systemd.Units, systemdPath); err != nil {
+		if err := checkV3Units(ignconfigi.(ign3types.Config).Systemd.Units, systemdPath, provenance); err != nil {
 			return &unitConfigDriftErr{err}
 		}
 		return nil
@@ -73,10 +80,10 @@ func checkV3Dropin(systemdPath string, unit ign3types.Unit, dropin ign3types.Dro
 
 // checkV3Units validates the contents of an individual unit in the
 // target config and returns nil if they match.
-func checkV3Unit(unit ign3types.Unit, systemdPath string) error {
+func checkV3Unit(unit ign3types.Unit, systemdPath string, provenance *ctrlcommon.MachineConfigProvenance) error {
 	for _, dropin := range unit.Dropins {
 		if err := checkV3Dropin(systemdPath, unit, dropin); err != nil {
-			return err
+			return withProvenance(err, provenance.UnitSource(unit.Name+".d/"+dropin.Name))
 		}
 	}
 
@@ -93,7 +100,7 @@ func checkV3Unit(unit ign3types.Unit, systemdPath string) error {
 		}
 
 		if link != pathDevNull {
-			return fmt.Errorf("state validation: invalid unit masked setting. path: %q; expected: %v; received: %v", path, pathDevNull, link)
+			return withProvenance(fmt.Errorf("state validation: invalid unit masked setting. path: %q; expected: %v; received: %v", path, pathDevNull, link), provenance.UnitSource(unit.Name))
 		}
 
 		// Return early if the unit is masked.
@@ -103,17 +110,17 @@ func checkV3Unit(unit ign3types.Unit, systemdPath string) error {
 
 	err := checkFileContentsAndMode(path, []byte(*unit.Contents), defaultFilePermissions)
 	if err != nil {
-		return err
+		return withProvenance(err, provenance.UnitSource(unit.Name))
 	}
 
-	return checkUnitEnabled(unit.Name, unit.Enabled)
+	return withProvenance(checkUnitEnabled(unit.Name, unit.Enabled), provenance.UnitSource(unit.Name))
 }
 
 // checkV3Units validates the contents of all the units in the
 // target config and returns nil if they match.
-func checkV3Units(units []ign3types.Unit, systemdPath string) error {
+func checkV3Units(units []ign3types.Unit, systemdPath string, provenance *ctrlcommon.MachineConfigProvenance) error {
 	for _, unit := range units {
-		if err := checkV3Unit(unit, systemdPath); err != nil {
+		if err := checkV3Unit(unit, systemdPath, provenance); err != nil {
 			return err
 		}
 	}
@@ -182,7 +189,7 @@ func getFilesToIgnore() sets.Set[string] {
 // checkV3Files validates the contents of all the files in the target config.
 // V3 files should not have any duplication anymore, so there is no need to
 // check for overwrites.
-func checkV3Files(files []ign3types.File) error {
+func checkV3Files(files []ign3types.File, provenance *ctrlcommon.MachineConfigProvenance) error {
 	filesToIgnore := getFilesToIgnore()
 
 	for _, f := range files {
@@ -202,12 +209,21 @@ func checkV3Files(files []ign3types.File) error {
 			return fmt.Errorf("couldn't decode file %q: %w", f.Path, err)
 		}
 		if err := checkFileContentsAndMode(f.Path, contents, mode); err != nil {
-			return err
+			return withProvenance(err, provenance.FileSource(f.Path))
 		}
 	}
 	return nil
 }
 
+// withProvenance adds the MachineConfig an item comes from to an error about
+// the item, if it is known.
+func withProvenance(err error, source string) error {
+	if err == nil || source == "" {
+		return err
+	}
+	return fmt.Errorf("%w (from MachineConfig %s)", err, source)
+}
+
 // checkV2Files validates the contents of all the files in the target config.
 func checkV2Files(files []ign2types.File) error {
 	checkedFiles := make(map[string]bool)
@@ -241,23 +257,29 @@ func checkUnitEnabled(name string, expectedEnabled *bool) error {
 	if expectedEnabled == nil {
 		return nil
 	}
-	cmdRunner := &CommandRunnerOS{}
+	out := getUnitEnabledState(&CommandRunnerOS{}, name)
+	if !unitEnabledStateMatches(*expectedEnabled, out) {
+		return fmt.Errorf("unit %q expected enabled=%t, but systemd reports %q", name, *expectedEnabled, out)
+	}
+	return nil
+}
+
+// getUnitEnabledState returns the state systemd reports for a unit, such as
+// "enabled" or "masked".
+func getUnitEnabledState(cmdRunner CommandRunner, name string) string {
 	outBytes, _ := cmdRunner.RunGetOut("systemctl", "is-enabled", name)
-	out := strings.TrimSpace(string(outBytes))
+	return strings.TrimSpace(string(outBytes))
+}
 
-	switch {
-	case *expectedEnabled:
+// unitEnabledStateMatches determines whether a systemd unit state is
+// compatible with the unit being enabled or not.
+func unitEnabledStateMatches(expectedEnabled bool, state string) bool {
+	if expectedEnabled {
 		// If expected to be enabled, reject known "not enabled" states
-		if out == "disabled" || out == "masked" || out == "masked-runtime" || out == "not-found" {
-			return fmt.Errorf("unit %q expected enabled=true, but systemd reports %q", name, out)
-		}
-	case !*expectedEnabled:
-		// If expected to be disabled, reject any of the enabled-like states
-		if out == "enabled" || out == "enabled-runtime" {
-			return fmt.Errorf("unit %q expected enabled=false, but systemd reports %q", name, out)
-		}
+		return state != "disabled" && state != "masked" && state != "masked-runtime" && state != "not-found"
 	}
-	return nil
+	// If expected to be disabled, reject any of the enabled-like states
+	return state != "enabled" && state != "enabled-runtime"
 }
 
 // checkFileContentsAndMode reads the file from the filepath and compares its
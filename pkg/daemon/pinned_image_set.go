@@ -30,6 +30,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformersv1 "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
 	corev1lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -93,6 +94,7 @@ type PinnedImageSetManager struct {
 	mcpSynced cache.InformerSynced
 
 	mcfgClient mcfgclientset.Interface
+	kubeClient clientset.Interface
 
 	prefetchCh chan prefetch
 
@@ -113,6 +115,12 @@ type PinnedImageSetManager struct {
 
 	// cache for reusable image information
 	cache *imageCache
+	// images pulled by the manager, removed once unpinned
+	pulledImages *pulledImageStore
+	// prefetch progress of the images, published on the MachineConfigNode
+	imageProgress *imageProgressTracker
+	// verifies images against the signature policies before they are pinned
+	signatureVerifier *imageSignatureVerifier
 
 	syncHandler              func(string) error
 	enqueueMachineConfigPool func(*mcfgv1.MachineConfigPool)
@@ -131,6 +139,7 @@ type PinnedImageSetManager struct {
 func NewPinnedImageSetManager(
 	nodeName string,
 	criClient *cri.Client,
+	kubeClient clientset.Interface,
 	mcfgClient mcfgclientset.Interface,
 	imageSetInformer mcfginformersv1.PinnedImageSetInformer,
 	nodeInformer coreinformersv1.NodeInformer,
@@ -145,6 +154,7 @@ func NewPinnedImageSetManager(
 	p := &PinnedImageSetManager{
 		nodeName:                 nodeName,
 		mcfgClient:               mcfgClient,
+		kubeClient:               kubeClient,
 		runtimeEndpoint:          runtimeEndpoint,
 		authFilePath:             authFilePath,
 		registryCfgPath:          registryCfgPath,
@@ -186,7 +196,7 @@ func NewPinnedImageSetManager(
 
 	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    p.handleNodeEvent,
-		UpdateFunc: func(_, newObj interface{}) { p.handleNodeEvent(newObj) },
+		UpdateFunc: p.updateNode,
 	})
 
 	imageSetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -196,6 +206,9 @@ func NewPinnedImageSetManager(
 	})
 
 	p.cache = newImageCache(256)
+	p.pulledImages = newPulledImageStore(constants.PinnedImagesPulledFilePath)
+	p.imageProgress = newImageProgressTracker()
+	p.signatureVerifier = newImageSignatureVerifier(constants.ContainerRegistryPolicyPath, constants.CrioPoliciesDir, constants.SigstoreRegistriesConfigDir, authFilePath, registryCfgPath)
 
 	return p
 }
@@ -222,6 +235,14 @@ func (p *PinnedImageSetManager) sync(key string) error {
 		return err
 	}
 
+	limiter, err := newPrefetchLimiter(primaryPool)
+	if err != nil {
+		if err := p.updateStatusError([]*mcfgv1.MachineConfigPool{primaryPool}, err); err != nil {
+			klog.Errorf("failed to update status: %v", err)
+		}
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), p.prefetchTimeout)
 	// cancel any currently running tasks in the worker pool
 	p.resetWorkload(cancel)
@@ -229,8 +250,28 @@ func (p *PinnedImageSetManager) sync(key string) error {
 		klog.Errorf("failed to update status: %v", err)
 	}
 
-	if err := p.syncMachineConfigPools(ctx, pools); err != nil {
+	// only nodes granted a slot by the controller pull images, to limit how
+	// many nodes prefetch at once
+	pull, err := p.hasImagesToPull(ctx, pools)
+	if err != nil {
+		return err
+	}
+	if pull {
+		granted, err := p.requestPrefetchSlot(node)
+		if err != nil {
+			return err
+		}
+		if !granted {
+			return errWaitingForPrefetchSlot
+		}
+	}
+
+	// publish the progress of the images while they are pulled
+	p.imageProgress.reset()
+	reportProgress := p.newPinnedImageSetProgressReporter([]*mcfgv1.MachineConfigPool{primaryPool})
+	if err := p.syncMachineConfigPools(ctx, pools, limiter, reportProgress); err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
+			// keep the prefetch slot to carry on after the requeue
 			ctxErr := fmt.Errorf("%w: %v", errRequeueAfterTimeout, p.prefetchTimeout)
 			if err := p.updateStatusError([]*mcfgv1.MachineConfigPool{primaryPool}, ctxErr); err != nil {
 				klog.Errorf("failed to update status: %v", err)
@@ -243,31 +284,54 @@ func (p *PinnedImageSetManager) sync(key string) error {
 		if err := p.updateStatusError([]*mcfgv1.MachineConfigPool{primaryPool}, err); err != nil {
 			klog.Errorf("failed to update status: %v", err)
 		}
+		if err := p.releasePrefetchSlot(); err != nil {
+			klog.Errorf("failed to release prefetch slot: %v", err)
+		}
 		return err
 	}
 
-	return p.updateStatusProgressingComplete([]*mcfgv1.MachineConfigPool{primaryPool}, "All pinned image sets complete")
+	if err := p.releasePrefetchSlot(); err != nil {
+		klog.Errorf("failed to release prefetch slot: %v", err)
+	}
+
+	message := "All pinned image sets complete"
+	if removed, reclaimed := p.garbageCollectUnpinnedImages(ctx, pools); len(removed) > 0 {
+		message = fmt.Sprintf("%s, reclaimed %d bytes from %d unpinned images", message, reclaimed, len(removed))
+	}
+	return p.updateStatusProgressingComplete([]*mcfgv1.MachineConfigPool{primaryPool}, message)
 }
 
-func (p *PinnedImageSetManager) syncMachineConfigPools(ctx context.Context, pools []*mcfgv1.MachineConfigPool) error {
-	images := make([]mcfgv1.PinnedImageRef, 0, 100)
+// garbageCollectUnpinnedImages removes the images pulled for the PinnedImageSets
+// of the pools which are no longer pinned, and reports what it reclaimed on the
+// MachineConfigNode. Failing to remove images does not fail the sync, the next
+// one tries again.
+func (p *PinnedImageSetManager) garbageCollectUnpinnedImages(ctx context.Context, pools []*mcfgv1.MachineConfigPool) ([]string, int64) {
+	images, err := p.getPinnedImagesForPools(pools)
+	if err != nil {
+		klog.Errorf("failed to get pinned images for garbage collection: %v", err)
+		return nil, 0
+	}
+	removed, reclaimed, err := p.garbageCollectImages(ctx, uniqueSortedImageNames(images))
+	if err != nil {
+		klog.Errorf("failed to garbage collect unpinned images: %v", err)
+	}
+	if err := upgrademonitor.UpdateMachineConfigNodePinnedImageGC(p.fgHandler, p.mcfgClient, p.nodeName, removed, reclaimed); err != nil {
+		klog.Errorf("failed to report unpinned image garbage collection: %v", err)
+	}
+	return removed, reclaimed
+}
+
+func (p *PinnedImageSetManager) syncMachineConfigPools(ctx context.Context, pools []*mcfgv1.MachineConfigPool, limiter *prefetchLimiter, reportProgress func()) error {
 	for _, pool := range pools {
-		if err := p.syncMachineConfigPool(ctx, pool); err != nil {
+		if err := p.syncMachineConfigPool(ctx, pool, limiter, reportProgress); err != nil {
 			return err
 		}
+	}
 
-		// collect all unique images from all pools
-		for _, image := range pool.Spec.PinnedImageSets {
-			imageSet, err := p.imageSetLister.Get(image.Name)
-			if err != nil {
-				if apierrors.IsNotFound(err) {
-					klog.Warningf("PinnedImageSet %q not found", image.Name)
-					continue
-				}
-				return fmt.Errorf("failed to get PinnedImageSet %q: %w", image.Name, err)
-			}
-			images = append(images, imageSet.Spec.PinnedImages...)
-		}
+	// collect all unique images from all pools
+	images, err := p.getPinnedImagesForPools(pools)
+	if err != nil {
+		return err
 	}
 
 	// verify all images available if not clear the cache and requeue
@@ -293,7 +357,50 @@ func (p *PinnedImageSetManager) syncMachineConfigPools(ctx context.Context, pool
 	return nil
 }
 
-func (p *PinnedImageSetManager) syncMachineConfigPool(ctx context.Context, pool *mcfgv1.MachineConfigPool) error {
+// getPinnedImagesForPools returns the images of the PinnedImageSets of the pools.
+func (p *PinnedImageSetManager) getPinnedImagesForPools(pools []*mcfgv1.MachineConfigPool) ([]mcfgv1.PinnedImageRef, error) {
+	images := make([]mcfgv1.PinnedImageRef, 0, 100)
+	for _, pool := range pools {
+		for _, image := range pool.Spec.PinnedImageSets {
+			imageSet, err := p.imageSetLister.Get(image.Name)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					klog.Warningf("PinnedImageSet %q not found", image.Name)
+					continue
+				}
+				return nil, fmt.Errorf("failed to get PinnedImageSet %q: %w", image.Name, err)
+			}
+			images = append(images, imageSet.Spec.PinnedImages...)
+		}
+	}
+	return images, nil
+}
+
+// hasImagesToPull returns true if any image of the PinnedImageSets of the
+// pools is missing from the node.
+func (p *PinnedImageSetManager) hasImagesToPull(ctx context.Context, pools []*mcfgv1.MachineConfigPool) (bool, error) {
+	images, err := p.getPinnedImagesForPools(pools)
+	if err != nil {
+		return false, err
+	}
+	for _, image := range uniqueSortedImageNames(images) {
+		if value, found := p.cache.Get(image); found {
+			if imageInfo, ok := value.(imageInfo); ok && imageInfo.Pulled {
+				continue
+			}
+		}
+		exists, err := p.criClient.ImageStatus(ctx, image)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *PinnedImageSetManager) syncMachineConfigPool(ctx context.Context, pool *mcfgv1.MachineConfigPool, limiter *prefetchLimiter, reportProgress func()) error {
 	if pool.Spec.PinnedImageSets == nil {
 		return nil
 	}
@@ -314,7 +421,7 @@ func (p *PinnedImageSetManager) syncMachineConfigPool(ctx context.Context, pool
 	// images are cached with size information
 	p.cache.ClearDigests()
 
-	return p.prefetchImageSets(ctx, imageSets...)
+	return p.prefetchImageSets(ctx, limiter, reportProgress, imageSets...)
 }
 
 func (p *PinnedImageSetManager) checkNodeAllocatableStorage(ctx context.Context, imageSet *mcfgv1.PinnedImageSet) error {
@@ -342,7 +449,9 @@ func (p *PinnedImageSetManager) checkNodeAllocatableStorage(ctx context.Context,
 }
 
 // prefetchImageSets schedules the prefetching of images for the given image sets and waits for completion.
-func (p *PinnedImageSetManager) prefetchImageSets(ctx context.Context, imageSets ...*mcfgv1.PinnedImageSet) error {
+// The pulls are limited by the given limiter, which may be nil. While waiting, reportProgress, if not nil, is
+// called every pinnedImageSetProgressInterval.
+func (p *PinnedImageSetManager) prefetchImageSets(ctx context.Context, limiter *prefetchLimiter, reportProgress func(), imageSets ...*mcfgv1.PinnedImageSet) error {
 	registryAuth, err := newRegistryAuth(p.authFilePath, p.registryCfgPath)
 	if err != nil {
 		return err
@@ -364,12 +473,12 @@ func (p *PinnedImageSetManager) prefetchImageSets(ctx context.Context, imageSets
 				continue
 			}
 		}
-		if err := p.scheduleWork(ctx, p.prefetchCh, registryAuth, imageSet.Spec.PinnedImages, monitor); err != nil {
+		if err := p.scheduleWork(ctx, p.prefetchCh, registryAuth, imageSet.Spec.PinnedImages, limiter, monitor); err != nil {
 			return err
 		}
 	}
 
-	if err := monitor.WaitForDone(); err != nil {
+	if err := monitor.WaitForDoneReporting(pinnedImageSetProgressInterval, reportProgress); err != nil {
 		return err
 	}
 
@@ -384,7 +493,7 @@ func (p *PinnedImageSetManager) prefetchImageSets(ctx context.Context, imageSets
 }
 
 // scheduleWork schedules the prefetch work for the images and collects the first error encountered.
-func (p *PinnedImageSetManager) scheduleWork(ctx context.Context, prefetchCh chan prefetch, registryAuth *registryAuth, prefetchImages []mcfgv1.PinnedImageRef, monitor *prefetchMonitor) error {
+func (p *PinnedImageSetManager) scheduleWork(ctx context.Context, prefetchCh chan prefetch, registryAuth *registryAuth, prefetchImages []mcfgv1.PinnedImageRef, limiter *prefetchLimiter, monitor *prefetchMonitor) error {
 	totalImages := len(prefetchImages)
 	updateIncrement := totalImages / 4
 	if updateIncrement == 0 {
@@ -403,13 +512,16 @@ func (p *PinnedImageSetManager) scheduleWork(ctx context.Context, prefetchCh cha
 
 			// check cache if image is pulled
 			// this is an optimization to speedup prefetching after requeue
+			var size int64
 			if value, found := p.cache.Get(image); found {
 				imageInfo, ok := value.(imageInfo)
 				if ok {
 					if imageInfo.Pulled {
+						p.imageProgress.set(image, upgrademonitor.PinnedImagePulled, "")
 						scheduledImages++
 						continue
 					}
+					size = imageInfo.Size
 				}
 			}
 
@@ -417,10 +529,14 @@ func (p *PinnedImageSetManager) scheduleWork(ctx context.Context, prefetchCh cha
 			if err != nil {
 				return fmt.Errorf("failed to get auth config for image %s: %w", image, err)
 			}
+			p.imageProgress.setSize(image, size)
+			p.imageProgress.set(image, upgrademonitor.PinnedImagePending, "")
 			monitor.Add(1)
 			prefetchCh <- prefetch{
 				image:   image,
+				size:    size,
 				auth:    authConfig,
+				limiter: limiter,
 				monitor: monitor,
 			}
 
@@ -540,10 +656,11 @@ func (p *PinnedImageSetManager) updateStatusProgressing(pools []*mcfgv1.MachineC
 	}
 
 	isComplete := false
-	applyCfg, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, nil)
+	applyCfg, progress, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get image set apply configs: %w", err)
 	}
+	p.publishPinnedImageSetProgress(progress)
 
 	// Get MCP associated with node
 	pool, err := helpers.GetPrimaryPoolNameForMCN(p.mcpLister, node)
@@ -575,10 +692,11 @@ func (p *PinnedImageSetManager) updateStatusProgressingComplete(pools []*mcfgv1.
 	}
 
 	isComplete := true
-	applyCfg, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, nil)
+	applyCfg, progress, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get image set apply configs: %w", err)
 	}
+	p.publishPinnedImageSetProgress(progress)
 
 	// Get MCP associated with node
 	pool, err := helpers.GetPrimaryPoolNameForMCN(p.mcpLister, node)
@@ -630,10 +748,11 @@ func (p *PinnedImageSetManager) updateStatusError(pools []*mcfgv1.MachineConfigP
 	}
 
 	isComplete := false
-	applyCfg, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, statusErr)
+	applyCfg, progress, err := p.getPinnedImageSetApplyConfigsForPools(pools, isComplete, statusErr)
 	if err != nil {
 		return fmt.Errorf("failed to get image set apply configs: %w", err)
 	}
+	p.publishPinnedImageSetProgress(progress)
 
 	// Get MCP associated with node
 	pool, err := helpers.GetPrimaryPoolNameForMCN(p.mcpLister, node)
@@ -641,11 +760,18 @@ func (p *PinnedImageSetManager) updateStatusError(pools []*mcfgv1.MachineConfigP
 		return err
 	}
 
+	reason := "PrefetchFailed"
+	message := "One or more PinnedImageSet is experiencing an error. See PinnedImageSet list for more details."
+	if errors.Is(statusErr, errImageSignatureRejected) {
+		reason = "SignatureVerificationFailed"
+		message = "One or more pinned image was refused by the image signature policies. See PinnedImageSet list for more details."
+	}
+
 	return upgrademonitor.UpdateMachineConfigNodeStatus(
 		&upgrademonitor.Condition{
 			State:   mcfgv1.MachineConfigNodePinnedImageSetsDegraded,
-			Reason:  "PrefetchFailed",
-			Message: "One or more PinnedImageSet is experiencing an error. See PinnedImageSet list for more details.",
+			Reason:  reason,
+			Message: message,
 		},
 		nil,
 		metav1.ConditionTrue,
@@ -658,9 +784,11 @@ func (p *PinnedImageSetManager) updateStatusError(pools []*mcfgv1.MachineConfigP
 	)
 }
 
-// getPinnedImageSetApplyConfigsForPools returns a list of MachineConfigNodeStatusPinnedImageSetApplyConfiguration for the given pools.
-func (p *PinnedImageSetManager) getPinnedImageSetApplyConfigsForPools(pools []*mcfgv1.MachineConfigPool, isCompleted bool, statusErr error) ([]*machineconfigurationv1.MachineConfigNodeStatusPinnedImageSetApplyConfiguration, error) {
+// getPinnedImageSetApplyConfigsForPools returns a list of MachineConfigNodeStatusPinnedImageSetApplyConfiguration for the given pools
+// and the prefetch progress of the images of their image sets.
+func (p *PinnedImageSetManager) getPinnedImageSetApplyConfigsForPools(pools []*mcfgv1.MachineConfigPool, isCompleted bool, statusErr error) ([]*machineconfigurationv1.MachineConfigNodeStatusPinnedImageSetApplyConfiguration, []upgrademonitor.PinnedImageSetProgress, error) {
 	applyConfigs := make([]*machineconfigurationv1.MachineConfigNodeStatusPinnedImageSetApplyConfiguration, 0)
+	progress := make([]upgrademonitor.PinnedImageSetProgress, 0)
 	for _, pool := range pools {
 		for _, imageSets := range pool.Spec.PinnedImageSets {
 			imageSet, err := p.imageSetLister.Get(imageSets.Name)
@@ -668,18 +796,19 @@ func (p *PinnedImageSetManager) getPinnedImageSetApplyConfigsForPools(pools []*m
 				if apierrors.IsNotFound(err) {
 					continue
 				}
-				return nil, err
+				return nil, nil, err
 			}
 
-			config := p.createApplyConfigForImageSet(imageSet, isCompleted, statusErr)
+			config, imageSetProgress := p.createApplyConfigForImageSet(imageSet, isCompleted, statusErr)
 			applyConfigs = append(applyConfigs, config)
+			progress = append(progress, imageSetProgress)
 		}
 	}
-	return applyConfigs, nil
+	return applyConfigs, progress, nil
 }
 
 //nolint:gosec
-func (p *PinnedImageSetManager) createApplyConfigForImageSet(imageSet *mcfgv1.PinnedImageSet, isCompleted bool, statusErr error) *machineconfigurationv1.MachineConfigNodeStatusPinnedImageSetApplyConfiguration {
+func (p *PinnedImageSetManager) createApplyConfigForImageSet(imageSet *mcfgv1.PinnedImageSet, isCompleted bool, statusErr error) (*machineconfigurationv1.MachineConfigNodeStatusPinnedImageSetApplyConfiguration, upgrademonitor.PinnedImageSetProgress) {
 	imageSetConfig := machineconfigurationv1.MachineConfigNodeStatusPinnedImageSet().
 		WithName(imageSet.Name).
 		WithDesiredGeneration(int32(imageSet.GetGeneration()))
@@ -689,19 +818,24 @@ func (p *PinnedImageSetManager) createApplyConfigForImageSet(imageSet *mcfgv1.Pi
 		if imageSet.Generation == cachedImageSet.Generation {
 			// return cached value
 			imageSetConfig.CurrentGeneration = ptr.To(int32(imageSet.GetGeneration()))
-			return imageSetConfig
+			return imageSetConfig, p.getPinnedImageSetProgress(imageSet, true)
 		}
 	}
 
+	progress := p.getPinnedImageSetProgress(imageSet, statusErr == nil && isCompleted)
 	if statusErr != nil {
 		imageSetConfig.LastFailedGeneration = ptr.To(int32(imageSet.GetGeneration()))
-		imageSetConfig.LastFailedGenerationError = ptr.To(statusErr.Error())
+		lastErr := statusErr.Error()
+		if failed := describeFailedImages(progress); failed != "" {
+			lastErr = fmt.Sprintf("%s; %s", lastErr, failed)
+		}
+		imageSetConfig.LastFailedGenerationError = ptr.To(lastErr)
 	} else if isCompleted {
 		// only set the current generation if prefetch is complete
 		imageSetConfig.CurrentGeneration = ptr.To(int32(imageSet.GetGeneration()))
 	}
 
-	return imageSetConfig
+	return imageSetConfig, progress
 }
 
 func checkNodeReady(node *corev1.Node) error {
@@ -763,9 +897,13 @@ func (p *PinnedImageSetManager) prefetchWorker(ctx context.Context) {
 			task.monitor.Done()
 			continue
 		}
-		if err := ensurePullImage(ctx, p.criClient, p.backoff, task.image, task.auth); err != nil {
+		if err := p.pullImage(ctx, task); err != nil {
 			task.monitor.Error(err)
 			klog.Warningf("failed to prefetch image %q: %v", task.image, err)
+			task.monitor.Done()
+			// failed images are not cached as pulled, so they are tried
+			// again, and verified again, on the next sync
+			continue
 		}
 		task.monitor.Done()
 
@@ -791,6 +929,44 @@ func (p *PinnedImageSetManager) prefetchWorker(ctx context.Context) {
 	}
 }
 
+// pullImage verifies the signatures of the image of a prefetch task and pulls
+// it within the limits of the task.
+func (p *PinnedImageSetManager) pullImage(ctx context.Context, task prefetch) error {
+	if err := p.signatureVerifier.verify(ctx, task.image); err != nil {
+		p.imageProgress.set(task.image, upgrademonitor.PinnedImageFailed, err.Error())
+		return err
+	}
+	if task.size == 0 && task.limiter.limitsBytes() {
+		// Images not sized by the storage check yet would otherwise be
+		// admitted without using any of the bytes allowed.
+		size, err := p.getImageSize(ctx, task.image, p.authFilePath)
+		if err != nil {
+			p.imageProgress.set(task.image, upgrademonitor.PinnedImageFailed, err.Error())
+			return err
+		}
+		task.size = size
+		p.cache.Add(strings.TrimSpace(task.image), imageInfo{Name: task.image, Size: size})
+		p.imageProgress.setSize(task.image, size)
+	}
+	if err := task.limiter.acquire(ctx, task.size); err != nil {
+		return err
+	}
+	defer task.limiter.release()
+	p.imageProgress.set(task.image, upgrademonitor.PinnedImagePulling, "")
+	pulled, err := ensurePullImage(ctx, p.criClient, p.backoff, task.image, task.auth)
+	if err != nil {
+		p.imageProgress.set(task.image, upgrademonitor.PinnedImageFailed, err.Error())
+		return err
+	}
+	p.imageProgress.set(task.image, upgrademonitor.PinnedImagePulled, "")
+	if pulled {
+		if err := p.pulledImages.Add(task.image); err != nil {
+			klog.Warningf("failed to record pulled image %q: %v", task.image, err)
+		}
+	}
+	return nil
+}
+
 func (p *PinnedImageSetManager) Run(workers int, stopCh <-chan struct{}) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer func() {
@@ -982,6 +1158,26 @@ func (p *PinnedImageSetManager) handleNodeEvent(newObj interface{}) {
 	p.setBootstrapped()
 }
 
+func (p *PinnedImageSetManager) updateNode(oldObj, newObj interface{}) {
+	p.handleNodeEvent(newObj)
+
+	oldNode := oldObj.(*corev1.Node)
+	newNode := newObj.(*corev1.Node)
+	if newNode.Name != p.nodeName || isPrefetchGranted(oldNode) || !isPrefetchGranted(newNode) {
+		return
+	}
+
+	// the controller granted the prefetch slot the node is waiting for
+	pools, _, err := helpers.GetPoolsForNode(p.mcpLister, newNode)
+	if err != nil {
+		klog.Errorf("error finding pools for node %s: %v", newNode.Name, err)
+		return
+	}
+	for _, pool := range pools {
+		p.enqueueMachineConfigPool(pool)
+	}
+}
+
 func (p *PinnedImageSetManager) isBootstrapped() bool {
 	return p.bootstrapped
 }
@@ -1117,6 +1313,13 @@ func (p *PinnedImageSetManager) handleErr(err error, key string) {
 		return
 	}
 
+	if errors.Is(err, errWaitingForPrefetchSlot) {
+		klog.V(4).Infof("MachineConfigPool %v: %v", key, err)
+		p.queue.Forget(key)
+		p.queue.AddAfter(key, prefetchSlotRetryInterval)
+		return
+	}
+
 	if p.queue.NumRequeues(key) < maxRetriesController {
 		klog.V(4).Infof("Requeue MachineConfigPool %v: %v", key, err)
 		p.queue.AddRateLimited(key)
@@ -1165,15 +1368,15 @@ func (p *PinnedImageSetManager) getImageSize(ctx context.Context, imageName, aut
 }
 
 // ensurePullImage first checks if the image exists locally and then will attempt to pull
-// the image from the container runtime with a retry/backoff.
-func ensurePullImage(ctx context.Context, client *cri.Client, backoff wait.Backoff, image string, authConfig *runtimeapi.AuthConfig) error {
+// the image from the container runtime with a retry/backoff. It returns true if it pulled the image.
+func ensurePullImage(ctx context.Context, client *cri.Client, backoff wait.Backoff, image string, authConfig *runtimeapi.AuthConfig) (bool, error) {
 	exists, err := client.ImageStatus(ctx, image)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if exists {
 		klog.V(4).Infof("image %q already exists", image)
-		return nil
+		return false, nil
 	}
 
 	var lastErr error
@@ -1193,12 +1396,12 @@ func ensurePullImage(ctx context.Context, client *cri.Client, backoff wait.Backo
 	})
 	// this is only an error if ctx has error or backoff limits are exceeded
 	if err != nil {
-		return fmt.Errorf("%w %q (%d tries): %w: %w", errFailedToPullImage, image, tries, err, lastErr)
+		return false, fmt.Errorf("%w %q (%d tries): %w: %w", errFailedToPullImage, image, tries, err, lastErr)
 	}
 
 	// successful pull
 	klog.V(4).Infof("image %q pulled", image)
-	return nil
+	return true, nil
 }
 
 func isErrNoSpace(err error) bool {
@@ -1540,8 +1743,11 @@ func parseSupportedNames(name string) []string {
 
 // prefetch represents a task to prefetch an image.
 type prefetch struct {
-	image   string
+	image string
+	// size of the image to pull, if known
+	size    int64
 	auth    *runtimeapi.AuthConfig
+	limiter *prefetchLimiter
 	monitor *prefetchMonitor
 }
 
@@ -1588,3 +1794,26 @@ func (m *prefetchMonitor) WaitForDone() error {
 	m.wg.Wait()
 	return m.err
 }
+
+// WaitForDoneReporting is WaitForDone, calling report every interval until the
+// prefetch operations complete. A nil report is not called.
+func (m *prefetchMonitor) WaitForDoneReporting(interval time.Duration, report func()) error {
+	if report == nil {
+		return m.WaitForDone()
+	}
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return m.err
+		case <-ticker.C:
+			report()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/klog/v2"
+)
+
+// pulledImageStore records the PinnedImageSet images the manager pulled on
+// disk, so that only those are removed once they are no longer pinned, even
+// across restarts of the daemon.
+type pulledImageStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newPulledImageStore(path string) *pulledImageStore {
+	return &pulledImageStore{path: path}
+}
+
+// read returns the recorded images. The lock must be held.
+func (s *pulledImageStore) read() (sets.Set[string], error) {
+	images := sets.New[string]()
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return images, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pulled images: %w", err)
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pulled images %s: %w", s.path, err)
+	}
+	return images.Insert(list...), nil
+}
+
+// update applies f to the recorded images and writes them back if they
+// changed.
+func (s *pulledImageStore) update(f func(sets.Set[string])) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	images, err := s.read()
+	if err != nil {
+		return err
+	}
+	updated := images.Clone()
+	f(updated)
+	if updated.Equal(images) {
+		return nil
+	}
+
+	data, err := json.Marshal(sets.List(updated))
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomicallyWithDefaults(s.path, data); err != nil {
+		return fmt.Errorf("failed to write pulled images: %w", err)
+	}
+	return nil
+}
+
+// Add records that the image was pulled.
+func (s *pulledImageStore) Add(image string) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(images sets.Set[string]) { images.Insert(image) })
+}
+
+// Remove forgets the image.
+func (s *pulledImageStore) Remove(image string) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(images sets.Set[string]) { images.Delete(image) })
+}
+
+// List returns the recorded images, sorted.
+func (s *pulledImageStore) List() ([]string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	images, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return sets.List(images), nil
+}
+
+// isImageInUse returns true if one of the containers runs the image.
+func isImageInUse(image *runtimeapi.Image, name string, containers []*runtimeapi.Container) bool {
+	refs := sets.New(name, image.Id)
+	refs.Insert(image.RepoTags...)
+	refs.Insert(image.RepoDigests...)
+	refs.Delete("")
+	for _, c := range containers {
+		if refs.Has(c.ImageRef) || refs.Has(c.ImageId) || (c.Image != nil && refs.Has(c.Image.Image)) {
+			return true
+		}
+	}
+	return false
+}
+
+// garbageCollectImages removes the images the manager pulled which are not in
+// pinnedImages anymore, unless a container uses them. It returns the removed
+// images and their size. Images in use are kept track of, to be removed by a
+// later garbage collection.
+func (p *PinnedImageSetManager) garbageCollectImages(ctx context.Context, pinnedImages []string) ([]string, int64, error) {
+	pulled, err := p.pulledImages.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	pinned := sets.New(pinnedImages...)
+	var unpinned []string
+	for _, image := range pulled {
+		if !pinned.Has(image) {
+			unpinned = append(unpinned, image)
+		}
+	}
+	if len(unpinned) == 0 {
+		return nil, 0, nil
+	}
+
+	containers, err := p.criClient.ListContainers(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var removed []string
+	var reclaimed int64
+	for _, name := range unpinned {
+		image, err := p.criClient.GetImage(ctx, name)
+		if err != nil {
+			return removed, reclaimed, err
+		}
+		if image != nil {
+			if isImageInUse(image, name, containers) {
+				klog.Infof("Not removing unpinned image %q: in use by a container", name)
+				continue
+			}
+			if err := p.criClient.RemoveImage(ctx, name); err != nil {
+				return removed, reclaimed, fmt.Errorf("failed to remove unpinned image %q: %w", name, err)
+			}
+			klog.Infof("Removed unpinned image %q, reclaiming %d bytes", name, image.Size)
+			removed = append(removed, name)
+			reclaimed += int64(image.Size)
+		}
+
+		p.cache.Remove(name)
+		if err := p.pulledImages.Remove(name); err != nil {
+			return removed, reclaimed, err
+		}
+	}
+	return removed, reclaimed, nil
+}
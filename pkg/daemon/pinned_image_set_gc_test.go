@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/openshift/machine-config-operator/pkg/daemon/cri"
+)
+
+// fakeRuntimeService is a fake container runtime service listing containers.
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	containers []*runtimeapi.Container
+}
+
+// ListContainers implements v1.RuntimeServiceServer.
+func (f *fakeRuntimeService) ListContainers(_ context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	resp := &runtimeapi.ListContainersResponse{}
+	for _, c := range f.containers {
+		if req.Filter != nil && req.Filter.State != nil && req.Filter.State.State != c.State {
+			continue
+		}
+		resp.Containers = append(resp.Containers, c)
+	}
+	return resp, nil
+}
+
+func TestPulledImageStore(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	store := newPulledImageStore(filepath.Join(t.TempDir(), "pulled.json"))
+	images, err := store.List()
+	require.NoError(err)
+	require.Empty(images)
+
+	require.NoError(store.Add("image2"))
+	require.NoError(store.Add("image1"))
+	require.NoError(store.Add("image1"))
+	images, err = store.List()
+	require.NoError(err)
+	require.Equal([]string{"image1", "image2"}, images)
+
+	// a new store sees what was recorded before
+	reloaded := newPulledImageStore(store.path)
+	require.NoError(reloaded.Remove("image2"))
+	images, err = reloaded.List()
+	require.NoError(err)
+	require.Equal([]string{"image1"}, images)
+
+	var unset *pulledImageStore
+	require.NoError(unset.Add("image1"))
+	images, err = unset.List()
+	require.NoError(err)
+	require.Empty(images)
+}
+
+func TestGarbageCollectImages(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		pinned      = "quay.io/test/pinned@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		unpinned    = "quay.io/test/unpinned@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		inUse       = "quay.io/test/in-use@sha256:3333333333333333333333333333333333333333333333333333333333333333"
+		notPulled   = "quay.io/test/not-pulled@sha256:4444444444444444444444444444444444444444444444444444444444444444"
+		alreadyGone = "quay.io/test/gone@sha256:5555555555555555555555555555555555555555555555555555555555555555"
+	)
+
+	runtime := newFakeRuntime([]string{pinned, unpinned, inUse, notPulled}, nil)
+	for i := range runtime.localImages {
+		runtime.localImages[i].Size = 1000
+	}
+	runtimeapi.RegisterRuntimeServiceServer(runtime.server, &fakeRuntimeService{
+		containers: []*runtimeapi.Container{
+			{Id: "running", Image: &runtimeapi.ImageSpec{Image: inUse}, State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+			{Id: "exited", Image: &runtimeapi.ImageSpec{Image: unpinned}, State: runtimeapi.ContainerState_CONTAINER_EXITED},
+		},
+	})
+	listener, err := newTestListener()
+	require.NoError(err)
+	require.NoError(runtime.Start(listener))
+	defer runtime.Stop()
+
+	criClient, err := cri.NewClient(ctx, listener.Addr().String())
+	require.NoError(err)
+
+	store := newPulledImageStore(filepath.Join(t.TempDir(), "pulled.json"))
+	for _, image := range []string{pinned, unpinned, inUse, alreadyGone} {
+		require.NoError(store.Add(image))
+	}
+
+	p := &PinnedImageSetManager{
+		criClient:    criClient,
+		cache:        newImageCache(256),
+		pulledImages: store,
+	}
+
+	removed, reclaimed, err := p.garbageCollectImages(ctx, []string{pinned})
+	require.NoError(err)
+	require.Equal([]string{unpinned}, removed)
+	require.Equal(int64(1000), reclaimed)
+
+	// the image in use is removed once it is not anymore
+	tracked, err := store.List()
+	require.NoError(err)
+	require.Equal([]string{inUse, pinned}, tracked)
+
+	// images which were not pulled by the manager are left alone
+	exists, err := criClient.ImageStatus(ctx, notPulled)
+	require.NoError(err)
+	require.True(exists)
+	exists, err = criClient.ImageStatus(ctx, unpinned)
+	require.NoError(err)
+	require.False(exists)
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/openshift/machine-config-operator/internal"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+// how long to wait before checking again whether the controller granted a
+// prefetch slot, in case the node event is missed.
+const prefetchSlotRetryInterval = time.Minute
+
+var errWaitingForPrefetchSlot = errors.New("waiting for the controller to grant a prefetch slot")
+
+// prefetchLimiter limits the image pulls of a node to the maximum number of
+// concurrent pulls and bytes per second of its pool. The bytes per second are
+// an admission rate: pulls are started no faster than the size of their
+// images allows, but the bandwidth a pull uses once started is not capped. A
+// nil limiter does not limit anything.
+type prefetchLimiter struct {
+	// pulls holds a token for each pull in progress, nil if unlimited.
+	pulls chan struct{}
+	// bytes limits the rate at which pulls start, nil if unlimited.
+	bytes *rate.Limiter
+}
+
+// newPrefetchLimiter returns the limiter for the prefetch annotations of a
+// pool, or nil if the pool does not limit prefetching.
+func newPrefetchLimiter(pool *mcfgv1.MachineConfigPool) (*prefetchLimiter, error) {
+	if pool == nil {
+		return nil, nil
+	}
+
+	limiter := &prefetchLimiter{}
+	if value, ok := pool.Annotations[ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey]; ok {
+		pulls, err := strconv.Atoi(value)
+		if err != nil || pulls < 1 {
+			return nil, fmt.Errorf("invalid %s annotation %q on pool %s: must be a positive integer", ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey, value, pool.Name)
+		}
+		limiter.pulls = make(chan struct{}, pulls)
+	}
+	if value, ok := pool.Annotations[ctrlcommon.PrefetchMaxBytesPerSecondAnnotationKey]; ok {
+		bytesPerSecond, err := resource.ParseQuantity(value)
+		if err != nil || bytesPerSecond.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid %s annotation %q on pool %s: must be a positive quantity", ctrlcommon.PrefetchMaxBytesPerSecondAnnotationKey, value, pool.Name)
+		}
+		// allow a second worth of bytes at once
+		burst := bytesPerSecond.Value()
+		if burst > math.MaxInt32 {
+			burst = math.MaxInt32
+		}
+		limiter.bytes = rate.NewLimiter(rate.Limit(bytesPerSecond.Value()), int(burst))
+	}
+
+	if limiter.pulls == nil && limiter.bytes == nil {
+		return nil, nil
+	}
+	return limiter, nil
+}
+
+// limitsBytes returns whether pulls must be sized before they are acquired.
+func (l *prefetchLimiter) limitsBytes() bool {
+	return l != nil && l.bytes != nil
+}
+
+// acquire waits until an image of the given size may be pulled. The bytes of
+// the image are accounted for before the pull, as the container runtime does
+// not report its progress, so the rate is only held on average over many
+// pulls. Each successful acquire must be followed by a release.
+func (l *prefetchLimiter) acquire(ctx context.Context, size int64) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.pulls != nil {
+		select {
+		case l.pulls <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if l.bytes != nil {
+		for size > 0 {
+			n := int64(l.bytes.Burst())
+			if size < n {
+				n = size
+			}
+			if err := l.bytes.WaitN(ctx, int(n)); err != nil {
+				l.release()
+				return err
+			}
+			size -= n
+		}
+	}
+	return nil
+}
+
+// release ends a pull started by acquire.
+func (l *prefetchLimiter) release() {
+	if l == nil || l.pulls == nil {
+		return
+	}
+	<-l.pulls
+}
+
+// isPrefetchGranted returns true if the controller granted the node's request
+// to prefetch images.
+func isPrefetchGranted(node *corev1.Node) bool {
+	_, requested := node.Annotations[constants.PrefetchRequestedAnnotationKey]
+	return requested && node.Annotations[constants.PrefetchGrantedAnnotationKey] == "true"
+}
+
+// requestPrefetchSlot asks the controller to let the node prefetch images and
+// returns true once it has.
+func (p *PinnedImageSetManager) requestPrefetchSlot(node *corev1.Node) (bool, error) {
+	if isPrefetchGranted(node) {
+		return true, nil
+	}
+	if _, ok := node.Annotations[constants.PrefetchRequestedAnnotationKey]; ok {
+		return false, nil
+	}
+
+	klog.Infof("Requesting a prefetch slot for node %s", node.Name)
+	_, err := internal.UpdateNodeRetry(p.kubeClient.CoreV1().Nodes(), p.nodeLister, node.Name, func(node *corev1.Node) {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[constants.PrefetchRequestedAnnotationKey] = time.Now().UTC().Format(time.RFC3339)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to request prefetch slot: %w", err)
+	}
+	return false, nil
+}
+
+// releasePrefetchSlot gives back the node's prefetch slot, if it has one or
+// asked for one.
+func (p *PinnedImageSetManager) releasePrefetchSlot() error {
+	node, err := p.nodeLister.Get(p.nodeName)
+	if err != nil {
+		return err
+	}
+	_, requested := node.Annotations[constants.PrefetchRequestedAnnotationKey]
+	_, granted := node.Annotations[constants.PrefetchGrantedAnnotationKey]
+	if !requested && !granted {
+		return nil
+	}
+
+	klog.Infof("Releasing prefetch slot for node %s", node.Name)
+	_, err = internal.UpdateNodeRetry(p.kubeClient.CoreV1().Nodes(), p.nodeLister, node.Name, func(node *corev1.Node) {
+		delete(node.Annotations, constants.PrefetchRequestedAnnotationKey)
+		delete(node.Annotations, constants.PrefetchGrantedAnnotationKey)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release prefetch slot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPrefetchLimiter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expectLimiter bool
+		expectErr     bool
+	}{
+		{
+			name: "no limits",
+		},
+		{
+			name:          "valid limits",
+			annotations:   map[string]string{ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey: "2", ctrlcommon.PrefetchMaxBytesPerSecondAnnotationKey: "50Mi"},
+			expectLimiter: true,
+		},
+		{
+			name:        "zero pulls",
+			annotations: map[string]string{ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey: "0"},
+			expectErr:   true,
+		},
+		{
+			name:        "invalid rate",
+			annotations: map[string]string{ctrlcommon.PrefetchMaxBytesPerSecondAnnotationKey: "fast"},
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+			pool.Annotations = test.annotations
+
+			limiter, err := newPrefetchLimiter(pool)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectLimiter, limiter != nil)
+		})
+	}
+}
+
+func TestPrefetchLimiter(t *testing.T) {
+	t.Parallel()
+
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+	pool.Annotations = map[string]string{
+		ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey: "1",
+		ctrlcommon.PrefetchMaxBytesPerSecondAnnotationKey:  "1000",
+	}
+	limiter, err := newPrefetchLimiter(pool)
+	require.NoError(t, err)
+
+	// the first second worth of bytes is available at once
+	require.NoError(t, limiter.acquire(context.Background(), 1000))
+
+	// a second pull waits for the first to be released
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.acquire(ctx, 0), context.DeadlineExceeded)
+	limiter.release()
+
+	// and for its bytes to be available
+	start := time.Now()
+	require.NoError(t, limiter.acquire(context.Background(), 200))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	limiter.release()
+
+	// unsized pulls must be sized to be limited by bytes
+	assert.True(t, limiter.limitsBytes())
+	pool.Annotations = map[string]string{ctrlcommon.PrefetchMaxConcurrentPullsAnnotationKey: "1"}
+	pullsOnly, err := newPrefetchLimiter(pool)
+	require.NoError(t, err)
+	assert.False(t, pullsOnly.limitsBytes())
+
+	// a nil limiter does not limit anything
+	var unlimited *prefetchLimiter
+	assert.False(t, unlimited.limitsBytes())
+	assert.NoError(t, unlimited.acquire(context.Background(), 1<<40))
+	unlimited.release()
+}
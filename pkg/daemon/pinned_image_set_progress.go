@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+)
+
+const (
+	// how often the progress of the images is checked, and published if it
+	// changed, while prefetching
+	pinnedImageSetProgressInterval = 10 * time.Second
+	// how many failed images are listed in the error of a PinnedImageSet
+	maxFailedImagesInError = 5
+)
+
+// imageProgressTracker tracks the prefetch progress of images by name. A nil
+// tracker does not track anything.
+type imageProgressTracker struct {
+	mu     sync.Mutex
+	images map[string]upgrademonitor.PinnedImageProgress
+}
+
+func newImageProgressTracker() *imageProgressTracker {
+	return &imageProgressTracker{images: map[string]upgrademonitor.PinnedImageProgress{}}
+}
+
+// reset forgets the progress of all images.
+func (t *imageProgressTracker) reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.images = map[string]upgrademonitor.PinnedImageProgress{}
+}
+
+// set records the state of an image and the reason it is in it.
+func (t *imageProgressTracker) set(image string, state upgrademonitor.PinnedImageState, reason string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.images[image]
+	progress.Name = image
+	progress.State = state
+	progress.Reason = reason
+	t.images[image] = progress
+}
+
+// setSize records the bytes to download for an image.
+func (t *imageProgressTracker) setSize(image string, size int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.images[image]
+	progress.Name = image
+	progress.TotalBytes = size
+	t.images[image] = progress
+}
+
+func (t *imageProgressTracker) get(image string) (upgrademonitor.PinnedImageProgress, bool) {
+	if t == nil {
+		return upgrademonitor.PinnedImageProgress{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress, ok := t.images[image]
+	return progress, ok
+}
+
+// getPinnedImageSetProgress returns the progress of the images of an image
+// set. All the images of a complete image set are pulled.
+func (p *PinnedImageSetManager) getPinnedImageSetProgress(imageSet *mcfgv1.PinnedImageSet, complete bool) upgrademonitor.PinnedImageSetProgress {
+	setProgress := upgrademonitor.PinnedImageSetProgress{Name: imageSet.Name, Images: []upgrademonitor.PinnedImageProgress{}}
+	for _, ref := range imageSet.Spec.PinnedImages {
+		name := strings.TrimSpace(string(ref.Name))
+		progress, ok := p.imageProgress.get(name)
+		if !ok {
+			progress = upgrademonitor.PinnedImageProgress{Name: name, State: upgrademonitor.PinnedImagePending}
+		}
+		if progress.TotalBytes == 0 {
+			if value, found := p.cache.Get(name); found {
+				if info, ok := value.(imageInfo); ok {
+					progress.TotalBytes = info.Size
+				}
+			}
+		}
+		if complete {
+			progress.State = upgrademonitor.PinnedImagePulled
+			progress.Reason = ""
+		}
+		if progress.State == upgrademonitor.PinnedImagePulled {
+			progress.DownloadedBytes = progress.TotalBytes
+		}
+
+		setProgress.Images = append(setProgress.Images, progress)
+		setProgress.DownloadedBytes += progress.DownloadedBytes
+		setProgress.TotalBytes += progress.TotalBytes
+	}
+	return setProgress
+}
+
+// describeFailedImages lists the failed images of an image set and why they
+// failed, to explain why the image set failed.
+func describeFailedImages(progress upgrademonitor.PinnedImageSetProgress) string {
+	var failed []string
+	for _, image := range progress.Images {
+		if image.State == upgrademonitor.PinnedImageFailed {
+			failed = append(failed, fmt.Sprintf("%s: %s", image.Name, image.Reason))
+		}
+	}
+	if len(failed) == 0 {
+		return ""
+	}
+	if len(failed) > maxFailedImagesInError {
+		failed = append(failed[:maxFailedImagesInError], fmt.Sprintf("and %d more", len(failed)-maxFailedImagesInError))
+	}
+	return "failed images: " + strings.Join(failed, "; ")
+}
+
+// publishPinnedImageSetProgress publishes the progress of the images of the
+// image sets on the MachineConfigNode.
+func (p *PinnedImageSetManager) publishPinnedImageSetProgress(progress []upgrademonitor.PinnedImageSetProgress) {
+	if err := upgrademonitor.UpdateMachineConfigNodePinnedImageSetProgress(p.fgHandler, p.mcfgClient, p.nodeName, progress); err != nil {
+		klog.Errorf("failed to publish pinned image set progress: %v", err)
+	}
+}
+
+// newPinnedImageSetProgressReporter returns a function publishing the progress
+// of the images of the image sets of the pools, with the Progressing status of
+// the MachineConfigNode, whenever it changed. It is called from the sync
+// waiting on the pulls, which applies the other statuses of the sync too, so a
+// report never lands after the status the sync ends with.
+func (p *PinnedImageSetManager) newPinnedImageSetProgressReporter(pools []*mcfgv1.MachineConfigPool) func() {
+	var reported []upgrademonitor.PinnedImageSetProgress
+	return func() {
+		_, progress, err := p.getPinnedImageSetApplyConfigsForPools(pools, false, nil)
+		if err != nil {
+			klog.Errorf("failed to get pinned image set progress: %v", err)
+			return
+		}
+		if reflect.DeepEqual(progress, reported) {
+			return
+		}
+		if err := p.updateStatusProgressing(pools); err != nil {
+			klog.Errorf("failed to update status: %v", err)
+			return
+		}
+		reported = progress
+	}
+}
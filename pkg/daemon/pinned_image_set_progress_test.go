@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+)
+
+func TestPinnedImageSetProgress(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	imageSet := &mcfgv1.PinnedImageSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-set", Generation: 2},
+		Spec: mcfgv1.PinnedImageSetSpec{
+			PinnedImages: []mcfgv1.PinnedImageRef{
+				{Name: "image-pulled"},
+				{Name: "image-failed"},
+				{Name: "image-pulling"},
+				{Name: "image-unknown"},
+			},
+		},
+	}
+
+	p := &PinnedImageSetManager{
+		cache:         newImageCache(10),
+		imageProgress: newImageProgressTracker(),
+	}
+	p.imageProgress.setSize("image-pulled", 100)
+	p.imageProgress.set("image-pulled", upgrademonitor.PinnedImagePulled, "")
+	p.imageProgress.setSize("image-failed", 200)
+	p.imageProgress.set("image-failed", upgrademonitor.PinnedImageFailed, "manifest unknown")
+	p.imageProgress.set("image-pulling", upgrademonitor.PinnedImagePulling, "")
+	// sizes missing from the tracker come from the cache
+	p.cache.Add("image-pulling", imageInfo{Name: "image-pulling", Size: 300})
+
+	progress := p.getPinnedImageSetProgress(imageSet, false)
+	require.Equal("worker-set", progress.Name)
+	require.Equal([]upgrademonitor.PinnedImageProgress{
+		{Name: "image-pulled", State: upgrademonitor.PinnedImagePulled, DownloadedBytes: 100, TotalBytes: 100},
+		{Name: "image-failed", State: upgrademonitor.PinnedImageFailed, Reason: "manifest unknown", TotalBytes: 200},
+		{Name: "image-pulling", State: upgrademonitor.PinnedImagePulling, TotalBytes: 300},
+		{Name: "image-unknown", State: upgrademonitor.PinnedImagePending},
+	}, progress.Images)
+	require.Equal(int64(100), progress.DownloadedBytes)
+	require.Equal(int64(600), progress.TotalBytes)
+
+	// the failed images are added to the error of the image set
+	config, _ := p.createApplyConfigForImageSet(imageSet, false, errors.New("prefetch failed"))
+	require.Equal(int32(2), *config.LastFailedGeneration)
+	require.Equal("prefetch failed; failed images: image-failed: manifest unknown", *config.LastFailedGenerationError)
+
+	// all the images of a complete image set are pulled
+	config, progress = p.createApplyConfigForImageSet(imageSet, true, nil)
+	require.Equal(int32(2), *config.CurrentGeneration)
+	for _, image := range progress.Images {
+		require.Equal(upgrademonitor.PinnedImagePulled, image.State)
+		require.Empty(image.Reason)
+	}
+	require.Equal(int64(600), progress.DownloadedBytes)
+
+	// a nil tracker reports every image pending
+	p.imageProgress = nil
+	progress = p.getPinnedImageSetProgress(imageSet, false)
+	for _, image := range progress.Images {
+		require.Equal(upgrademonitor.PinnedImagePending, image.State)
+	}
+}
+
+func TestDescribeFailedImages(t *testing.T) {
+	t.Parallel()
+
+	progress := upgrademonitor.PinnedImageSetProgress{}
+	require.Empty(t, describeFailedImages(progress))
+
+	for i := 0; i < maxFailedImagesInError+2; i++ {
+		progress.Images = append(progress.Images, upgrademonitor.PinnedImageProgress{
+			Name:   fmt.Sprintf("image%d", i),
+			State:  upgrademonitor.PinnedImageFailed,
+			Reason: "denied",
+		})
+	}
+	progress.Images = append(progress.Images, upgrademonitor.PinnedImageProgress{Name: "pulled", State: upgrademonitor.PinnedImagePulled})
+	require.Equal(t,
+		"failed images: image0: denied; image1: denied; image2: denied; image3: denied; image4: denied; and 2 more",
+		describeFailedImages(progress))
+}
+
+func TestPrefetchMonitorWaitForDoneReporting(t *testing.T) {
+	t.Parallel()
+
+	monitor := newPrefetchMonitor()
+	monitor.Add(1)
+	reports := 0
+	err := monitor.WaitForDoneReporting(time.Millisecond, func() {
+		reports++
+		// the report runs on the waiting goroutine until the prefetch is done
+		if reports == 3 {
+			monitor.Error(errors.New("pull failed"))
+			monitor.Done()
+		}
+	})
+	require.EqualError(t, err, "pull failed")
+	require.Equal(t, 3, reports)
+
+	// without a report it only waits
+	monitor = newPrefetchMonitor()
+	require.NoError(t, monitor.WaitForDoneReporting(time.Millisecond, nil))
+}
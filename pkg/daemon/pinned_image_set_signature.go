@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/machine-config-operator/pkg/imageutils"
+)
+
+var errImageSignatureRejected = errors.New("image rejected by signature policy")
+
+// imageSignatureVerifier checks images against the signature policies the
+// container runtime config controller renders for ClusterImagePolicies and
+// ImagePolicies. A pinned image is never pulled again, so the container
+// runtime would not verify it when a pod runs it. Images must therefore be
+// accepted by the cluster policy and by the policy of every namespace before
+// they are pinned. A nil verifier accepts every image.
+type imageSignatureVerifier struct {
+	// path to the cluster signature policy
+	policyPath string
+	// directory of the per namespace signature policies
+	namespacedPoliciesDir string
+	// used to fetch the images and their signatures from the registries or
+	// their mirrors
+	sysCtx *types.SystemContext
+}
+
+func newImageSignatureVerifier(policyPath, namespacedPoliciesDir, registriesDirPath, authFilePath, registryCfgPath string) *imageSignatureVerifier {
+	return &imageSignatureVerifier{
+		policyPath:            policyPath,
+		namespacedPoliciesDir: namespacedPoliciesDir,
+		sysCtx: &types.SystemContext{
+			AuthFilePath:             authFilePath,
+			SystemRegistriesConfPath: registryCfgPath,
+			RegistriesDirPath:        registriesDirPath,
+		},
+	}
+}
+
+// policyPaths returns the paths of the cluster policy and the namespace
+// policies which exist.
+func (v *imageSignatureVerifier) policyPaths() ([]string, error) {
+	var paths []string
+	if _, err := os.Stat(v.policyPath); err == nil {
+		paths = append(paths, v.policyPath)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	namespaced, err := filepath.Glob(filepath.Join(v.namespacedPoliciesDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(namespaced)
+	return append(paths, namespaced...), nil
+}
+
+// requiresVerification returns true if the policy does more than accept any
+// image from a registry.
+func requiresVerification(policy *signature.Policy) bool {
+	acceptAnything := signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}
+	if !reflect.DeepEqual(policy.Default, acceptAnything) {
+		return true
+	}
+	for _, requirements := range policy.Transports["docker"] {
+		if !reflect.DeepEqual(requirements, acceptAnything) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify returns an error wrapping errImageSignatureRejected if a signature
+// policy does not accept the image.
+func (v *imageSignatureVerifier) verify(ctx context.Context, imageName string) error {
+	if v == nil {
+		return nil
+	}
+
+	paths, err := v.policyPaths()
+	if err != nil {
+		return fmt.Errorf("failed to find signature policies: %w", err)
+	}
+
+	ref, err := imageutils.ParseImageName(imageName)
+	if err != nil {
+		return fmt.Errorf("failed to parse image %q: %w", imageName, err)
+	}
+
+	// the image is only fetched if a policy has to verify it
+	var src types.ImageSource
+	defer func() {
+		if src != nil {
+			src.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		policy, err := signature.NewPolicyFromFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read signature policy %s: %w", path, err)
+		}
+		if !requiresVerification(policy) {
+			continue
+		}
+
+		if src == nil {
+			src, err = ref.NewImageSource(ctx, v.sysCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get image %q to verify its signatures: %w", imageName, err)
+			}
+		}
+
+		allowed, err := isImageAllowed(ctx, policy, src)
+		if !allowed {
+			return fmt.Errorf("%w %s: %q: %v", errImageSignatureRejected, path, imageName, err)
+		}
+		klog.V(4).Infof("Image %q accepted by signature policy %s", imageName, path)
+	}
+	return nil
+}
+
+func isImageAllowed(ctx context.Context, policy *signature.Policy, src types.ImageSource) (bool, error) {
+	policyContext, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := policyContext.Destroy(); err != nil {
+			klog.Warningf("failed to destroy signature policy context: %v", err)
+		}
+	}()
+	return policyContext.IsRunningImageAllowed(ctx, image.UnparsedInstance(src, nil))
+}
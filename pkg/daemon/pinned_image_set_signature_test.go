@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	acceptAnythingPolicy = `{"default": [{"type": "insecureAcceptAnything"}]}`
+	ociManifest          = `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "size": 2}, "layers": []}`
+)
+
+// newFakeRegistry returns a registry serving a single unsigned image, its
+// pull spec and the number of requests it served.
+func newFakeRegistry(t *testing.T) (*httptest.Server, string, *atomic.Int32) {
+	manifestDigest := digest.FromString(ociManifest)
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/test/image/manifests/" + manifestDigest.String():
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, ociManifest)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	host := strings.TrimPrefix(server.URL, "http://")
+	return server, fmt.Sprintf("%s/test/image@%s", host, manifestDigest), requests
+}
+
+func sigstorePublicKeyPolicy(t *testing.T, scope string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return fmt.Sprintf(`{"default": [{"type": "insecureAcceptAnything"}], "transports": {"docker": {%q: [{"type": "sigstoreSigned", "keyData": %q, "signedIdentity": {"type": "matchRepoDigestOrExact"}}]}}}`, scope, keyData)
+}
+
+func TestImageSignatureVerifier(t *testing.T) {
+	server, image, requests := newFakeRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	scope := host + "/test"
+
+	testCases := []struct {
+		name               string
+		clusterPolicy      string
+		namespacedPolicies map[string]string
+		expectRejected     bool
+		// the image is only fetched if a policy has to verify it
+		expectFetched bool
+	}{
+		{
+			name: "no policies",
+		},
+		{
+			name:          "cluster policy accepts anything",
+			clusterPolicy: acceptAnythingPolicy,
+		},
+		{
+			name:           "cluster policy rejects the scope",
+			clusterPolicy:  fmt.Sprintf(`{"default": [{"type": "insecureAcceptAnything"}], "transports": {"docker": {%q: [{"type": "reject"}]}}}`, scope),
+			expectRejected: true,
+			expectFetched:  true,
+		},
+		{
+			name:          "cluster policy accepts the scope",
+			clusterPolicy: fmt.Sprintf(`{"default": [{"type": "reject"}], "transports": {"docker": {%q: [{"type": "insecureAcceptAnything"}]}}}`, scope),
+			expectFetched: true,
+		},
+		{
+			name:           "cluster policy requires a signature",
+			clusterPolicy:  sigstorePublicKeyPolicy(t, scope),
+			expectRejected: true,
+			expectFetched:  true,
+		},
+		{
+			name:          "namespace policy requires a signature",
+			clusterPolicy: acceptAnythingPolicy,
+			namespacedPolicies: map[string]string{
+				"other": acceptAnythingPolicy,
+				"test":  sigstorePublicKeyPolicy(t, scope),
+			},
+			expectRejected: true,
+			expectFetched:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require := require.New(t)
+			dir := t.TempDir()
+			registriesConf := filepath.Join(dir, "registries.conf")
+			require.NoError(os.WriteFile(registriesConf, []byte(fmt.Sprintf("[[registry]]\nlocation = %q\ninsecure = true\n", host)), 0o644))
+			policyPath := filepath.Join(dir, "policy.json")
+			if testCase.clusterPolicy != "" {
+				require.NoError(os.WriteFile(policyPath, []byte(testCase.clusterPolicy), 0o644))
+			}
+			policiesDir := filepath.Join(dir, "policies")
+			require.NoError(os.Mkdir(policiesDir, 0o755))
+			for namespace, policy := range testCase.namespacedPolicies {
+				require.NoError(os.WriteFile(filepath.Join(policiesDir, namespace+".json"), []byte(policy), 0o644))
+			}
+			registriesDir := filepath.Join(dir, "registries.d")
+			require.NoError(os.Mkdir(registriesDir, 0o755))
+
+			verifier := newImageSignatureVerifier(policyPath, policiesDir, registriesDir, filepath.Join(dir, "auth.json"), registriesConf)
+			requests.Store(0)
+			err := verifier.verify(context.Background(), image)
+			if testCase.expectRejected {
+				require.ErrorIs(err, errImageSignatureRejected)
+			} else {
+				require.NoError(err)
+			}
+			require.Equal(testCase.expectFetched, requests.Load() > 0)
+		})
+	}
+
+	var unset *imageSignatureVerifier
+	require.NoError(t, unset.verify(context.Background(), image))
+}
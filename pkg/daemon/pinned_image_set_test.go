@@ -384,7 +384,7 @@ func TestPrefetchImageSets(t *testing.T) {
 				p.prefetchWorker(ctx)
 			}()
 
-			err = p.prefetchImageSets(ctx, imageSets...)
+			err = p.prefetchImageSets(ctx, nil, nil, imageSets...)
 			if tt.wantErr != nil {
 				require.ErrorIs(err, tt.wantErr)
 				return
@@ -760,8 +760,14 @@ func (r *FakeRuntime) PullImage(ctx context.Context, req *runtimeapi.PullImageRe
 }
 
 // RemoveImage implements v1.ImageServiceServer.
-func (*FakeRuntime) RemoveImage(context.Context, *runtimeapi.RemoveImageRequest) (*runtimeapi.RemoveImageResponse, error) {
-	panic("unimplemented")
+func (r *FakeRuntime) RemoveImage(_ context.Context, req *runtimeapi.RemoveImageRequest) (*runtimeapi.RemoveImageResponse, error) {
+	for i := range r.localImages {
+		if r.localImages[i].Spec.Image == req.Image.Image {
+			r.localImages = append(r.localImages[:i], r.localImages[i+1:]...)
+			return &runtimeapi.RemoveImageResponse{}, nil
+		}
+	}
+	return &runtimeapi.RemoveImageResponse{}, nil
 }
 
 // Start starts the fake remote runtime.
@@ -0,0 +1,317 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// PreUpdateHookDecision is what a pre-update hook wants done with an update.
+type PreUpdateHookDecision string
+
+const (
+	// PreUpdateHookProceed lets the update go ahead.
+	PreUpdateHookProceed PreUpdateHookDecision = "Proceed"
+	// PreUpdateHookDelay holds the update off until DelayUntil, after which
+	// the hooks run again.
+	PreUpdateHookDelay PreUpdateHookDecision = "Delay"
+	// PreUpdateHookReject fails the update, degrading the node until the
+	// hooks let it proceed.
+	PreUpdateHookReject PreUpdateHookDecision = "Reject"
+)
+
+const (
+	// How long a single pre-update hook may take to decide.
+	preUpdateHookTimeout = 5 * time.Minute
+	// How long to wait before running the hooks again when a hook delays an
+	// update without saying until when.
+	defaultPreUpdateHookDelay = 5 * time.Minute
+
+	// preUpdateHookServiceCAPath is the service CA bundle OpenShift injects into
+	// the service account volume of the daemon, trusted by HTTP hooks on top of
+	// the system roots so hooks can be served by in-cluster services.
+	preUpdateHookServiceCAPath = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+)
+
+// PreUpdateHookRequest describes an update to a pre-update hook. Executable
+// hooks get it on stdin, HTTP hooks as the body of a POST.
+type PreUpdateHookRequest struct {
+	Node           string `json:"node"`
+	Pool           string `json:"pool"`
+	CurrentConfig  string `json:"currentConfig"`
+	DesiredConfig  string `json:"desiredConfig"`
+	DrainRequired  bool   `json:"drainRequired"`
+	RebootRequired bool   `json:"rebootRequired"`
+}
+
+// PreUpdateHookResponse is the decision of a pre-update hook. Executable hooks
+// write it to stdout, HTTP hooks return it as the response body. Executable
+// hooks which exit successfully without output proceed.
+type PreUpdateHookResponse struct {
+	Decision PreUpdateHookDecision `json:"decision"`
+	// DelayUntil is when to run the hooks again for Delay decisions.
+	DelayUntil *metav1.Time `json:"delayUntil,omitempty"`
+	// Reason explains Delay and Reject decisions.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (r *PreUpdateHookResponse) validate() error {
+	switch r.Decision {
+	case PreUpdateHookProceed, PreUpdateHookDelay, PreUpdateHookReject:
+		return nil
+	default:
+		return fmt.Errorf("invalid decision %q: must be one of %s, %s or %s", r.Decision, PreUpdateHookProceed, PreUpdateHookDelay, PreUpdateHookReject)
+	}
+}
+
+// preUpdateHookDelayErr is returned when a pre-update hook delays an update.
+// It is not a failure: the node is requeued for when the delay ends instead of
+// being degraded.
+type preUpdateHookDelayErr struct {
+	error
+	until time.Time
+}
+
+// A preUpdateHook asks a hook what to do with an update.
+type preUpdateHook struct {
+	name string
+	run  func(ctx context.Context, req []byte) (*PreUpdateHookResponse, error)
+}
+
+func parsePreUpdateHookResponse(out []byte) (*PreUpdateHookResponse, error) {
+	resp := &PreUpdateHookResponse{}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return nil, fmt.Errorf("could not parse decision: %w", err)
+	}
+	if err := resp.validate(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// newExecPreUpdateHook returns a hook running an executable.
+func newExecPreUpdateHook(path string) preUpdateHook {
+	return preUpdateHook{
+		name: path,
+		run: func(ctx context.Context, req []byte) (*PreUpdateHookResponse, error) {
+			var stdout, stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, path)
+			cmd.Stdin = bytes.NewReader(req)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			// Don't wait on children of a killed hook which still hold its output.
+			cmd.WaitDelay = time.Second
+			if err := cmd.Run(); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, truncateHookOutput(stderr.Bytes()))
+			}
+			if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+				return &PreUpdateHookResponse{Decision: PreUpdateHookProceed}, nil
+			}
+			return parsePreUpdateHookResponse(stdout.Bytes())
+		},
+	}
+}
+
+// newPreUpdateHookHTTPClient returns the client HTTP hooks are called with. It
+// trusts the system roots and the CA bundle at caPath, if it can be read, and
+// gives up on hooks which take longer than preUpdateHookTimeout.
+func newPreUpdateHookHTTPClient(caPath string) *http.Client {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		klog.Warningf("Could not load system roots for pre-update hooks: %v", err)
+		roots = x509.NewCertPool()
+	}
+	if caPath != "" {
+		if caData, err := os.ReadFile(caPath); err != nil {
+			klog.Warningf("Could not read CA bundle for pre-update hooks: %v", err)
+		} else if !roots.AppendCertsFromPEM(caData) {
+			klog.Warningf("No certificates found in %s", caPath)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+	return &http.Client{Transport: transport, Timeout: preUpdateHookTimeout}
+}
+
+// newHTTPPreUpdateHook returns a hook POSTing to a URL with client.
+func newHTTPPreUpdateHook(url string, client *http.Client) preUpdateHook {
+	return preUpdateHook{
+		name: url,
+		run: func(ctx context.Context, req []byte) (*PreUpdateHookResponse, error) {
+			httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(req))
+			if err != nil {
+				return nil, err
+			}
+			httpReq.Header.Set("Content-Type", "application/json")
+			resp, err := client.Do(httpReq)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return nil, fmt.Errorf("returned %s: %s", resp.Status, truncateHookOutput(body))
+			}
+			return parsePreUpdateHookResponse(body)
+		},
+	}
+}
+
+// getPreUpdateHooks returns the executable hooks in hooksDir, followed by the
+// HTTP hook of the pool, if any, whose certificate is verified with the CA
+// bundle at caPath.
+func getPreUpdateHooks(hooksDir, caPath string, pool *mcfgv1.MachineConfigPool) ([]preUpdateHook, error) {
+	scripts, err := getExecutableHooks(hooksDir)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []preUpdateHook
+	for _, script := range scripts {
+		hooks = append(hooks, newExecPreUpdateHook(script))
+	}
+	if pool != nil {
+		if url := strings.TrimSpace(pool.Annotations[ctrlcommon.PreUpdateHookURLAnnotationKey]); url != "" {
+			hooks = append(hooks, newHTTPPreUpdateHook(url, newPreUpdateHookHTTPClient(caPath)))
+		}
+	}
+	return hooks, nil
+}
+
+// decidePreUpdate asks the hooks in order what to do with the update, stopping
+// at the first one which does not proceed. A hook which fails to decide fails
+// the update.
+func decidePreUpdate(hooks []preUpdateHook, req *PreUpdateHookRequest, now time.Time) (string, *PreUpdateHookResponse, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not marshal pre-update hook request: %w", err)
+	}
+
+	for _, hook := range hooks {
+		klog.Infof("Running pre-update hook %s", hook.name)
+		ctx, cancel := context.WithTimeout(context.Background(), preUpdateHookTimeout)
+		resp, err := hook.run(ctx, reqBytes)
+		cancel()
+		if err != nil {
+			return hook.name, nil, fmt.Errorf("pre-update hook %s failed: %w", hook.name, err)
+		}
+
+		// A delay which is already over is as good as proceeding.
+		if resp.Decision == PreUpdateHookDelay && resp.DelayUntil != nil && !resp.DelayUntil.After(now) {
+			resp.Decision = PreUpdateHookProceed
+		}
+		if resp.Decision != PreUpdateHookProceed {
+			return hook.name, resp, nil
+		}
+	}
+	return "", &PreUpdateHookResponse{Decision: PreUpdateHookProceed}, nil
+}
+
+// runPreUpdateHooks runs the pre-update hooks before the node is drained for
+// an update from oldConfigName to newConfigName. It returns a
+// *preUpdateHookDelayErr if a hook delays the update, or an error if one
+// rejects it. Either way the decision is recorded on the MachineConfigNode.
+func (dn *Daemon) runPreUpdateHooks(poolName, oldConfigName, newConfigName string, drain, reboot bool) error {
+	var pool *mcfgv1.MachineConfigPool
+	if dn.mcpLister != nil && poolName != "" {
+		var err error
+		if pool, err = dn.mcpLister.Get(poolName); err != nil {
+			return fmt.Errorf("could not get MachineConfigPool %q for pre-update hooks: %w", poolName, err)
+		}
+	}
+	hooks, err := getPreUpdateHooks(constants.PreUpdateHooksDir, preUpdateHookServiceCAPath, pool)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	req := &PreUpdateHookRequest{
+		Node:           dn.name,
+		Pool:           poolName,
+		CurrentConfig:  oldConfigName,
+		DesiredConfig:  newConfigName,
+		DrainRequired:  drain,
+		RebootRequired: reboot,
+	}
+	now := time.Now()
+	hook, resp, err := decidePreUpdate(hooks, req, now)
+	if err != nil {
+		return err
+	}
+
+	var message string
+	var decisionErr error
+	switch resp.Decision {
+	case PreUpdateHookProceed:
+		logSystem("Pre-update hooks let the update to %s proceed", newConfigName)
+		return nil
+	case PreUpdateHookDelay:
+		until := now.Add(defaultPreUpdateHookDelay)
+		if resp.DelayUntil != nil {
+			until = resp.DelayUntil.Time
+		}
+		message = fmt.Sprintf("Update to %s delayed until %s by pre-update hook %s: %s", newConfigName, until.UTC().Format(time.RFC3339), hook, resp.Reason)
+		decisionErr = &preUpdateHookDelayErr{error: errors.New(message), until: until}
+	case PreUpdateHookReject:
+		message = fmt.Sprintf("Update to %s rejected by pre-update hook %s: %s", newConfigName, hook, resp.Reason)
+		decisionErr = errors.New(message)
+	}
+
+	logSystem("%s", message)
+	if dn.nodeWriter != nil {
+		dn.nodeWriter.Eventf(corev1.EventTypeWarning, "PreUpdateHook"+string(resp.Decision), message)
+	}
+	if err := upgrademonitor.GenerateAndApplyMachineConfigNodes(
+		&upgrademonitor.Condition{State: mcfgv1.MachineConfigNodeUpdatePrepared, Reason: string(mcfgv1.MachineConfigNodeUpdatePrepared), Message: message},
+		nil,
+		metav1.ConditionFalse,
+		metav1.ConditionFalse,
+		dn.node,
+		dn.mcfgClient,
+		dn.fgHandler,
+		poolName,
+	); err != nil {
+		klog.Errorf("Error making MCN for pre-update hook decision: %v", err)
+	}
+	return decisionErr
+}
+
+// restoreStateForPreUpdateHookDelay puts back the state the node was in before
+// it was marked Working for an update a pre-update hook delayed, so the node
+// is not reported as updating for the whole delay, and restarts the config
+// drift monitor stopped for the update. Nothing has changed on the node at
+// that point, so drift from the current config is still detected while the
+// update waits.
+func (dn *Daemon) restoreStateForPreUpdateHookDelay(state string) {
+	if dn.nodeWriter == nil {
+		return
+	}
+	if state != "" {
+		if _, err := dn.nodeWriter.SetAnnotations(map[string]string{constants.MachineConfigDaemonStateAnnotationKey: state}); err != nil {
+			klog.Errorf("Error restoring node state %s after pre-update hook delay: %v", state, err)
+		} else {
+			UpdateStateMetric(mcdState, state, "")
+		}
+	}
+	dn.startConfigDriftMonitor()
+}
@@ -0,0 +1,219 @@
+package daemon
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDecidePreUpdate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	writeHook := func(name, contents string) preUpdateHook {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o755))
+		return newExecPreUpdateHook(path)
+	}
+
+	proceed := writeHook("proceed", "#!/bin/sh\ncat >/dev/null\n")
+	explicitProceed := writeHook("explicit-proceed", "#!/bin/sh\necho '{\"decision\": \"Proceed\"}'\n")
+	delay := writeHook("delay", "#!/bin/sh\necho '{\"decision\": \"Delay\", \"delayUntil\": \"2025-01-01T13:00:00Z\", \"reason\": \"handing off leadership\"}'\n")
+	pastDelay := writeHook("past-delay", "#!/bin/sh\necho '{\"decision\": \"Delay\", \"delayUntil\": \"2025-01-01T11:00:00Z\"}'\n")
+	reject := writeHook("reject", "#!/bin/sh\necho '{\"decision\": \"Reject\", \"reason\": \"license server unreachable\"}'\n")
+	failing := writeHook("failing", "#!/bin/sh\necho broken >&2\nexit 2\n")
+	invalid := writeHook("invalid", "#!/bin/sh\necho '{\"decision\": \"Maybe\"}'\n")
+	// Checks it gets the update on stdin.
+	checksRequest := writeHook("checks-request", "#!/bin/sh\ngrep -q '\"desiredConfig\":\"rendered-worker-1\"' || exit 1\n")
+
+	req := &PreUpdateHookRequest{Node: "node", Pool: "worker", CurrentConfig: "rendered-worker-0", DesiredConfig: "rendered-worker-1", DrainRequired: true}
+
+	tests := []struct {
+		name             string
+		hooks            []preUpdateHook
+		expectedHook     string
+		expectedDecision PreUpdateHookDecision
+		expectedReason   string
+		expectErr        bool
+	}{
+		{
+			name:             "no hooks",
+			expectedDecision: PreUpdateHookProceed,
+		},
+		{
+			name:             "all proceed",
+			hooks:            []preUpdateHook{proceed, explicitProceed, pastDelay, checksRequest},
+			expectedDecision: PreUpdateHookProceed,
+		},
+		{
+			name:             "delay",
+			hooks:            []preUpdateHook{proceed, delay, reject},
+			expectedHook:     delay.name,
+			expectedDecision: PreUpdateHookDelay,
+			expectedReason:   "handing off leadership",
+		},
+		{
+			name:             "reject",
+			hooks:            []preUpdateHook{reject, delay},
+			expectedHook:     reject.name,
+			expectedDecision: PreUpdateHookReject,
+			expectedReason:   "license server unreachable",
+		},
+		{
+			name:         "failing hook",
+			hooks:        []preUpdateHook{failing},
+			expectedHook: failing.name,
+			expectErr:    true,
+		},
+		{
+			name:         "invalid decision",
+			hooks:        []preUpdateHook{invalid},
+			expectedHook: invalid.name,
+			expectErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			hook, resp, err := decidePreUpdate(test.hooks, req, now)
+			assert.Equal(t, test.expectedHook, hook)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedDecision, resp.Decision)
+			assert.Equal(t, test.expectedReason, resp.Reason)
+		})
+	}
+}
+
+func TestHTTPPreUpdateHook(t *testing.T) {
+	t.Parallel()
+
+	var got PreUpdateHookRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"decision": "Reject", "reason": "primary database"}`))
+	}))
+	defer server.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	caPath := filepath.Join(t.TempDir(), "service-ca.crt")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "")
+	pool.Annotations = map[string]string{ctrlcommon.PreUpdateHookURLAnnotationKey: server.URL}
+	hooks, err := getPreUpdateHooks(filepath.Join(t.TempDir(), "missing"), caPath, pool)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+
+	req := &PreUpdateHookRequest{Node: "node", Pool: "worker", CurrentConfig: "rendered-worker-0", DesiredConfig: "rendered-worker-1", RebootRequired: true}
+	hook, resp, err := decidePreUpdate(hooks, req, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, hook)
+	assert.Equal(t, PreUpdateHookReject, resp.Decision)
+	assert.Equal(t, "primary database", resp.Reason)
+	assert.Equal(t, *req, got)
+
+	_, _, err = decidePreUpdate([]preUpdateHook{newHTTPPreUpdateHook(failing.URL, newPreUpdateHookHTTPClient(""))}, req, time.Now())
+	assert.ErrorContains(t, err, "503")
+
+	// The hook's certificate is not trusted without the CA.
+	untrusted, err := getPreUpdateHooks(filepath.Join(t.TempDir(), "missing"), filepath.Join(t.TempDir(), "missing.crt"), pool)
+	require.NoError(t, err)
+	_, _, err = decidePreUpdate(untrusted, req, time.Now())
+	assert.ErrorContains(t, err, "certificate")
+}
+
+// fakeConfigDriftMonitor tracks whether it was started.
+type fakeConfigDriftMonitor struct {
+	mu      sync.Mutex
+	running bool
+	done    chan struct{}
+}
+
+func (m *fakeConfigDriftMonitor) Start(ConfigDriftMonitorOpts) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = true
+	return nil
+}
+
+func (m *fakeConfigDriftMonitor) Done() <-chan struct{} { return m.done }
+
+func (m *fakeConfigDriftMonitor) IsRunning() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running
+}
+
+func (m *fakeConfigDriftMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = false
+}
+
+// fakeNodeWriter records the annotations set on the node.
+type fakeNodeWriter struct {
+	NodeWriter
+	annotations map[string]string
+}
+
+func (nw *fakeNodeWriter) SetAnnotations(annos map[string]string) (*corev1.Node, error) {
+	maps.Copy(nw.annotations, annos)
+	return &corev1.Node{}, nil
+}
+
+func (nw *fakeNodeWriter) Eventf(_, _, _ string, _ ...interface{}) {}
+
+func TestRestoreStateForPreUpdateHookDelay(t *testing.T) {
+	dir := t.TempDir()
+	currentConfigPath := filepath.Join(dir, "currentconfig")
+	mcJSON, err := json.Marshal(helpers.NewMachineConfig("rendered-worker-0", nil, "", nil))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(currentConfigPath, mcJSON, 0o644))
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	exitCh := make(chan error, 1)
+	monitor := &fakeConfigDriftMonitor{done: make(chan struct{})}
+	nodeWriter := &fakeNodeWriter{annotations: map[string]string{}}
+	dn := &Daemon{
+		configDriftMonitor: monitor,
+		nodeWriter:         nodeWriter,
+		currentConfigPath:  currentConfigPath,
+		currentImagePath:   filepath.Join(dir, "currentimage"),
+		exitCh:             exitCh,
+		stopCh:             stopCh,
+	}
+
+	// The monitor is stopped before the update and the node marked Working.
+	dn.restoreStateForPreUpdateHookDelay(constants.MachineConfigDaemonStateDone)
+
+	assert.Equal(t, constants.MachineConfigDaemonStateDone, nodeWriter.annotations[constants.MachineConfigDaemonStateAnnotationKey])
+	assert.True(t, monitor.IsRunning(), "config drift monitor is not running while the update is delayed")
+	assert.Empty(t, exitCh)
+}
@@ -215,60 +215,73 @@ func (dn *Daemon) performPostConfigChangeNodeDisruptionAction(postConfigChangeAc
 			}
 			logSystem("Node has Desired Config %s, skipping reboot", configName)
 
-		case opv1.RestartStatusAction:
-			serviceName := string(action.Restart.ServiceName)
-
-			if err := restartService(serviceName); err != nil {
-				// On RHEL nodes, this service is not available and will error out.
-				// In those cases, directly run the command instead of using the service
-				if serviceName == constants.UpdateCATrustServiceName {
-					logSystem("Error executing %s unit, falling back to command", serviceName)
-					cmd := exec.Command(constants.UpdateCATrustCommand)
-					var stderr bytes.Buffer
-					cmd.Stdout = os.Stdout
-					cmd.Stderr = &stderr
-					if err := cmd.Run(); err != nil {
-						if dn.nodeWriter != nil {
-							dn.nodeWriter.Eventf(corev1.EventTypeWarning, "FailedServiceRestart", fmt.Sprintf("Restarting %s service failed. Error: %v", serviceName, err))
-						}
-						return fmt.Errorf("error running %s: %s: %w", constants.UpdateCATrustCommand, stderr.String(), err)
-					}
-				} else {
+		default:
+			if err := dn.performNodeDisruptionServiceAction(action); err != nil {
+				return err
+			}
+		}
+	}
+
+	// We are here, which means a reboot was not needed to apply the configuration.
+	return dn.finishRebootlessUpdate()
+}
+
+// performNodeDisruptionServiceAction executes a node disruption policy action
+// that restarts or reloads a service. Reboot, drain and none actions are left
+// to the caller.
+func (dn *Daemon) performNodeDisruptionServiceAction(action opv1.NodeDisruptionPolicyStatusAction) error {
+	switch action.Type {
+	case opv1.RestartStatusAction:
+		serviceName := string(action.Restart.ServiceName)
+
+		if err := restartService(serviceName); err != nil {
+			// On RHEL nodes, this service is not available and will error out.
+			// In those cases, directly run the command instead of using the service
+			if serviceName == constants.UpdateCATrustServiceName {
+				logSystem("Error executing %s unit, falling back to command", serviceName)
+				cmd := exec.Command(constants.UpdateCATrustCommand)
+				var stderr bytes.Buffer
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = &stderr
+				if err := cmd.Run(); err != nil {
 					if dn.nodeWriter != nil {
 						dn.nodeWriter.Eventf(corev1.EventTypeWarning, "FailedServiceRestart", fmt.Sprintf("Restarting %s service failed. Error: %v", serviceName, err))
 					}
-					return fmt.Errorf("could not apply update: restarting %s service failed. Error: %w", serviceName, err)
+					return fmt.Errorf("error running %s: %s: %w", constants.UpdateCATrustCommand, stderr.String(), err)
 				}
+			} else {
+				if dn.nodeWriter != nil {
+					dn.nodeWriter.Eventf(corev1.EventTypeWarning, "FailedServiceRestart", fmt.Sprintf("Restarting %s service failed. Error: %v", serviceName, err))
+				}
+				return fmt.Errorf("could not apply update: restarting %s service failed. Error: %w", serviceName, err)
 			}
-			// TODO: Add a new MCN Condition to the API for service restarts?
-			if dn.nodeWriter != nil {
-				dn.nodeWriter.Eventf(corev1.EventTypeNormal, "ServiceRestart", "Config changes do not require reboot. Service %s was restarted.", serviceName)
-			}
-			logSystem("%s service restarted successfully!", serviceName)
+		}
+		// TODO: Add a new MCN Condition to the API for service restarts?
+		if dn.nodeWriter != nil {
+			dn.nodeWriter.Eventf(corev1.EventTypeNormal, "ServiceRestart", "Config changes do not require reboot. Service %s was restarted.", serviceName)
+		}
+		logSystem("%s service restarted successfully!", serviceName)
 
-		case opv1.ReloadStatusAction:
-			// Execute a generic service reload defined by the action object
-			serviceName := string(action.Reload.ServiceName)
-			if err := dn.executeReloadServiceNodeDisruptionAction(serviceName, reloadService(serviceName)); err != nil {
-				return err
-			}
+	case opv1.ReloadStatusAction:
+		// Execute a generic service reload defined by the action object
+		serviceName := string(action.Reload.ServiceName)
+		if err := dn.executeReloadServiceNodeDisruptionAction(serviceName, reloadService(serviceName)); err != nil {
+			return err
+		}
 
-		case opv1.SpecialStatusAction:
-			// The special action type requires a CRIO reload
-			if err := dn.executeReloadServiceNodeDisruptionAction(constants.CRIOServiceName, reloadService(constants.CRIOServiceName)); err != nil {
-				return err
-			}
+	case opv1.SpecialStatusAction:
+		// The special action type requires a CRIO reload
+		if err := dn.executeReloadServiceNodeDisruptionAction(constants.CRIOServiceName, reloadService(constants.CRIOServiceName)); err != nil {
+			return err
+		}
 
-		case opv1.DaemonReloadStatusAction:
-			// Execute daemon-reload
-			if err := dn.executeReloadServiceNodeDisruptionAction(constants.DaemonReloadCommand, reloadDaemon()); err != nil {
-				return err
-			}
+	case opv1.DaemonReloadStatusAction:
+		// Execute daemon-reload
+		if err := dn.executeReloadServiceNodeDisruptionAction(constants.DaemonReloadCommand, reloadDaemon()); err != nil {
+			return err
 		}
 	}
-
-	// We are here, which means a reboot was not needed to apply the configuration.
-	return dn.finishRebootlessUpdate()
+	return nil
 }
 
 // performPostConfigChangeAction takes action based on what postConfigChangeAction has been asked.
@@ -737,13 +750,40 @@ func calculatePostConfigChangeAction(diff *machineConfigDiff, diffFileSet []stri
 		return []string{postConfigChangeActionReboot}, nil
 	}
 
+	return calculatePostConfigChangeActionFromDiff(diff, diffFileSet), nil
+}
+
+// calculatePostConfigChangeActionFromDiff computes the post config change actions for a
+// machineConfigDiff, without taking the force file into account.
+func calculatePostConfigChangeActionFromDiff(diff *machineConfigDiff, diffFileSet []string) []string {
 	if diff.osUpdate || diff.kargs || diff.fips || diff.units || diff.kernelType || diff.extensions {
 		// must reboot
-		return []string{postConfigChangeActionReboot}, nil
+		return []string{postConfigChangeActionReboot}
+	}
+
+	// Calculate actions based on file, unit and ssh diffs
+	return calculatePostConfigChangeActionFromMCDiffs(diffFileSet)
+}
+
+// calculatePostConfigChangeNodeDisruptionActionFromDiff computes the node disruption actions for a
+// machineConfigDiff under the given cluster policies, without taking the force file into account.
+func calculatePostConfigChangeNodeDisruptionActionFromDiff(diff *machineConfigDiff, diffFileSet, diffUnitSet []string, clusterPolicies opv1.NodeDisruptionPolicyClusterStatus) []opv1.NodeDisruptionPolicyStatusAction {
+	if diff.osUpdate || diff.kargs || diff.fips || diff.kernelType || diff.extensions {
+		// must reboot
+		return []opv1.NodeDisruptionPolicyStatusAction{{
+			Type: opv1.RebootStatusAction,
+		}}
+	}
+	if !diff.files && !diff.units && !diff.passwd {
+		// This is a diff which requires no actions
+		klog.Infof("No changes in files, units or SSH keys, no NodeDisruptionPolicies are in effect")
+		return []opv1.NodeDisruptionPolicyStatusAction{{
+			Type: opv1.NoneStatusAction,
+		}}
 	}
 
 	// Calculate actions based on file, unit and ssh diffs
-	return calculatePostConfigChangeActionFromMCDiffs(diffFileSet), nil
+	return calculatePostConfigChangeNodeDisruptionActionFromMCDiffs(diff.passwd, diffFileSet, diffUnitSet, clusterPolicies)
 }
 
 // calculatePostConfigChangeNodeDisruptionAction takes action based on the cluster's Node disruption policies.
@@ -788,22 +828,7 @@ func (dn *Daemon) calculatePostConfigChangeNodeDisruptionAction(diff *machineCon
 		}}, nil
 	}
 
-	if diff.osUpdate || diff.kargs || diff.fips || diff.kernelType || diff.extensions {
-		// must reboot
-		return []opv1.NodeDisruptionPolicyStatusAction{{
-			Type: opv1.RebootStatusAction,
-		}}, nil
-	}
-	if !diff.files && !diff.units && !diff.passwd {
-		// This is a diff which requires no actions
-		klog.Infof("No changes in files, units or SSH keys, no NodeDisruptionPolicies are in effect")
-		return []opv1.NodeDisruptionPolicyStatusAction{{
-			Type: opv1.NoneStatusAction,
-		}}, nil
-	}
-
-	// Calculate actions based on file, unit and ssh diffs
-	nodeDisruptionActions := calculatePostConfigChangeNodeDisruptionActionFromMCDiffs(diff.passwd, diffFileSet, diffUnitSet, mcop.Status.NodeDisruptionPolicyStatus.ClusterPolicies)
+	nodeDisruptionActions := calculatePostConfigChangeNodeDisruptionActionFromDiff(diff, diffFileSet, diffUnitSet, mcop.Status.NodeDisruptionPolicyStatus.ClusterPolicies)
 
 	// Print out node disruption actions for debug purposes
 	klog.Infof("Calculated node disruption actions:")
@@ -928,6 +953,9 @@ func (dn *Daemon) update(oldConfig, newConfig *mcfgv1.MachineConfig, skipCertifi
 		}()
 	}
 
+	// The state of the node before it was marked Working, restored if a
+	// pre-update hook delays the update before anything changed on the node.
+	var stateBeforeUpdate string
 	if dn.nodeWriter != nil {
 		// Refetch node from lister to get fresh state before checking guard.
 		// This prevents overwriting Degraded/Unreconcilable states that were just set.
@@ -943,6 +971,7 @@ func (dn *Daemon) update(oldConfig, newConfig *mcfgv1.MachineConfig, skipCertifi
 			if err := dn.nodeWriter.SetWorking(); err != nil {
 				return fmt.Errorf("error setting node's state to Working: %w", err)
 			}
+			stateBeforeUpdate = state
 		}
 	}
 
@@ -1088,6 +1117,20 @@ func (dn *Daemon) update(oldConfig, newConfig *mcfgv1.MachineConfig, skipCertifi
 	if err != nil {
 		klog.Errorf("Error making MCN spec for Update Compatible: %v", err)
 	}
+
+	// Pre-update hooks cannot be configured during firstboot either, and get
+	// the chance to hand off workloads before the node is drained.
+	if !firstBoot {
+		reboot := apihelpers.CheckNodeDisruptionActionsForTargetActions(nodeDisruptionActions, opv1.RebootStatusAction)
+		if err := dn.runPreUpdateHooks(pool, oldConfigName, newConfigName, drain, reboot); err != nil {
+			var delayErr *preUpdateHookDelayErr
+			if errors.As(err, &delayErr) {
+				dn.restoreStateForPreUpdateHookDelay(stateBeforeUpdate)
+			}
+			return err
+		}
+	}
+
 	if drain {
 		if err := dn.performDrain(); err != nil {
 			return err
@@ -2512,12 +2555,7 @@ func (dn *Daemon) updateSSHKeys(newUsers, oldUsers []ign3types.PasswdUser) error
 	// we're also appending all keys for any user to core, so for now
 	// we pass this to atomicallyWriteSSHKeys to write.
 	// we know these users are "core" ones also cause this slice went through Reconcilable
-	var concatSSHKeys string
-	for _, u := range newUsers {
-		for _, k := range u.SSHAuthorizedKeys {
-			concatSSHKeys = concatSSHKeys + string(k) + "\n"
-		}
-	}
+	concatSSHKeys := concatSSHKeys(newUsers)
 
 	authKeyPath := constants.RHCOS8SSHKeyPath
 
@@ -2546,6 +2584,18 @@ func (dn *Daemon) updateSSHKeys(newUsers, oldUsers []ign3types.PasswdUser) error
 	return nil
 }
 
+// concatSSHKeys returns the authorized keys file contents holding the SSH keys
+// of all the given users.
+func concatSSHKeys(users []ign3types.PasswdUser) string {
+	var keys string
+	for _, u := range users {
+		for _, k := range u.SSHAuthorizedKeys {
+			keys = keys + string(k) + "\n"
+		}
+	}
+	return keys
+}
+
 func deconfigureAbsentUsers(newUsers, oldUsers []ign3types.PasswdUser) {
 	for _, oldUser := range oldUsers {
 		if !isUserPresent(oldUser, newUsers) {
@@ -3292,6 +3342,11 @@ func (dn *Daemon) reportMachineNodeDegradeStatus(err error, pool string) {
 	if dn.node == nil {
 		return
 	}
+	// An update delayed by a pre-update hook has not failed.
+	var delayErr *preUpdateHookDelayErr
+	if errors.As(err, &delayErr) {
+		return
+	}
 	condition := &upgrademonitor.Condition{
 		State:  mcfgv1.MachineConfigNodeNodeDegraded,
 		Reason: string(mcfgv1.MachineConfigNodeNodeDegraded),
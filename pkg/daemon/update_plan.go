@@ -0,0 +1,302 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	features "github.com/openshift/api/features"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/machine-config-operator/pkg/apihelpers"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/pkg/helpers"
+	"github.com/openshift/machine-config-operator/pkg/upgrademonitor"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// UpdatePlan describes what the MCD would do to move a node from one rendered
+// MachineConfig to another. It is computed without touching the node, so that
+// it can be previewed before the update is rolled out.
+type UpdatePlan struct {
+	CurrentConfig string `json:"currentConfig"`
+	DesiredConfig string `json:"desiredConfig"`
+
+	// Reconcilable is false if the MCD would refuse the update, in which case
+	// UnreconcilableReason says why and the rest of the plan is empty.
+	Reconcilable         bool   `json:"reconcilable"`
+	UnreconcilableReason string `json:"unreconcilableReason,omitempty"`
+
+	// OSImageURL is the OS image the node would be rebased to, if it changes.
+	OSImageURL string `json:"osImageURL,omitempty"`
+	// KernelType is the kernel type the node would switch to, if it changes.
+	KernelType string `json:"kernelType,omitempty"`
+	// KernelArguments are the rpm-ostree kargs arguments the MCD would run.
+	KernelArguments   []string `json:"kernelArguments,omitempty"`
+	ExtensionsAdded   []string `json:"extensionsAdded,omitempty"`
+	ExtensionsRemoved []string `json:"extensionsRemoved,omitempty"`
+
+	FilesWritten  []string `json:"filesWritten,omitempty"`
+	FilesRemoved  []string `json:"filesRemoved,omitempty"`
+	UnitsWritten  []string `json:"unitsWritten,omitempty"`
+	UnitsRemoved  []string `json:"unitsRemoved,omitempty"`
+	UnitsEnabled  []string `json:"unitsEnabled,omitempty"`
+	UnitsDisabled []string `json:"unitsDisabled,omitempty"`
+	// UsersUpdated is true if SSH keys or password hashes change.
+	UsersUpdated bool `json:"usersUpdated,omitempty"`
+
+	// PostConfigChangeActions is set instead of NodeDisruptionActions when no
+	// node disruption policies are available, as during firstboot.
+	PostConfigChangeActions []string                                `json:"postConfigChangeActions,omitempty"`
+	NodeDisruptionActions   []opv1.NodeDisruptionPolicyStatusAction `json:"nodeDisruptionActions,omitempty"`
+	DrainRequired           bool                                    `json:"drainRequired"`
+	RebootRequired          bool                                    `json:"rebootRequired"`
+}
+
+// PlanUpdate computes the UpdatePlan for moving from oldConfig to newConfig. It
+// makes the same decisions as update(), but does not depend on or modify the
+// state of the node it runs on. If clusterPolicies is nil, the post config change actions used
+// during firstboot are planned instead of node disruption actions.
+func PlanUpdate(oldConfig, newConfig *mcfgv1.MachineConfig, overrides *opv1.IrreconcilableValidationOverrides, clusterPolicies *opv1.NodeDisruptionPolicyClusterStatus) (*UpdatePlan, error) {
+	oldConfig = canonicalizeEmptyMC(oldConfig)
+	plan := &UpdatePlan{
+		CurrentConfig: oldConfig.GetName(),
+		DesiredConfig: newConfig.GetName(),
+	}
+
+	oldIgnConfig, err := ctrlcommon.ParseAndConvertConfig(oldConfig.Spec.Config.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing old Ignition config failed: %w", err)
+	}
+	newIgnConfig, err := ctrlcommon.ParseAndConvertConfig(newConfig.Spec.Config.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing new Ignition config failed: %w", err)
+	}
+
+	// This is reconcilable() minus the comparison against the FIPS state of
+	// the running system, which the plan must not depend on.
+	if err := ctrlcommon.IsRenderedConfigReconcilable(oldConfig, newConfig, overrides); err != nil {
+		plan.UnreconcilableReason = err.Error()
+		return plan, nil
+	}
+	plan.Reconcilable = true
+
+	diff, err := newMachineConfigDiff(oldConfig, newConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate config diff: %w", err)
+	}
+
+	if diff.osUpdate {
+		plan.OSImageURL = newConfig.Spec.OSImageURL
+		if _, image := extractOCLImageFromMachineConfig(newConfig); image != "" {
+			plan.OSImageURL = image
+		}
+	}
+	if diff.kernelType {
+		plan.KernelType = helpers.CanonicalizeKernelType(newConfig.Spec.KernelType)
+	}
+	if diff.kargs {
+		plan.KernelArguments = generateKargs(oldConfig.Spec.KernelArguments, newConfig.Spec.KernelArguments)
+	}
+	if diff.extensions {
+		oldExtensions := sets.New(oldConfig.Spec.Extensions...)
+		newExtensions := sets.New(newConfig.Spec.Extensions...)
+		plan.ExtensionsAdded = sets.List(newExtensions.Difference(oldExtensions))
+		plan.ExtensionsRemoved = sets.List(oldExtensions.Difference(newExtensions))
+	}
+	plan.UsersUpdated = diff.passwd
+
+	diffFileSet := ctrlcommon.CalculateConfigFileDiffs(&oldIgnConfig, &newIgnConfig)
+	plan.FilesWritten, plan.FilesRemoved = splitFileDiffs(diffFileSet, newIgnConfig)
+
+	unitDiff := ctrlcommon.GetChangedConfigUnitsByType(&oldIgnConfig, &newIgnConfig)
+	var allChangedUnitNames []string
+	for _, unit := range slices.Concat(unitDiff.Added, unitDiff.Updated) {
+		plan.UnitsWritten = append(plan.UnitsWritten, unit.Name)
+		if unit.Enabled != nil {
+			if *unit.Enabled {
+				plan.UnitsEnabled = append(plan.UnitsEnabled, unit.Name)
+			} else {
+				plan.UnitsDisabled = append(plan.UnitsDisabled, unit.Name)
+			}
+		}
+		allChangedUnitNames = append(allChangedUnitNames, unit.Name)
+	}
+	for _, unit := range unitDiff.Removed {
+		plan.UnitsRemoved = append(plan.UnitsRemoved, unit.Name)
+		allChangedUnitNames = append(allChangedUnitNames, unit.Name)
+	}
+	for _, units := range [][]string{plan.UnitsWritten, plan.UnitsRemoved, plan.UnitsEnabled, plan.UnitsDisabled} {
+		sort.Strings(units)
+	}
+
+	if clusterPolicies != nil {
+		plan.NodeDisruptionActions = calculatePostConfigChangeNodeDisruptionActionFromDiff(diff, diffFileSet, allChangedUnitNames, *clusterPolicies)
+		plan.DrainRequired, err = isDrainRequiredForNodeDisruptionActions(plan.NodeDisruptionActions, oldIgnConfig, newIgnConfig)
+		plan.RebootRequired = apihelpers.CheckNodeDisruptionActionsForTargetActions(plan.NodeDisruptionActions, opv1.RebootStatusAction)
+	} else {
+		plan.PostConfigChangeActions = calculatePostConfigChangeActionFromDiff(diff, diffFileSet)
+		plan.DrainRequired, err = isDrainRequired(plan.PostConfigChangeActions, diffFileSet, oldIgnConfig, newIgnConfig)
+		plan.RebootRequired = ctrlcommon.InSlice(postConfigChangeActionReboot, plan.PostConfigChangeActions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not determine whether a drain is required: %w", err)
+	}
+
+	return plan, nil
+}
+
+// splitFileDiffs splits the paths returned by CalculateConfigFileDiffs into the
+// files which are written and the files which are removed.
+func splitFileDiffs(diffFileSet []string, newIgnConfig ign3types.Config) (written, removed []string) {
+	newPaths := sets.New[string]()
+	for _, f := range newIgnConfig.Storage.Files {
+		newPaths.Insert(f.Path)
+	}
+	for _, path := range diffFileSet {
+		if newPaths.Has(path) {
+			written = append(written, path)
+		} else {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(written)
+	sort.Strings(removed)
+	return written, removed
+}
+
+// updatePlanFieldManager is the field manager applying the update plan
+// annotation on MachineConfigNodes.
+const updatePlanFieldManager = "machine-config-daemon-update-plan"
+
+// updatePlanState is what decides which update plan, if any, is published on
+// the node's MachineConfigNode.
+type updatePlanState struct {
+	current string
+	desired string
+	pending string
+}
+
+// reportUpdatePlan publishes the plan for moving the node to its pool's
+// rendered config on the node's MachineConfigNode, as long as the node has not
+// been asked to update yet, e.g. because the pool is paused. Otherwise any
+// previously published plan is removed. The MachineConfigNode is only read and
+// written when the node's current or desired config or the pool's config
+// changed since the last time the plan was published.
+func (dn *Daemon) reportUpdatePlan(node *corev1.Node, poolName string) error {
+	if dn.fgHandler == nil || dn.mcfgClient == nil || poolName == "" {
+		return nil
+	}
+
+	pool, err := dn.mcpLister.Get(poolName)
+	if err != nil {
+		return fmt.Errorf("could not get MachineConfigPool %q: %w", poolName, err)
+	}
+	state := updatePlanState{
+		current: node.Annotations[constants.CurrentMachineConfigAnnotationKey],
+		desired: node.Annotations[constants.DesiredMachineConfigAnnotationKey],
+		pending: pool.Spec.Configuration.Name,
+	}
+	if dn.publishedUpdatePlan != nil && *dn.publishedUpdatePlan == state {
+		return nil
+	}
+	wantPlan := state.pending != "" && state.current != "" && state.current == state.desired && state.pending != state.current
+
+	mcn, err := dn.mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	existing, hasPlan := mcn.Annotations[constants.UpdatePlanAnnotationKey]
+	if !wantPlan {
+		if hasPlan {
+			if err := upgrademonitor.ApplyMachineConfigNodeAnnotation(dn.mcfgClient, node.Name, updatePlanFieldManager, constants.UpdatePlanAnnotationKey, ""); err != nil {
+				return err
+			}
+		}
+		dn.publishedUpdatePlan = &state
+		return nil
+	}
+
+	// The plan only depends on the two configs and the cluster policies, so
+	// don't recompute it if it was published before the daemon restarted.
+	var published UpdatePlan
+	if hasPlan && json.Unmarshal([]byte(existing), &published) == nil &&
+		published.CurrentConfig == state.current && published.DesiredConfig == state.pending {
+		dn.publishedUpdatePlan = &state
+		return nil
+	}
+
+	plan, err := dn.planUpdateFromCluster(state.current, state.pending)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("could not marshal update plan: %w", err)
+	}
+	klog.Infof("Publishing plan for update from %s to %s: drain required: %t, reboot required: %t", state.current, state.pending, plan.DrainRequired, plan.RebootRequired)
+	if err := upgrademonitor.ApplyMachineConfigNodeAnnotation(dn.mcfgClient, node.Name, updatePlanFieldManager, constants.UpdatePlanAnnotationKey, string(out)); err != nil {
+		return err
+	}
+	dn.publishedUpdatePlan = &state
+	return nil
+}
+
+// planUpdateFromCluster computes the UpdatePlan between two rendered configs,
+// using the node disruption policies and irreconcilable overrides currently
+// set on the cluster.
+func (dn *Daemon) planUpdateFromCluster(current, desired string) (*UpdatePlan, error) {
+	oldConfig, err := dn.mcLister.Get(current)
+	if err != nil {
+		return nil, fmt.Errorf("could not get MachineConfig %q: %w", current, err)
+	}
+	newConfig, err := dn.mcLister.Get(desired)
+	if err != nil {
+		return nil, fmt.Errorf("could not get MachineConfig %q: %w", desired, err)
+	}
+
+	mcop, err := dn.mcopLister.Get(ctrlcommon.MCOOperatorKnobsObjectName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get MachineConfiguration %q: %w", ctrlcommon.MCOOperatorKnobsObjectName, err)
+	}
+
+	// As in update(), the overrides only apply with the IrreconcilableMachineConfig feature gate.
+	overrides := &opv1.IrreconcilableValidationOverrides{}
+	if dn.fgHandler.Enabled(features.FeatureGateIrreconcilableMachineConfig) {
+		overrides = &mcop.Spec.IrreconcilableValidationOverrides
+	}
+
+	return PlanUpdate(oldConfig, newConfig, overrides, &mcop.Status.NodeDisruptionPolicyStatus.ClusterPolicies)
+}
+
+// handleMachineConfigPoolUpdate resyncs the node when its pool moves on to a
+// new rendered config, so that the update plan is published before the node
+// controller asks the node to update.
+func (dn *Daemon) handleMachineConfigPoolUpdate(oldObj, newObj interface{}) {
+	oldPool := oldObj.(*mcfgv1.MachineConfigPool)
+	newPool := newObj.(*mcfgv1.MachineConfigPool)
+	if oldPool.Spec.Configuration.Name == newPool.Spec.Configuration.Name {
+		return
+	}
+	node, err := dn.nodeLister.Get(dn.name)
+	if err != nil {
+		return
+	}
+	if pool, err := helpers.GetPrimaryPoolNameForMCN(dn.mcpLister, node); err != nil || pool != newPool.Name {
+		return
+	}
+	// Nodes are cluster scoped, so the node name is its queue key.
+	dn.queue.AddRateLimited(dn.name)
+}
@@ -0,0 +1,253 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	apicfgv1 "github.com/openshift/api/config/v1"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	informers "github.com/openshift/client-go/machineconfiguration/informers/externalversions"
+	mcopfake "github.com/openshift/client-go/operator/clientset/versioned/fake"
+	operatorinformer "github.com/openshift/client-go/operator/informers/externalversions"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+)
+
+func TestPlanUpdate(t *testing.T) {
+	t.Parallel()
+
+	randomFile1 := ctrlcommon.NewIgnFile("/etc/random-reboot-file", "test\n")
+	randomFile2 := ctrlcommon.NewIgnFile("/etc/random-reboot-file", "test 2\n")
+	newFile := ctrlcommon.NewIgnFile("/etc/new-file", "new\n")
+	pullSecret1 := ctrlcommon.NewIgnFile("/var/lib/kubelet/config.json", "kubelet conf 1\n")
+	pullSecret2 := ctrlcommon.NewIgnFile("/var/lib/kubelet/config.json", "kubelet conf 2\n")
+	unit := ign3types.Unit{Name: "foo.service", Contents: ptr.To("[Unit]\n"), Enabled: ptr.To(true)}
+	disabledUnit := ign3types.Unit{Name: "bar.service", Enabled: ptr.To(false)}
+
+	newMC := func(name string, files []ign3types.File, units []ign3types.Unit, extensions []string, fips bool, kargs []string) *mcfgv1.MachineConfig {
+		return helpers.NewMachineConfigExtended(name, nil, nil, files, units, []ign3types.SSHAuthorizedKey{}, extensions, fips, kargs, "default", "dummy://")
+	}
+
+	policies := &opv1.NodeDisruptionPolicyClusterStatus{
+		Files: []opv1.NodeDisruptionPolicyStatusFile{{
+			Path:    "/etc/new-file",
+			Actions: []opv1.NodeDisruptionPolicyStatusAction{{Type: opv1.NoneStatusAction}},
+		}},
+		Units: []opv1.NodeDisruptionPolicyStatusUnit{{
+			Name: "foo.service",
+			Actions: []opv1.NodeDisruptionPolicyStatusAction{{
+				Type:    opv1.RestartStatusAction,
+				Restart: &opv1.RestartService{ServiceName: "foo.service"},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		oldConfig *mcfgv1.MachineConfig
+		newConfig *mcfgv1.MachineConfig
+		policies  *opv1.NodeDisruptionPolicyClusterStatus
+		expected  UpdatePlan
+	}{
+		{
+			name:      "file without policy reboots",
+			oldConfig: newMC("00-test", []ign3types.File{randomFile1}, nil, nil, false, nil),
+			newConfig: newMC("01-test", []ign3types.File{randomFile2, newFile}, nil, nil, false, nil),
+			policies:  policies,
+			expected: UpdatePlan{
+				FilesWritten:          []string{"/etc/new-file", "/etc/random-reboot-file"},
+				NodeDisruptionActions: []opv1.NodeDisruptionPolicyStatusAction{{Type: opv1.RebootStatusAction}},
+				DrainRequired:         true,
+				RebootRequired:        true,
+			},
+		},
+		{
+			name:      "file and unit with policies",
+			oldConfig: newMC("00-test", nil, []ign3types.Unit{disabledUnit}, nil, false, nil),
+			newConfig: newMC("01-test", []ign3types.File{newFile}, []ign3types.Unit{unit}, nil, false, nil),
+			policies:  policies,
+			expected: UpdatePlan{
+				FilesWritten: []string{"/etc/new-file"},
+				UnitsWritten: []string{"foo.service"},
+				UnitsRemoved: []string{"bar.service"},
+				UnitsEnabled: []string{"foo.service"},
+				// The removed unit has no policy.
+				NodeDisruptionActions: []opv1.NodeDisruptionPolicyStatusAction{{Type: opv1.RebootStatusAction}},
+				DrainRequired:         true,
+				RebootRequired:        true,
+			},
+		},
+		{
+			name:      "file removed with policy",
+			oldConfig: newMC("00-test", []ign3types.File{newFile}, nil, nil, false, nil),
+			newConfig: newMC("01-test", nil, nil, nil, false, nil),
+			policies:  policies,
+			expected: UpdatePlan{
+				FilesRemoved:          []string{"/etc/new-file"},
+				NodeDisruptionActions: []opv1.NodeDisruptionPolicyStatusAction{{Type: opv1.NoneStatusAction}},
+			},
+		},
+		{
+			name:      "kernel arguments and extensions",
+			oldConfig: newMC("00-test", nil, nil, []string{"usbguard"}, false, []string{"foo=1"}),
+			newConfig: newMC("01-test", nil, nil, []string{"kernel-devel"}, false, []string{"foo=2"}),
+			policies:  policies,
+			expected: UpdatePlan{
+				KernelArguments:       []string{"--delete=foo=1", "--append=foo=2"},
+				ExtensionsAdded:       []string{"kernel-devel"},
+				ExtensionsRemoved:     []string{"usbguard"},
+				NodeDisruptionActions: []opv1.NodeDisruptionPolicyStatusAction{{Type: opv1.RebootStatusAction}},
+				DrainRequired:         true,
+				RebootRequired:        true,
+			},
+		},
+		{
+			name:      "firstboot actions without policies",
+			oldConfig: newMC("00-test", []ign3types.File{pullSecret1}, nil, nil, false, nil),
+			newConfig: newMC("01-test", []ign3types.File{pullSecret2}, nil, nil, false, nil),
+			expected: UpdatePlan{
+				FilesWritten:            []string{"/var/lib/kubelet/config.json"},
+				PostConfigChangeActions: []string{postConfigChangeActionNone},
+			},
+		},
+		{
+			name:      "FIPS change is unreconcilable",
+			oldConfig: newMC("00-test", nil, nil, nil, false, nil),
+			newConfig: newMC("01-test", nil, nil, nil, true, nil),
+			policies:  policies,
+			expected: UpdatePlan{
+				UnreconcilableReason: "detected change to FIPS flag",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := PlanUpdate(test.oldConfig, test.newConfig, &opv1.IrreconcilableValidationOverrides{}, test.policies)
+			require.NoError(t, err)
+
+			test.expected.CurrentConfig = test.oldConfig.Name
+			test.expected.DesiredConfig = test.newConfig.Name
+			test.expected.Reconcilable = test.expected.UnreconcilableReason == ""
+			if !test.expected.Reconcilable {
+				assert.Contains(t, plan.UnreconcilableReason, test.expected.UnreconcilableReason)
+				test.expected.UnreconcilableReason = plan.UnreconcilableReason
+			}
+			assert.Equal(t, test.expected, *plan)
+		})
+	}
+}
+
+func TestReportUpdatePlan(t *testing.T) {
+	oldConfig := helpers.NewMachineConfig("rendered-worker-0", nil, "dummy://", nil)
+	newConfig := helpers.NewMachineConfig("rendered-worker-1", nil, "dummy://", []ign3types.File{ctrlcommon.NewIgnFile("/etc/random-reboot-file", "test\n")})
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, newConfig.Name)
+	pool.Spec.Paused = true
+	mcop := &opv1.MachineConfiguration{ObjectMeta: metav1.ObjectMeta{Name: ctrlcommon.MCOOperatorKnobsObjectName}}
+	mcn := helpers.NewMachineConfigNode("node-0", pool.Name, oldConfig.Name, "", true, false)
+
+	client := fake.NewClientset(mcn)
+	i := informers.NewSharedInformerFactory(client, noResyncPeriodFunc())
+	oi := operatorinformer.NewSharedInformerFactory(mcopfake.NewSimpleClientset(), noResyncPeriodFunc())
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigs().Informer().GetIndexer().Add(oldConfig))
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigs().Informer().GetIndexer().Add(newConfig))
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigPools().Informer().GetIndexer().Add(pool))
+	require.NoError(t, oi.Operator().V1().MachineConfigurations().Informer().GetIndexer().Add(mcop))
+
+	dn := &Daemon{
+		mcfgClient: client,
+		mcLister:   i.Machineconfiguration().V1().MachineConfigs().Lister(),
+		mcpLister:  i.Machineconfiguration().V1().MachineConfigPools().Lister(),
+		mcopLister: oi.Operator().V1().MachineConfigurations().Lister(),
+		fgHandler:  ctrlcommon.NewFeatureGatesHardcodedHandler([]apicfgv1.FeatureGateName{}, []apicfgv1.FeatureGateName{}),
+	}
+
+	getPlan := func() (*UpdatePlan, bool) {
+		updated, err := client.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), mcn.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		value, ok := updated.Annotations[constants.UpdatePlanAnnotationKey]
+		if !ok {
+			return nil, false
+		}
+		plan := &UpdatePlan{}
+		require.NoError(t, json.Unmarshal([]byte(value), plan))
+		return plan, true
+	}
+
+	// The node is on the previous config and has not been asked to update.
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0", Annotations: map[string]string{
+		constants.CurrentMachineConfigAnnotationKey: oldConfig.Name,
+		constants.DesiredMachineConfigAnnotationKey: oldConfig.Name,
+	}}}
+	require.NoError(t, dn.reportUpdatePlan(node, pool.Name))
+	plan, ok := getPlan()
+	require.True(t, ok)
+	assert.Equal(t, oldConfig.Name, plan.CurrentConfig)
+	assert.Equal(t, newConfig.Name, plan.DesiredConfig)
+	assert.Equal(t, []string{"/etc/random-reboot-file"}, plan.FilesWritten)
+	assert.True(t, plan.RebootRequired)
+
+	// The MachineConfigNode is left alone until something changes.
+	client.ClearActions()
+	require.NoError(t, dn.reportUpdatePlan(node, pool.Name))
+	assert.Empty(t, client.Actions())
+
+	// Once the node is updating, the plan is removed.
+	node.Annotations[constants.DesiredMachineConfigAnnotationKey] = newConfig.Name
+	require.NoError(t, dn.reportUpdatePlan(node, pool.Name))
+	_, ok = getPlan()
+	assert.False(t, ok)
+}
+
+func TestHandleMachineConfigPoolUpdate(t *testing.T) {
+	worker := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "rendered-worker-0")
+	master := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "rendered-master-0")
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0", Labels: map[string]string{"node-role/worker": ""}}}
+
+	i := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), noResyncPeriodFunc())
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigPools().Informer().GetIndexer().Add(worker))
+	require.NoError(t, i.Machineconfiguration().V1().MachineConfigPools().Informer().GetIndexer().Add(master))
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, nodeIndexer.Add(node))
+
+	dn := &Daemon{
+		name:       node.Name,
+		nodeLister: corev1listers.NewNodeLister(nodeIndexer),
+		mcpLister:  i.Machineconfiguration().V1().MachineConfigPools().Lister(),
+		queue:      workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]()),
+	}
+	defer dn.queue.ShutDown()
+
+	updated := func(pool *mcfgv1.MachineConfigPool, config string) *mcfgv1.MachineConfigPool {
+		pool = pool.DeepCopy()
+		pool.Spec.Configuration.Name = config
+		return pool
+	}
+
+	// Other pools moving on to a new config do not concern the node.
+	dn.handleMachineConfigPoolUpdate(master, updated(master, "rendered-master-1"))
+	assert.Equal(t, 0, dn.queue.NumRequeues(node.Name))
+
+	// Neither do changes of its own pool which keep the config.
+	dn.handleMachineConfigPoolUpdate(worker, updated(worker, worker.Spec.Configuration.Name))
+	assert.Equal(t, 0, dn.queue.NumRequeues(node.Name))
+
+	dn.handleMachineConfigPoolUpdate(worker, updated(worker, "rendered-worker-1"))
+	assert.Equal(t, 1, dn.queue.NumRequeues(node.Name))
+}
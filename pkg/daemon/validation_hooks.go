@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/unit"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// How long a single validation hook may run.
+	validationHookTimeout = 5 * time.Minute
+	// How much of the output of a failed validation hook is reported.
+	maxValidationHookOutput = 2048
+)
+
+// validationHookError is the failure of a validation hook, carrying its
+// output so that it can be acted on from the node's degraded reason.
+type validationHookError struct {
+	hook   string
+	err    error
+	output string
+}
+
+func (e *validationHookError) Error() string {
+	if e.output == "" {
+		return fmt.Sprintf("validation hook %s failed: %v", e.hook, e.err)
+	}
+	return fmt.Sprintf("validation hook %s failed: %v: %s", e.hook, e.err, e.output)
+}
+
+func (e *validationHookError) Unwrap() error {
+	return e.err
+}
+
+// truncateHookOutput keeps the end of the output, where the reason a hook
+// failed usually is.
+func truncateHookOutput(out []byte) string {
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxValidationHookOutput {
+		output = "..." + output[len(output)-maxValidationHookOutput:]
+	}
+	return output
+}
+
+// getExecutableHooks returns the executable files in a hooks dir, in lexical
+// order. A missing dir has no hooks.
+func getExecutableHooks(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list hooks in %s: %w", dir, err)
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// Follow symlinks, as hooks may link to a shared script.
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not stat hook %s: %w", path, err)
+		}
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0o111 == 0 {
+			klog.V(4).Infof("Skipping %s: not an executable file", path)
+			continue
+		}
+		scripts = append(scripts, path)
+	}
+	return scripts, nil
+}
+
+// runValidationHookScript runs a validation hook script, failing it if it
+// exits unsuccessfully or takes longer than timeout.
+func runValidationHookScript(path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	// Don't wait on children of a killed hook which still hold its output.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	if err != nil {
+		return &validationHookError{hook: path, err: err, output: truncateHookOutput(out)}
+	}
+	return nil
+}
+
+// getValidationHookUnits returns the names of the systemd units of a
+// MachineConfig which are marked as validation hooks.
+func getValidationHookUnits(mc *mcfgv1.MachineConfig) ([]string, error) {
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Ignition for validation hooks: %w", err)
+	}
+
+	var units []string
+	for _, u := range ignConfig.Systemd.Units {
+		if u.Contents == nil || (u.Mask != nil && *u.Mask) {
+			continue
+		}
+		opts, err := unit.Deserialize(strings.NewReader(*u.Contents))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse unit %s: %w", u.Name, err)
+		}
+		for _, opt := range opts {
+			if opt.Section == "Unit" && opt.Name == constants.ValidationHookUnitOption && opt.Value == "yes" {
+				units = append(units, u.Name)
+				break
+			}
+		}
+	}
+	return units, nil
+}
+
+// runValidationHookUnit starts a validation hook unit and waits for it, which
+// systemctl does for oneshot units. The unit's journal is reported if it fails.
+func (dn *Daemon) runValidationHookUnit(name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "systemctl", "start", name).CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if journal, journalErr := dn.cmdRunner.RunGetOut("journalctl", "--no-pager", "-o", "cat", "-n", "50", "-b", "-u", name); journalErr == nil {
+		out = append(out, journal...)
+	}
+	return &validationHookError{hook: name, err: err, output: truncateHookOutput(out)}
+}
+
+// runValidationHooks runs the validation hook scripts shipped in
+// constants.ValidationHooksDir, then the validation hook units of the given
+// MachineConfig, stopping at the first failure.
+func (dn *Daemon) runValidationHooks(mc *mcfgv1.MachineConfig) error {
+	scripts, err := getExecutableHooks(constants.ValidationHooksDir)
+	if err != nil {
+		return err
+	}
+	units, err := getValidationHookUnits(mc)
+	if err != nil {
+		return err
+	}
+	if len(scripts) == 0 && len(units) == 0 {
+		return nil
+	}
+
+	logSystem("Running %d validation hooks for config %s", len(scripts)+len(units), mc.Name)
+
+	run := func(hook string, runHook func() error) error {
+		klog.Infof("Running validation hook %s", hook)
+		if err := runHook(); err != nil {
+			if dn.nodeWriter != nil {
+				dn.nodeWriter.Eventf(corev1.EventTypeWarning, "ValidationHookFailed", err.Error())
+			}
+			return err
+		}
+		return nil
+	}
+	for _, script := range scripts {
+		if err := run(script, func() error { return runValidationHookScript(script, validationHookTimeout) }); err != nil {
+			return err
+		}
+	}
+	for _, name := range units {
+		if err := run(name, func() error { return dn.runValidationHookUnit(name, validationHookTimeout) }); err != nil {
+			return err
+		}
+	}
+
+	logSystem("Validation hooks passed for config %s", mc.Name)
+	return nil
+}
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidationHookScripts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeHook := func(name, contents string, mode os.FileMode) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), mode))
+		return path
+	}
+	pass := writeHook("10-pass", "#!/bin/sh\necho checking\n", 0o755)
+	fail := writeHook("20-fail", "#!/bin/sh\necho no GPU found\nexit 1\n", 0o755)
+	slow := writeHook("30-slow", "#!/bin/sh\nsleep 10\n", 0o755)
+	writeHook("40-not-executable", "#!/bin/sh\nexit 1\n", 0o644)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "50-dir"), 0o755))
+
+	scripts, err := getExecutableHooks(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pass, fail, slow}, scripts)
+
+	scripts, err = getExecutableHooks(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, scripts)
+
+	assert.NoError(t, runValidationHookScript(pass, time.Minute))
+
+	err = runValidationHookScript(fail, time.Minute)
+	var hookErr *validationHookError
+	require.True(t, errors.As(err, &hookErr), "expected validation hook error, got: %v", err)
+	assert.Equal(t, "no GPU found", hookErr.output)
+	assert.Contains(t, err.Error(), "validation hook "+fail+" failed")
+
+	err = runValidationHookScript(slow, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestGetValidationHookUnits(t *testing.T) {
+	t.Parallel()
+
+	units := []ign3types.Unit{
+		{Name: "gpu-check.service", Contents: ptr.To("[Unit]\nDescription=Check GPU\nX-MachineConfigValidationHook=yes\n\n[Service]\nType=oneshot\nExecStart=/usr/bin/true\n")},
+		{Name: "masked-check.service", Contents: ptr.To("[Unit]\nX-MachineConfigValidationHook=yes\n"), Mask: ptr.To(true)},
+		{Name: "service-section.service", Contents: ptr.To("[Service]\nX-MachineConfigValidationHook=yes\n")},
+		{Name: "plain.service", Contents: ptr.To("[Unit]\nDescription=Plain\n")},
+		{Name: "enabled-only.service", Enabled: ptr.To(true)},
+	}
+	mc := helpers.NewMachineConfigExtended("rendered-worker-0", nil, nil, nil, units, []ign3types.SSHAuthorizedKey{}, nil, false, nil, "", "dummy://")
+
+	hooks, err := getValidationHookUnits(mc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu-check.service"}, hooks)
+}
+
+func TestTruncateHookOutput(t *testing.T) {
+	t.Parallel()
+
+	long := make([]byte, maxValidationHookOutput*2)
+	for i := range long {
+		long[i] = 'a'
+	}
+	long[len(long)-1] = 'z'
+
+	truncated := truncateHookOutput(long)
+	assert.Len(t, truncated, maxValidationHookOutput+3)
+	assert.Equal(t, byte('z'), truncated[len(truncated)-1])
+	assert.Equal(t, "short", truncateHookOutput([]byte("  short\n")))
+}
@@ -0,0 +1,130 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	opv1 "github.com/openshift/api/operator/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/server"
+)
+
+const (
+	// how long the bootstrap token of a pool embedded in its stub user-data
+	// is valid for
+	poolBootstrapTokenValidity = 48 * time.Hour
+	// how long before it expires the bootstrap token of a pool is replaced,
+	// leaving machines created from the previous user-data time to boot
+	poolBootstrapTokenRefresh = 24 * time.Hour
+
+	// name of the role and role binding in manifests/machineconfigserver
+	machineConfigServerRoleName = "machine-config-server"
+)
+
+// getNodeIdentity returns the methods machines must prove their identity with
+// before the machine-config-server serves them a config, as configured on the
+// cluster MachineConfiguration. Unknown methods are ignored.
+func getNodeIdentity(mcop *opv1.MachineConfiguration) []string {
+	if mcop == nil {
+		return nil
+	}
+	var methods []string
+	for _, method := range strings.Split(mcop.Annotations[ctrlcommon.NodeIdentityAnnotationKey], ",") {
+		method = strings.TrimSpace(method)
+		switch method {
+		case "":
+		case server.NodeIdentityBootstrapToken, server.NodeIdentityClientCertificate:
+			if !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		default:
+			klog.Warningf("Ignoring unknown node identity %q in %s annotation", method, ctrlcommon.NodeIdentityAnnotationKey)
+		}
+	}
+	return methods
+}
+
+// syncPoolBootstrapToken returns the bootstrap token machines of the pool
+// fetch their config with, minting a new one when there is none or it is about
+// to expire, and deletes the expired ones. If bootstrap tokens are not enabled
+// it deletes all the tokens of the pool and returns an empty token.
+func (optr *Operator) syncPoolBootstrapToken(pool string, enabled bool) (string, error) {
+	secrets, err := optr.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{server.BootstrapTokenPoolLabelKey: pool}).String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not list bootstrap tokens of pool %s: %w", pool, err)
+	}
+
+	now := time.Now()
+	var token string
+	var latest time.Time
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		secretToken, expires, err := server.GetBootstrapToken(secret)
+		if !enabled || err != nil || now.After(expires) {
+			if err := optr.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("could not delete bootstrap token %s: %w", secret.Name, err)
+			}
+			continue
+		}
+		if expires.After(latest) {
+			token, latest = secretToken, expires
+		}
+	}
+	if !enabled || latest.Sub(now) > poolBootstrapTokenRefresh {
+		return token, nil
+	}
+
+	secret, token, err := server.NewBootstrapTokenSecret(pool, "", poolBootstrapTokenValidity)
+	if err != nil {
+		return "", err
+	}
+	secret.Annotations = map[string]string{"openshift.io/owning-component": "Machine Config Operator"}
+	if _, err := optr.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("could not create bootstrap token of pool %s: %w", pool, err)
+	}
+	klog.Infof("Minted bootstrap token %s for pool %s", secret.Name, pool)
+	return token, nil
+}
+
+// withBootstrapToken returns the pointer config with the bootstrap token sent
+// when fetching the config it points to.
+func withBootstrapToken(pointerConfigData []byte, token string) ([]byte, error) {
+	var pointerConfig ign3types.Config
+	if err := json.Unmarshal(pointerConfigData, &pointerConfig); err != nil {
+		return nil, fmt.Errorf("could not parse pointer config: %w", err)
+	}
+	for i := range pointerConfig.Ignition.Config.Merge {
+		pointerConfig.Ignition.Config.Merge[i].HTTPHeaders = append(pointerConfig.Ignition.Config.Merge[i].HTTPHeaders, ign3types.HTTPHeader{
+			Name:  "Authorization",
+			Value: ptr.To("Bearer " + token),
+		})
+	}
+	return json.Marshal(pointerConfig)
+}
+
+// deleteMachineConfigServerRole deletes the role and role binding allowing the
+// machine-config-server to read and consume bootstrap tokens.
+func (optr *Operator) deleteMachineConfigServerRole() error {
+	err := optr.kubeClient.RbacV1().RoleBindings(optr.namespace).Delete(context.TODO(), machineConfigServerRoleName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete machine-config-server role binding: %w", err)
+	}
+	err = optr.kubeClient.RbacV1().Roles(optr.namespace).Delete(context.TODO(), machineConfigServerRoleName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete machine-config-server role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/server"
+)
+
+func TestGetNodeIdentity(t *testing.T) {
+	newMCOP := func(annotation string) *opv1.MachineConfiguration {
+		return &opv1.MachineConfiguration{ObjectMeta: metav1.ObjectMeta{
+			Name:        ctrlcommon.MCOOperatorKnobsObjectName,
+			Annotations: map[string]string{ctrlcommon.NodeIdentityAnnotationKey: annotation},
+		}}
+	}
+
+	assert.Nil(t, getNodeIdentity(nil))
+	assert.Nil(t, getNodeIdentity(&opv1.MachineConfiguration{}))
+	assert.Nil(t, getNodeIdentity(newMCOP("")))
+	assert.Equal(t, []string{"bootstrap-token"}, getNodeIdentity(newMCOP("bootstrap-token")))
+	assert.Equal(t, []string{"client-certificate", "bootstrap-token"}, getNodeIdentity(newMCOP(" client-certificate, bootstrap-token,client-certificate")))
+	assert.Equal(t, []string{"bootstrap-token"}, getNodeIdentity(newMCOP("password,bootstrap-token")))
+}
+
+func TestWithBootstrapToken(t *testing.T) {
+	pointerConfig, err := ctrlcommon.PointerConfig("api-int.example.com:22623", []byte("ca"))
+	require.NoError(t, err)
+	pointerConfigData, err := json.Marshal(pointerConfig)
+	require.NoError(t, err)
+
+	data, err := withBootstrapToken(pointerConfigData, "abcdef.0123456789abcdef")
+	require.NoError(t, err)
+	withToken := ign3types.Config{}
+	require.NoError(t, json.Unmarshal(data, &withToken))
+	require.Len(t, withToken.Ignition.Config.Merge, 1)
+	assert.Equal(t, pointerConfig.Ignition.Config.Merge[0].Source, withToken.Ignition.Config.Merge[0].Source)
+	require.Len(t, withToken.Ignition.Config.Merge[0].HTTPHeaders, 1)
+	assert.Equal(t, "Authorization", withToken.Ignition.Config.Merge[0].HTTPHeaders[0].Name)
+	assert.Equal(t, "Bearer abcdef.0123456789abcdef", *withToken.Ignition.Config.Merge[0].HTTPHeaders[0].Value)
+	// The role is still templated into the source.
+	assert.Contains(t, string(data), "/config/{{.Role}}")
+
+	_, err = withBootstrapToken([]byte("not json"), "abcdef.0123456789abcdef")
+	assert.Error(t, err)
+}
+
+func TestSyncPoolBootstrapToken(t *testing.T) {
+	listTokens := func(t *testing.T, optr *Operator) []corev1.Secret {
+		secrets, err := optr.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).List(context.TODO(), metav1.ListOptions{})
+		require.NoError(t, err)
+		return secrets.Items
+	}
+	createToken := func(t *testing.T, optr *Operator, pool string, ttl time.Duration) string {
+		secret, token, err := server.NewBootstrapTokenSecret(pool, "", ttl)
+		require.NoError(t, err)
+		_, err = optr.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("mints a token", func(t *testing.T) {
+		optr := &Operator{kubeClient: fake.NewSimpleClientset()}
+		token, err := optr.syncPoolBootstrapToken("worker", true)
+		require.NoError(t, err)
+		assert.NotEmpty(t, token)
+		secrets := listTokens(t, optr)
+		require.Len(t, secrets, 1)
+		assert.Equal(t, "worker", secrets[0].Labels[server.BootstrapTokenPoolLabelKey])
+
+		// The token is reused while it has enough validity left.
+		again, err := optr.syncPoolBootstrapToken("worker", true)
+		require.NoError(t, err)
+		assert.Equal(t, token, again)
+		assert.Len(t, listTokens(t, optr), 1)
+
+		// Other pools get their own token.
+		master, err := optr.syncPoolBootstrapToken("master", true)
+		require.NoError(t, err)
+		assert.NotEqual(t, token, master)
+		assert.Len(t, listTokens(t, optr), 2)
+	})
+
+	t.Run("replaces a token about to expire", func(t *testing.T) {
+		optr := &Operator{kubeClient: fake.NewSimpleClientset()}
+		expiring := createToken(t, optr, "worker", time.Hour)
+		createToken(t, optr, "worker", -time.Hour)
+
+		token, err := optr.syncPoolBootstrapToken("worker", true)
+		require.NoError(t, err)
+		assert.NotEqual(t, expiring, token)
+		// The expired token is deleted, the expiring one is kept for machines
+		// created from the previous user-data.
+		assert.Len(t, listTokens(t, optr), 2)
+	})
+
+	t.Run("deletes tokens when disabled", func(t *testing.T) {
+		optr := &Operator{kubeClient: fake.NewSimpleClientset()}
+		createToken(t, optr, "worker", poolBootstrapTokenValidity)
+		createToken(t, optr, "master", poolBootstrapTokenValidity)
+
+		token, err := optr.syncPoolBootstrapToken("worker", false)
+		require.NoError(t, err)
+		assert.Empty(t, token)
+		secrets := listTokens(t, optr)
+		require.Len(t, secrets, 1)
+		assert.Equal(t, "master", secrets[0].Labels[server.BootstrapTokenPoolLabelKey])
+	})
+}
+
+func TestDeleteMachineConfigServerRole(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: machineConfigServerRoleName, Namespace: ctrlcommon.MCONamespace}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: machineConfigServerRoleName, Namespace: ctrlcommon.MCONamespace}},
+	)
+	optr := &Operator{kubeClient: kubeClient, namespace: ctrlcommon.MCONamespace}
+	require.NoError(t, optr.deleteMachineConfigServerRole())
+
+	roles, err := kubeClient.RbacV1().Roles(ctrlcommon.MCONamespace).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, roles.Items)
+	roleBindings, err := kubeClient.RbacV1().RoleBindings(ctrlcommon.MCONamespace).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, roleBindings.Items)
+
+	// Nothing to delete when the feature was never enabled.
+	require.NoError(t, optr.deleteMachineConfigServerRole())
+}
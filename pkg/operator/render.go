@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -52,6 +53,15 @@ type renderConfig struct {
 	TLSMinVersion          string
 	TLSCipherSuites        []string
 	LogLevel               string
+	// NodeIdentity are the methods machines must prove their identity with
+	// before the machine-config-server serves them a config.
+	NodeIdentity []string
+}
+
+// NodeIdentityEnabled returns whether the machine-config-server authenticates
+// machines with method.
+func (rc renderConfig) NodeIdentityEnabled(method string) bool {
+	return slices.Contains(rc.NodeIdentity, method)
 }
 
 type assetRenderer struct {
@@ -279,6 +279,44 @@ func TestRenderAsset(t *testing.T) {
 				"--payload-version=4.8.0-rc.0",
 			},
 		},
+		{
+			// Test that the machine-config-server authenticates machines with the
+			// configured node identity methods
+			Path: "manifests/machineconfigserver/daemonset.yaml",
+			RenderConfig: &renderConfig{
+				TargetNamespace: "testing-namespace",
+				ReleaseVersion:  "4.8.0-rc.0",
+				Images: &ctrlcommon.RenderConfigImages{
+					MachineConfigOperator: "mco-operator-image",
+				},
+				NodeIdentity: []string{"bootstrap-token", "client-certificate"},
+			},
+			FindExpected: []string{
+				"--metrics-listen-address=127.0.0.1:8798",
+				"--access-log=-",
+				"--upstream=http://127.0.0.1:8798",
+				"secretName: mcs-proxy-tls",
+				"--node-identity=bootstrap-token,client-certificate",
+				"--node-client-ca=/etc/mcs/node-client-ca/ca-bundle.crt",
+				"name: machine-config-server-node-client-ca",
+			},
+		},
+		{
+			// Test that the machine-config-server serves configs to any machine by default
+			Path: "manifests/machineconfigserver/daemonset.yaml",
+			RenderConfig: &renderConfig{
+				TargetNamespace: "testing-namespace",
+				ReleaseVersion:  "4.8.0-rc.0",
+				Images: &ctrlcommon.RenderConfigImages{
+					MachineConfigOperator: "mco-operator-image",
+				},
+			},
+			NotFindExpected: []string{
+				"--node-identity",
+				"--node-client-ca",
+				"node-client-ca",
+			},
+		},
 		{
 			// Bad path, will cause asset error
 			Path:  "BAD PATH",
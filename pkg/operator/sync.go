@@ -95,6 +95,7 @@ const (
 	mccEventsRoleBindingDefaultManifestPath                               = "manifests/machineconfigcontroller/events-rolebinding-default.yaml"
 	mccEventsRoleBindingTargetManifestPath                                = "manifests/machineconfigcontroller/events-rolebinding-target.yaml"
 	mccClusterRoleBindingManifestPath                                     = "manifests/machineconfigcontroller/clusterrolebinding.yaml"
+	mccClusterMonitoringViewClusterRoleBindingManifestPath                = "manifests/machineconfigcontroller/cluster-monitoring-view-clusterrolebinding.yaml"
 	mccServiceAccountManifestPath                                         = "manifests/machineconfigcontroller/sa.yaml"
 	mccKubeRbacProxyConfigMapPath                                         = "manifests/machineconfigcontroller/kube-rbac-proxy-config.yaml"
 	mccKubeRbacProxyPrometheusRolePath                                    = "manifests/machineconfigcontroller/prometheus-rbac.yaml"
@@ -142,12 +143,16 @@ const (
 	// Machine Config Server manifest paths
 	mcsClusterRoleManifestPath                    = "manifests/machineconfigserver/clusterrole.yaml"
 	mcsClusterRoleBindingManifestPath             = "manifests/machineconfigserver/clusterrolebinding.yaml"
+	mcsRoleManifestPath                           = "manifests/machineconfigserver/role.yaml"
+	mcsRoleBindingManifestPath                    = "manifests/machineconfigserver/rolebinding.yaml"
 	mcsCSRBootstrapRoleBindingManifestPath        = "manifests/machineconfigserver/csr-bootstrap-role-binding.yaml"
 	mcsCSRRenewalRoleBindingManifestPath          = "manifests/machineconfigserver/csr-renewal-role-binding.yaml"
 	mcsServiceAccountManifestPath                 = "manifests/machineconfigserver/sa.yaml"
 	mcsNodeBootstrapperServiceAccountManifestPath = "manifests/machineconfigserver/node-bootstrapper-sa.yaml"
 	mcsNodeBootstrapperTokenManifestPath          = "manifests/machineconfigserver/node-bootstrapper-token.yaml"
 	mcsDaemonsetManifestPath                      = "manifests/machineconfigserver/daemonset.yaml"
+	mcsNodeClientCAConfigMapManifestPath          = "manifests/machineconfigserver/node-client-ca-configmap.yaml"
+	mcsKubeRbacProxyConfigMapPath                 = "manifests/machineconfigserver/kube-rbac-proxy-config.yaml"
 
 	// Machine OS puller manifest paths
 	mopRoleBindingManifestPath    = "manifests/machine-os-puller/rolebinding.yaml"
@@ -657,6 +662,10 @@ func (optr *Operator) syncRenderConfig(_ *renderConfig, _ *configv1.ClusterOpera
 	}
 
 	optr.renderConfig = getRenderConfig(optr.namespace, string(kubeAPIServerServingCABytes), spec, &imgs.RenderConfigImages, infra, pointerConfigData, apiServer, fmt.Sprintf("%d", optr.logLevel))
+	// The node identity methods are only configured on an existing MachineConfiguration.
+	if err == nil {
+		optr.renderConfig.NodeIdentity = getNodeIdentity(mcop)
+	}
 
 	return nil
 }
@@ -782,6 +791,17 @@ func (optr *Operator) syncMachineConfigPools(config *renderConfig, _ *configv1.C
 		if err != nil {
 			return err
 		}
+		// Machines of the pool fetch their config with a bootstrap token of
+		// the pool when the machine-config-server requires one.
+		token, err := optr.syncPoolBootstrapToken(pool.Name, config.NodeIdentityEnabled(server.NodeIdentityBootstrapToken))
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			if pointerConfigData, err = withBootstrapToken(pointerConfigData, token); err != nil {
+				return err
+			}
+		}
 
 		userDataAsset := newAssetRenderer(userDataTemplatePath)
 		if err := userDataAsset.read(); err != nil {
@@ -1188,6 +1208,7 @@ func (optr *Operator) syncMachineConfigController(config *renderConfig, _ *confi
 		},
 		clusterRoleBindings: []string{
 			mccClusterRoleBindingManifestPath,
+			mccClusterMonitoringViewClusterRoleBindingManifestPath,
 		},
 		configMaps: []string{
 			mccKubeRbacProxyConfigMapPath,
@@ -1695,6 +1716,20 @@ func (optr *Operator) syncMachineConfigServer(config *renderConfig, _ *configv1.
 		secrets: []string{
 			mcsNodeBootstrapperTokenManifestPath,
 		},
+		configMaps: []string{
+			mcsKubeRbacProxyConfigMapPath,
+		},
+	}
+	// The machine-config-server only reads and consumes bootstrap tokens when
+	// it authenticates machines with them.
+	if config.NodeIdentityEnabled(server.NodeIdentityBootstrapToken) {
+		paths.roles = append(paths.roles, mcsRoleManifestPath)
+		paths.roleBindings = append(paths.roleBindings, mcsRoleBindingManifestPath)
+	} else if err := optr.deleteMachineConfigServerRole(); err != nil {
+		return err
+	}
+	if config.NodeIdentityEnabled(server.NodeIdentityClientCertificate) {
+		paths.configMaps = append(paths.configMaps, mcsNodeClientCAConfigMapManifestPath)
 	}
 
 	if err := optr.applyManifests(config, paths); err != nil {
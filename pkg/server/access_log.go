@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+// configRequestInfo is what the handler learned about a config request, to
+// record it in the metrics and the access log.
+type configRequestInfo struct {
+	pool     string
+	node     string
+	version  *semver.Version
+	identity *NodeIdentity
+	// whether the pool requested exists, so it can label the metrics
+	knownPool bool
+	// the name of the rendered MachineConfig served
+	configName string
+}
+
+// responseRecorder records the status code and the size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+// accessLogEntry is a line of the access log.
+type accessLogEntry struct {
+	Time            time.Time `json:"time"`
+	RemoteAddr      string    `json:"remoteAddr"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	Pool            string    `json:"pool,omitempty"`
+	Node            string    `json:"node,omitempty"`
+	Machine         string    `json:"machine,omitempty"`
+	UserAgent       string    `json:"userAgent,omitempty"`
+	IgnitionVersion string    `json:"ignitionVersion,omitempty"`
+	RenderedConfig  string    `json:"renderedConfig,omitempty"`
+	Status          int       `json:"status"`
+	Bytes           int       `json:"bytes"`
+	DurationSeconds float64   `json:"durationSeconds"`
+}
+
+// AccessLog writes a JSON line for each config request. A nil AccessLog does
+// not log anything.
+type AccessLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAccessLog returns an access log writing to w.
+func NewAccessLog(w io.Writer) *AccessLog {
+	return &AccessLog{w: w}
+}
+
+func (l *AccessLog) log(r *http.Request, info *configRequestInfo, status, bytes int, start time.Time, duration time.Duration) {
+	if l == nil {
+		return
+	}
+
+	entry := accessLogEntry{
+		Time:            start.UTC(),
+		RemoteAddr:      r.RemoteAddr,
+		Method:          r.Method,
+		Path:            r.URL.Path,
+		Pool:            info.pool,
+		Node:            info.node,
+		UserAgent:       r.Header.Get("User-Agent"),
+		RenderedConfig:  info.configName,
+		Status:          status,
+		Bytes:           bytes,
+		DurationSeconds: duration.Seconds(),
+	}
+	if info.version != nil {
+		entry.IgnitionVersion = info.version.String()
+	}
+	if info.identity != nil {
+		entry.Machine = info.identity.Machine
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		klog.Errorf("failed to marshal access log entry: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		klog.Errorf("failed to write access log: %v", err)
+	}
+}
+
+// renderedConfigName returns the name of the rendered MachineConfig a served
+// config was generated from, as seeded in the node annotations of the config.
+// It is looked up once when the config is encoded, and cached with it.
+func renderedConfigName(config *runtime.RawExtension) string {
+	if config == nil {
+		return ""
+	}
+	ignConfig, err := ctrlcommon.ParseAndConvertConfig(config.Raw)
+	if err != nil {
+		return ""
+	}
+	data, err := ctrlcommon.GetIgnitionFileDataByPath(&ignConfig, daemonconsts.InitialNodeAnnotationsFilePath)
+	if err != nil || data == nil {
+		return ""
+	}
+	var annotations map[string]string
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return ""
+	}
+	return annotations[daemonconsts.CurrentMachineConfigAnnotationKey]
+}
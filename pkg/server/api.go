@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/clarketm/json"
 	"github.com/coreos/go-semver/semver"
@@ -29,6 +30,9 @@ const (
 type poolRequest struct {
 	machineConfigPool string
 	version           *semver.Version
+	// node is the name, MAC address or system UUID
+	// of the host requesting the config, if given.
+	node string
 }
 
 // APIServer provides the HTTP(s) endpoint
@@ -95,19 +99,47 @@ func (a *APIServer) Serve() {
 // Machine Config Server.
 type APIHandler struct {
 	server Server
+	// if set, configs are only served to machines
+	// which one of them authenticates.
+	authenticators []NodeAuthenticator
+	// if set, logs each config request.
+	accessLog *AccessLog
 }
 
 // NewServerAPIHandler initializes a new API handler
-// for the Machine Config Server.
-func NewServerAPIHandler(s Server) *APIHandler {
+// for the Machine Config Server. If authenticators
+// are given, configs are only served to machines
+// which prove their identity to one of them.
+func NewServerAPIHandler(s Server, authenticators ...NodeAuthenticator) *APIHandler {
 	return &APIHandler{
-		server: s,
+		server:         s,
+		authenticators: authenticators,
 	}
 }
 
+// WithAccessLog makes the handler log each config request
+// to the access log.
+func (sh *APIHandler) WithAccessLog(accessLog *AccessLog) *APIHandler {
+	sh.accessLog = accessLog
+	return sh
+}
+
 // ServeHTTP handles the requests for the machine config server
 // API handler.
 func (sh *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &responseRecorder{ResponseWriter: w}
+	info := &configRequestInfo{}
+	sh.serveConfig(rec, r, info)
+
+	duration := time.Since(start)
+	observeConfigRequest(info, rec.status, rec.bytes, duration)
+	sh.accessLog.log(r, info, rec.status, rec.bytes, start, duration)
+}
+
+// serveConfig serves the config of the pool requested, and records what it
+// learned about the request in info.
+func (sh *APIHandler) serveConfig(w http.ResponseWriter, r *http.Request, info *configRequestInfo) {
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		w.Header().Set("Content-Length", "0")
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -121,9 +153,26 @@ func (sh *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	poolName := path.Base(r.URL.Path)
+	node := r.URL.Query().Get("node")
 	useragent := r.Header.Get("User-Agent")
 	acceptHeader := r.Header.Get("Accept")
-	klog.Infof("Pool %q requested by address:%q User-Agent:%q Accept-Header: %q", poolName, r.RemoteAddr, useragent, acceptHeader)
+	klog.Infof("Pool %q requested by address:%q node:%q User-Agent:%q Accept-Header: %q", poolName, r.RemoteAddr, node, useragent, acceptHeader)
+	info.pool = poolName
+	info.node = node
+	if checker, ok := sh.server.(poolChecker); ok {
+		info.knownPool = checker.hasPool(poolName)
+	}
+
+	var identity *NodeIdentity
+	if len(sh.authenticators) > 0 {
+		var err error
+		identity, err = authenticateNode(r.Context(), sh.authenticators, r, poolName)
+		if err != nil {
+			refuseUnauthenticated(w, r, poolName, err)
+			return
+		}
+		info.identity = identity
+	}
 
 	reqConfigVer, err := detectSpecVersionFromAcceptHeader(acceptHeader)
 	if err != nil {
@@ -132,41 +181,71 @@ func (sh *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		klog.Error(err.Error())
 		return
 	}
+	info.version = reqConfigVer
 
 	cr := poolRequest{
 		machineConfigPool: poolName,
 		version:           reqConfigVer,
+		node:              node,
 	}
 
-	conf, err := sh.server.GetConfig(cr)
-	if err != nil {
-		w.Header().Set("Content-Length", "0")
-		w.WriteHeader(http.StatusInternalServerError)
-		klog.Errorf("couldn't get config for req: %+v, error: %v", cr, err)
-		return
+	// Configs are cached and revalidated by entity tag if the server
+	// supports it. Configs requested for a node are not cached, as
+	// they may contain host config fragments.
+	var etag string
+	tagger, _ := sh.server.(configTagger)
+	if tagger != nil {
+		if etag, err = tagger.getConfigETag(cr); err != nil {
+			klog.Warningf("couldn't get entity tag of config for req: %+v, error: %v", cr, err)
+			etag = ""
+		}
 	}
-	if conf == nil {
-		w.Header().Set("Content-Length", "0")
-		w.WriteHeader(http.StatusNotFound)
+	if etag != "" && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		info.knownPool = true
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
-
-	serveConf, err := ctrlcommon.ConvertRawExtIgnitionToVersion(conf, *reqConfigVer)
-	if err != nil {
-		w.Header().Set("Content-Length", "0")
-		w.WriteHeader(http.StatusInternalServerError)
-		klog.Errorf("couldn't convert config for req: %v, error: %v", cr, err)
-		return
+	var cache *configCache
+	if etag != "" && node == "" {
+		cache = tagger.getConfigCache()
 	}
-
-	data, err := json.Marshal(&serveConf)
-	if err != nil {
-		w.Header().Set("Content-Length", "0")
-		w.WriteHeader(http.StatusInternalServerError)
-		klog.Errorf("failed to marshal %v config: %v", cr, err)
-		return
+	cacheKey := configCacheKey{pool: poolName, version: reqConfigVer.String()}
+
+	served := cache.get(cacheKey, etag)
+	if served == nil {
+		var status int
+		if served, status, err = sh.encodeConfig(cr); err != nil {
+			w.Header().Set("Content-Length", "0")
+			w.WriteHeader(status)
+			if !errors.Is(err, errConfigNotFound) {
+				klog.Error(err.Error())
+			}
+			return
+		}
+		// The objects the config is rendered from may have
+		// changed since the entity tag was computed.
+		if cache != nil {
+			if current, err := tagger.getConfigETag(cr); err == nil && current == etag {
+				served.etag = etag
+				cache.add(cacheKey, served)
+			}
+		}
+	}
+	info.knownPool = true
+	info.configName = served.name
+	data := served.data
+
+	if identity != nil && identity.commit != nil && r.Method == http.MethodGet {
+		if err := identity.commit(r.Context()); err != nil {
+			refuseUnauthenticated(w, r, poolName, err)
+			return
+		}
 	}
 
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
 	w.Header().Set("Content-Type", "application/json")
 	if r.Method == http.MethodHead {
@@ -177,7 +256,53 @@ func (sh *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(data)
 	if err != nil {
 		klog.Errorf("failed to write %v response: %v", cr, err)
+		return
+	}
+	if identity != nil {
+		klog.Infof("Config of pool %q served to machine %q authenticated by %s, address:%q", poolName, identity.Machine, identity.Method, r.RemoteAddr)
+	}
+}
+
+var errConfigNotFound = errors.New("config not found")
+
+// encodeConfig renders the config of the request and encodes it in the
+// Ignition spec version requested. It returns the status code to answer
+// with if it fails.
+func (sh *APIHandler) encodeConfig(cr poolRequest) (*cachedConfig, int, error) {
+	conf, err := sh.server.GetConfig(cr)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("couldn't get config for req: %+v, error: %w", cr, err)
+	}
+	if conf == nil {
+		return nil, http.StatusNotFound, errConfigNotFound
+	}
+
+	serveConf, err := ctrlcommon.ConvertRawExtIgnitionToVersion(conf, *cr.version)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("couldn't convert config for req: %v, error: %w", cr, err)
+	}
+
+	data, err := json.Marshal(&serveConf)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to marshal %v config: %w", cr, err)
+	}
+	return &cachedConfig{name: renderedConfigName(conf), data: data}, http.StatusOK, nil
+}
+
+// refuseUnauthenticated answers requests from machines which did not prove
+// their identity: 401 without credentials, 403 with invalid ones.
+func refuseUnauthenticated(w http.ResponseWriter, r *http.Request, poolName string, err error) {
+	w.Header().Set("Content-Length", "0")
+	switch {
+	case errors.Is(err, errNoCredentials):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="machine-config-server"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, errInvalidCredentials):
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
 	}
+	klog.Warningf("Config of pool %q refused to address:%q: %v", poolName, r.RemoteAddr, err)
 }
 
 type healthHandler struct{}
@@ -56,6 +56,10 @@ type clusterServer struct {
 
 	kubeconfigFunc kubeconfigFunc
 	apiserverURL   string
+
+	// configCache holds the encoded configs served, until
+	// an object they are rendered from changes.
+	configCache *configCache
 }
 
 const minResyncPeriod = 20 * time.Minute
@@ -104,6 +108,27 @@ func NewClusterServer(kubeConfig, apiserverURL string) (Server, error) {
 		moscInformer.Informer().HasSynced,
 		mosbInformer.Informer().HasSynced
 
+	// Any change to the objects configs are rendered from
+	// invalidates the configs cached.
+	configCache := newConfigCache()
+	invalidateConfigCache := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { configCache.invalidate() },
+		UpdateFunc: func(interface{}, interface{}) { configCache.invalidate() },
+		DeleteFunc: func(interface{}) { configCache.invalidate() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		mcpInformer.Informer(),
+		mcInformer.Informer(),
+		ccInformer.Informer(),
+		cmInformer.Informer(),
+		moscInformer.Informer(),
+		mosbInformer.Informer(),
+	} {
+		if _, err := informer.AddEventHandler(invalidateConfigCache); err != nil {
+			return nil, fmt.Errorf("failed to add config cache event handler: %w", err)
+		}
+	}
+
 	var informerStopCh chan struct{}
 	go sharedInformerFactory.Start(informerStopCh)
 	go kubeNamespacedSharedInformer.Start(informerStopCh)
@@ -123,6 +148,7 @@ func NewClusterServer(kubeConfig, apiserverURL string) (Server, error) {
 		routeclient:             routeClient,
 		kubeconfigFunc:          func() ([]byte, []byte, error) { return kubeconfigFromSecret(bootstrapTokenDir, apiserverURL, nil) },
 		apiserverURL:            apiserverURL,
+		configCache:             configCache,
 	}, nil
 }
 
@@ -134,16 +160,7 @@ func (cs *clusterServer) GetConfig(cr poolRequest) (*runtime.RawExtension, error
 		return nil, fmt.Errorf("could not fetch pool. err: %w", err)
 	}
 
-	// For new nodes, we roll out the latest if at least one node has successfully updated.
-	// This avoids deadlocks in situations where the old configuration broke somehow
-	// (e.g. pull secret expired)
-	// and also avoids provisioning a new node, only to update it not long thereafter.
-	var currConf string
-	if mp.Status.UpdatedMachineCount > 0 {
-		currConf = mp.Spec.Configuration.Name
-	} else {
-		currConf = mp.Status.Configuration.Name
-	}
+	currConf, selection := newNodeConfig(mp)
 
 	mc, err := cs.machineConfigLister.Get(currConf)
 	if err != nil {
@@ -186,9 +203,15 @@ func (cs *clusterServer) GetConfig(cr poolRequest) (*runtime.RawExtension, error
 
 	desiredImage := cs.resolveDesiredImageForPool(mp)
 
+	hostConfigs, err := cs.getHostConfigs(mp.Name, cr.node)
+	if err != nil {
+		return nil, err
+	}
+
 	appenders := newAppendersBuilder(cr.version, cs.kubeconfigFunc, []string{}, "").
-		WithNodeAnnotations(currConf, desiredImage).
+		WithNodeAnnotations(currConf, desiredImage, selection).
 		WithCustomAppender(appendDesiredOSImage(desiredImage)).
+		WithCustomAppender(appendHostConfigs(hostConfigs)).
 		build()
 
 	for _, a := range appenders {
@@ -301,12 +324,7 @@ func (cs *clusterServer) resolveDesiredImageForPool(pool *mcfgv1.MachineConfigPo
 		return ""
 	}
 
-	var currentConf string
-	if pool.Status.UpdatedMachineCount > 0 {
-		currentConf = pool.Spec.Configuration.Name
-	} else {
-		currentConf = pool.Status.Configuration.Name
-	}
+	currentConf, _ := newNodeConfig(pool)
 
 	var mosb *mcfgv1.MachineOSBuild
 	for _, build := range mosbList {
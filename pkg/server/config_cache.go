@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+)
+
+// configTagger is implemented by servers which can tell the entity tag of
+// the config they serve for a request without rendering it, and which
+// invalidate their cache of encoded configs when the objects configs are
+// rendered from change.
+type configTagger interface {
+	getConfigETag(poolRequest) (string, error)
+	getConfigCache() *configCache
+}
+
+// configCacheKey identifies an encoded config: configs are only cached for
+// requests without a node, so they only depend on the pool and the
+// Ignition spec version requested.
+type configCacheKey struct {
+	pool    string
+	version string
+}
+
+// cachedConfig is a config encoded in the Ignition spec version requested.
+type cachedConfig struct {
+	etag string
+	// the name of the rendered MachineConfig the config is generated from
+	name string
+	data []byte
+}
+
+// configCache holds the configs served, encoded in the Ignition spec version
+// requested, so scale-ups of many machines do not render and convert the
+// same config over and over.
+type configCache struct {
+	mu      sync.Mutex
+	configs map[configCacheKey]*cachedConfig
+}
+
+func newConfigCache() *configCache {
+	return &configCache{configs: map[configCacheKey]*cachedConfig{}}
+}
+
+// get returns the cached config for the key if it has the entity tag given.
+// A nil configCache does not cache anything.
+func (c *configCache) get(key configCacheKey, etag string) *cachedConfig {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.configs[key]; ok && cached.etag == etag {
+		return cached
+	}
+	return nil
+}
+
+func (c *configCache) add(key configCacheKey, cached *cachedConfig) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configs[key] = cached
+}
+
+// invalidate drops all the cached configs.
+func (c *configCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configs = map[configCacheKey]*cachedConfig{}
+}
+
+// etagMatches returns whether an If-None-Match header matches the entity tag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// getConfigETag returns the entity tag of the config GetConfig serves for the
+// request. It is derived from the rendered MachineConfig, the generation of
+// the ControllerConfig and the data the server appends to the config.
+func (cs *clusterServer) getConfigETag(cr poolRequest) (string, error) {
+	mp, err := cs.machineConfigPoolLister.Get(cr.machineConfigPool)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch pool. err: %w", err)
+	}
+	cc, err := cs.controllerConfigLister.Get(ctrlcommon.ControllerConfigName)
+	if err != nil {
+		return "", fmt.Errorf("could not get controllerconfig: %w", err)
+	}
+
+	h := sha256.New()
+	writeETagField(h, cr.version.String())
+	currConf, selection := newNodeConfig(mp)
+	writeETagField(h, currConf)
+	writeETagField(h, selection)
+	writeETagField(h, fmt.Sprint(cc.Generation))
+	writeETagField(h, cs.resolveDesiredImageForPool(mp))
+
+	kubeconfig, _, err := cs.kubeconfigFunc()
+	if err != nil {
+		return "", err
+	}
+	writeETagField(h, string(kubeconfig))
+	if cs.configMapLister != nil {
+		if cm, err := cs.configMapLister.ConfigMaps(ctrlcommon.MCONamespace).Get("kubeconfig-data"); err == nil {
+			writeETagField(h, cm.ResourceVersion)
+		}
+	}
+
+	hostConfigs, err := cs.getHostConfigs(mp.Name, cr.node)
+	if err != nil {
+		return "", err
+	}
+	for _, hc := range hostConfigs {
+		writeETagField(h, hc.name+"/"+hc.resourceVersion)
+	}
+
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`, nil
+}
+
+func (cs *clusterServer) getConfigCache() *configCache {
+	return cs.configCache
+}
+
+func writeETagField(h hash.Hash, field string) {
+	h.Write([]byte(field))
+	h.Write([]byte{0})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+)
+
+// mockTaggingServer is a mockServer which tags its configs.
+type mockTaggingServer struct {
+	mockServer
+	etag  string
+	cache *configCache
+}
+
+func (ms *mockTaggingServer) getConfigETag(poolRequest) (string, error) {
+	return ms.etag, nil
+}
+
+func (ms *mockTaggingServer) getConfigCache() *configCache {
+	return ms.cache
+}
+
+func TestAPIHandlerConfigCache(t *testing.T) {
+	rendered := 0
+	ms := &mockTaggingServer{
+		mockServer: mockServer{
+			GetConfigFn: func(poolRequest) (*runtime.RawExtension, error) {
+				rendered++
+				return &runtime.RawExtension{Raw: helpers.MarshalOrDie(ctrlcommon.NewIgnConfig())}, nil
+			},
+		},
+		etag:  `"1"`,
+		cache: newConfigCache(),
+	}
+	handler := NewServerAPIHandler(ms)
+
+	serve := func(method, url, ifNoneMatch string) *http.Response {
+		req := setV3_1AcceptHeaderOnReq(httptest.NewRequest(method, url, nil))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := serve(http.MethodHead, "http://testrequest/config/worker", "")
+	checkStatus(t, resp, http.StatusOK)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, 1, rendered)
+
+	resp = serve(http.MethodGet, "http://testrequest/config/worker", "")
+	checkStatus(t, resp, http.StatusOK)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.NotZero(t, resp.ContentLength)
+	assert.Equal(t, 1, rendered, "config should be served from the cache")
+
+	resp = serve(http.MethodGet, "http://testrequest/config/worker", `"0", W/"1"`)
+	checkStatus(t, resp, http.StatusNotModified)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, 1, rendered)
+
+	resp = serve(http.MethodGet, "http://testrequest/config/worker", `"0"`)
+	checkStatus(t, resp, http.StatusOK)
+	assert.Equal(t, 1, rendered)
+
+	ms.etag = `"2"`
+	resp = serve(http.MethodGet, "http://testrequest/config/worker", `"1"`)
+	checkStatus(t, resp, http.StatusOK)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+	assert.Equal(t, 2, rendered)
+
+	ms.cache.invalidate()
+	checkStatus(t, serve(http.MethodGet, "http://testrequest/config/worker", ""), http.StatusOK)
+	assert.Equal(t, 3, rendered)
+
+	// configs requested for a node are not cached
+	checkStatus(t, serve(http.MethodGet, "http://testrequest/config/worker?node=worker-0", ""), http.StatusOK)
+	checkStatus(t, serve(http.MethodGet, "http://testrequest/config/worker?node=worker-0", ""), http.StatusOK)
+	assert.Equal(t, 5, rendered)
+	checkStatus(t, serve(http.MethodGet, "http://testrequest/config/worker?node=worker-0", `"2"`), http.StatusNotModified)
+	assert.Equal(t, 5, rendered)
+}
+
+func TestClusterServerConfigETag(t *testing.T) {
+	mp, err := getTestMachineConfigPool()
+	require.NoError(t, err)
+	cc := getTestControllerConfig()
+	kubeconfig := "kubeconfig"
+	cs := &clusterServer{
+		machineConfigPoolLister: &mockMCPLister{pools: []*mcfgv1.MachineConfigPool{mp}},
+		controllerConfigLister:  &mockCCLister{configs: []*mcfgv1.ControllerConfig{cc}},
+		kubeconfigFunc: func() ([]byte, []byte, error) {
+			return []byte(kubeconfig), nil, nil
+		},
+	}
+	cr := poolRequest{machineConfigPool: testPool, version: semver.New("3.5.0")}
+
+	etag, err := cs.getConfigETag(cr)
+	require.NoError(t, err)
+	again, err := cs.getConfigETag(cr)
+	require.NoError(t, err)
+	assert.Equal(t, etag, again)
+
+	v22, err := cs.getConfigETag(poolRequest{machineConfigPool: testPool, version: semver.New("2.2.0")})
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, v22)
+
+	cc.Generation++
+	changed, err := cs.getConfigETag(cr)
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, changed)
+
+	etag = changed
+	kubeconfig = "rotated"
+	changed, err = cs.getConfigETag(cr)
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, changed)
+
+	etag = changed
+	mp.Status.Configuration.Name = "rendered-worker-2"
+	mp.Spec.Configuration.Name = "rendered-worker-2"
+	changed, err = cs.getConfigETag(cr)
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, changed)
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", "a"`, `"a"`))
+	assert.True(t, etagMatches(`W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`*`, `"a"`))
+	assert.False(t, etagMatches(``, `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+}
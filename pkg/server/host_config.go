@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ign3 "github.com/coreos/ignition/v2/config/v3_5"
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+)
+
+const (
+	// HostConfigLabel marks the ConfigMaps of the MCO namespace which hold an
+	// Ignition config fragment for specific hosts, merged into the config
+	// served to them.
+	HostConfigLabel = "machineconfiguration.openshift.io/host-config"
+	// HostConfigHostsKey is the key of the hosts a fragment is for, one per
+	// line: node names, MAC addresses or system UUIDs, as requested with
+	// /config/<pool>?node=<host>.
+	HostConfigHostsKey = "hosts"
+	// HostConfigPoolKey is the key of the pool a fragment is restricted to.
+	// A fragment without it is merged into the config of any pool.
+	HostConfigPoolKey = "pool"
+	// HostConfigIgnitionKey is the key of the Ignition config fragment.
+	HostConfigIgnitionKey = "config.ign"
+)
+
+// hostConfig is the Ignition config fragment of a host ConfigMap.
+type hostConfig struct {
+	name            string
+	resourceVersion string
+	config          ign3types.Config
+}
+
+func normalizeHostID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// getHostConfigs returns the fragments of the host ConfigMaps for the node,
+// sorted by ConfigMap name.
+func (cs *clusterServer) getHostConfigs(pool, node string) ([]hostConfig, error) {
+	node = normalizeHostID(node)
+	if node == "" || cs.configMapLister == nil {
+		return nil, nil
+	}
+
+	requirement, err := labels.NewRequirement(HostConfigLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	configMaps, err := cs.configMapLister.ConfigMaps(ctrlcommon.MCONamespace).List(labels.NewSelector().Add(*requirement))
+	if err != nil {
+		return nil, fmt.Errorf("could not list host configs: %w", err)
+	}
+	sort.Slice(configMaps, func(i, j int) bool { return configMaps[i].Name < configMaps[j].Name })
+
+	var hostConfigs []hostConfig
+	for _, cm := range configMaps {
+		if cmPool, ok := cm.Data[HostConfigPoolKey]; ok && strings.TrimSpace(cmPool) != pool {
+			continue
+		}
+		hosts := sets.New[string]()
+		for _, host := range strings.Split(cm.Data[HostConfigHostsKey], "\n") {
+			hosts.Insert(normalizeHostID(host))
+		}
+		if !hosts.Has(node) {
+			continue
+		}
+
+		config, err := ctrlcommon.ParseAndConvertConfig([]byte(cm.Data[HostConfigIgnitionKey]))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse host config %s: %w", cm.Name, err)
+		}
+		hostConfigs = append(hostConfigs, hostConfig{name: cm.Name, resourceVersion: cm.ResourceVersion, config: config})
+	}
+	return hostConfigs, nil
+}
+
+// configPaths returns the paths of the files, links and directories and the
+// names of the units of a config.
+func configPaths(cfg *ign3types.Config) (sets.Set[string], sets.Set[string]) {
+	paths := sets.New[string]()
+	for _, f := range cfg.Storage.Files {
+		paths.Insert(f.Path)
+	}
+	for _, l := range cfg.Storage.Links {
+		paths.Insert(l.Path)
+	}
+	for _, d := range cfg.Storage.Directories {
+		paths.Insert(d.Path)
+	}
+	units := sets.New[string]()
+	for _, u := range cfg.Systemd.Units {
+		units.Insert(u.Name)
+	}
+	return paths, units
+}
+
+// appendHostConfigs merges host config fragments into the config. Fragments
+// are not part of the MachineConfig of the pool, so the daemon does not
+// manage them once the host is up, and they may not change what the pool
+// config, or the server, writes.
+func appendHostConfigs(hostConfigs []hostConfig) appenderFunc {
+	return func(cfg *ign3types.Config, _ *mcfgv1.MachineConfig) error {
+		for _, hc := range hostConfigs {
+			paths, units := configPaths(cfg)
+			paths.Insert(defaultMachineKubeConfPath, machineConfigContentPath)
+			hcPaths, hcUnits := configPaths(&hc.config)
+			if conflicts := paths.Intersection(hcPaths); conflicts.Len() > 0 {
+				return fmt.Errorf("host config %s writes paths of the pool config: %v", hc.name, sets.List(conflicts))
+			}
+			if conflicts := units.Intersection(hcUnits); conflicts.Len() > 0 {
+				return fmt.Errorf("host config %s defines units of the pool config: %v", hc.name, sets.List(conflicts))
+			}
+			klog.Infof("Merging host config %s", hc.name)
+			*cfg = ign3.Merge(*cfg, hc.config)
+		}
+		return nil
+	}
+}
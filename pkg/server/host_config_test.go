@@ -0,0 +1,160 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corelisterv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+)
+
+func newHostConfigMap(name string, labeled bool, data map[string]string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ctrlcommon.MCONamespace},
+		Data:       data,
+	}
+	if labeled {
+		cm.Labels = map[string]string{HostConfigLabel: ""}
+	}
+	return cm
+}
+
+func hostIgnition(path, unit string) string {
+	cfg := ctrlcommon.NewIgnConfig()
+	if path != "" {
+		cfg.Storage.Files = append(cfg.Storage.Files, helpers.CreateEncodedIgn3File(path, "contents", 0o600))
+	}
+	if unit != "" {
+		enabled := true
+		cfg.Systemd.Units = append(cfg.Systemd.Units, ign3types.Unit{Name: unit, Enabled: &enabled})
+	}
+	return string(helpers.MarshalOrDie(cfg))
+}
+
+func TestGetHostConfigs(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, cm := range []*corev1.ConfigMap{
+		newHostConfigMap("b-network", true, map[string]string{
+			HostConfigHostsKey:    "worker-0\n52:54:00:AA:BB:CC\n",
+			HostConfigIgnitionKey: hostIgnition("/etc/NetworkManager/system-connections/eno1.nmconnection", ""),
+		}),
+		newHostConfigMap("a-hostname", true, map[string]string{
+			HostConfigHostsKey:    "worker-0",
+			HostConfigPoolKey:     "worker",
+			HostConfigIgnitionKey: hostIgnition("/etc/hostname", ""),
+		}),
+		newHostConfigMap("infra-only", true, map[string]string{
+			HostConfigHostsKey:    "worker-0",
+			HostConfigPoolKey:     "infra",
+			HostConfigIgnitionKey: hostIgnition("/etc/infra", ""),
+		}),
+		newHostConfigMap("not-labeled", false, map[string]string{
+			HostConfigHostsKey:    "worker-0",
+			HostConfigIgnitionKey: hostIgnition("/etc/unlabeled", ""),
+		}),
+		newHostConfigMap("broken", true, map[string]string{
+			HostConfigHostsKey:    "broken-0",
+			HostConfigIgnitionKey: "not ignition",
+		}),
+	} {
+		require.NoError(t, indexer.Add(cm))
+	}
+	cs := &clusterServer{configMapLister: corelisterv1.NewConfigMapLister(indexer)}
+
+	names := func(hostConfigs []hostConfig) []string {
+		var names []string
+		for _, hc := range hostConfigs {
+			names = append(names, hc.name)
+		}
+		return names
+	}
+
+	hostConfigs, err := cs.getHostConfigs("worker", "worker-0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a-hostname", "b-network"}, names(hostConfigs))
+	assert.Equal(t, "/etc/hostname", hostConfigs[0].config.Storage.Files[0].Path)
+
+	hostConfigs, err = cs.getHostConfigs("worker", "52:54:00:aa:bb:cc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b-network"}, names(hostConfigs))
+
+	hostConfigs, err = cs.getHostConfigs("worker", "")
+	require.NoError(t, err)
+	assert.Empty(t, hostConfigs)
+
+	hostConfigs, err = cs.getHostConfigs("worker", "worker-1")
+	require.NoError(t, err)
+	assert.Empty(t, hostConfigs)
+
+	_, err = cs.getHostConfigs("worker", "broken-0")
+	assert.ErrorContains(t, err, "broken")
+}
+
+func TestAppendHostConfigs(t *testing.T) {
+	parse := func(data string) ign3types.Config {
+		cfg, err := ctrlcommon.ParseAndConvertConfig([]byte(data))
+		require.NoError(t, err)
+		return cfg
+	}
+	pool := func() *ign3types.Config {
+		cfg := parse(hostIgnition("/etc/pool", "pool.service"))
+		return &cfg
+	}
+
+	cfg := pool()
+	require.NoError(t, appendHostConfigs([]hostConfig{
+		{name: "hostname", config: parse(hostIgnition("/etc/hostname", "host.service"))},
+	})(cfg, nil))
+	var paths, units []string
+	for _, f := range cfg.Storage.Files {
+		paths = append(paths, f.Path)
+	}
+	for _, u := range cfg.Systemd.Units {
+		units = append(units, u.Name)
+	}
+	assert.ElementsMatch(t, []string{"/etc/pool", "/etc/hostname"}, paths)
+	assert.ElementsMatch(t, []string{"pool.service", "host.service"}, units)
+
+	err := appendHostConfigs([]hostConfig{
+		{name: "overwrite-file", config: parse(hostIgnition("/etc/pool", ""))},
+	})(pool(), nil)
+	assert.ErrorContains(t, err, "overwrite-file writes paths of the pool config")
+
+	err = appendHostConfigs([]hostConfig{
+		{name: "overwrite-unit", config: parse(hostIgnition("", "pool.service"))},
+	})(pool(), nil)
+	assert.ErrorContains(t, err, "overwrite-unit defines units of the pool config")
+
+	err = appendHostConfigs([]hostConfig{
+		{name: "kubeconfig", config: parse(hostIgnition(defaultMachineKubeConfPath, ""))},
+	})(pool(), nil)
+	assert.ErrorContains(t, err, "kubeconfig writes paths of the pool config")
+}
+
+func TestAPIHandlerNodeParameter(t *testing.T) {
+	var requested poolRequest
+	ms := &mockServer{
+		GetConfigFn: func(pr poolRequest) (*runtime.RawExtension, error) {
+			requested = pr
+			return &runtime.RawExtension{Raw: helpers.MarshalOrDie(ctrlcommon.NewIgnConfig())}, nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	NewServerAPIHandler(ms).ServeHTTP(w, setAcceptHeaderOnReq(httptest.NewRequest(http.MethodGet, "http://testrequest/config/worker?node=worker-0", nil)))
+	resp := w.Result()
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusOK)
+	assert.Equal(t, "worker", requested.machineConfigPool)
+	assert.Equal(t, "worker-0", requested.node)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+)
+
+// MCS Metrics
+var (
+	// mcsConfigRequests counts the config requests by pool, requested Ignition
+	// spec version and status code; requests for pools which do not exist are
+	// counted as the unknown pool
+	mcsConfigRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mcs_config_requests_total",
+			Help: "Total number of config requests by pool, Ignition spec version and status code.",
+		}, []string{"pool", "ignition_version", "code"})
+
+	// mcsConfigRequestDuration is the time taken to serve config requests
+	mcsConfigRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mcs_config_request_duration_seconds",
+			Help:    "Time taken to serve config requests by pool and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"pool", "code"})
+
+	// mcsConfigResponseBytes is the size of the served configs
+	mcsConfigResponseBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mcs_config_response_bytes",
+			Help:    "Size of the config responses by pool.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+		}, []string{"pool"})
+)
+
+// RegisterMCSMetrics registers the machine config server metrics.
+func RegisterMCSMetrics() error {
+	err := ctrlcommon.RegisterMetrics([]prometheus.Collector{
+		mcsConfigRequests,
+		mcsConfigRequestDuration,
+		mcsConfigResponseBytes,
+	})
+	if err != nil {
+		return fmt.Errorf("could not register machine-config-server metrics: %w", err)
+	}
+	return nil
+}
+
+// unknownPool labels the metrics of requests for pools which do not exist, so
+// arbitrary request paths do not create new series.
+const unknownPool = "unknown"
+
+// poolChecker is implemented by servers which can tell whether a pool exists
+// without rendering its config. The metrics of requests to other servers are
+// only labeled with pools they found the config of.
+type poolChecker interface {
+	hasPool(string) bool
+}
+
+func (cs *clusterServer) hasPool(pool string) bool {
+	_, err := cs.machineConfigPoolLister.Get(pool)
+	return err == nil
+}
+
+// observeConfigRequest records a served config request in the metrics.
+func observeConfigRequest(info *configRequestInfo, status, bytes int, duration time.Duration) {
+	pool := unknownPool
+	if info.knownPool {
+		pool = info.pool
+	}
+	code := strconv.Itoa(status)
+	version := "unknown"
+	if info.version != nil {
+		version = info.version.String()
+	}
+	mcsConfigRequests.WithLabelValues(pool, version, code).Inc()
+	mcsConfigRequestDuration.WithLabelValues(pool, code).Observe(duration.Seconds())
+	mcsConfigResponseBytes.WithLabelValues(pool).Observe(float64(bytes))
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/machine-config-operator/test/helpers"
+)
+
+func TestAPIHandlerMetricsAndAccessLog(t *testing.T) {
+	cfg := ctrlcommon.NewIgnConfig()
+	annotations := helpers.MarshalOrDie(map[string]string{
+		daemonconsts.CurrentMachineConfigAnnotationKey: "rendered-metrics-1",
+	})
+	cfg.Storage.Files = append(cfg.Storage.Files, helpers.CreateEncodedIgn3File(daemonconsts.InitialNodeAnnotationsFilePath, string(annotations), 0o600))
+	ms := &mockServer{
+		GetConfigFn: func(pr poolRequest) (*runtime.RawExtension, error) {
+			if pr.machineConfigPool == "metrics-broken" {
+				return nil, errors.New("broken")
+			}
+			return &runtime.RawExtension{Raw: helpers.MarshalOrDie(cfg)}, nil
+		},
+	}
+
+	var out bytes.Buffer
+	handler := NewServerAPIHandler(ms).WithAccessLog(NewAccessLog(&out))
+
+	served := testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics", "3.1.0", "200"))
+	// The server does not tell which pools exist, so pools it did not find
+	// the config of are unknown.
+	failed := testutil.ToFloat64(mcsConfigRequests.WithLabelValues(unknownPool, "3.1.0", "500"))
+
+	req := setV3_1AcceptHeaderOnReq(httptest.NewRequest(http.MethodGet, "http://testrequest/config/metrics?node=worker-0", nil))
+	req.Header.Set("User-Agent", "Ignition/2.17.0")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	checkStatus(t, w.Result(), http.StatusOK)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, setV3_1AcceptHeaderOnReq(httptest.NewRequest(http.MethodGet, "http://testrequest/config/metrics-broken", nil)))
+	checkStatus(t, w.Result(), http.StatusInternalServerError)
+
+	assert.Equal(t, served+1, testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics", "3.1.0", "200")))
+	assert.Equal(t, failed+1, testutil.ToFloat64(mcsConfigRequests.WithLabelValues(unknownPool, "3.1.0", "500")))
+	assert.Zero(t, testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics-broken", "3.1.0", "500")))
+
+	decoder := json.NewDecoder(&out)
+	var entry accessLogEntry
+	require.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, http.MethodGet, entry.Method)
+	assert.Equal(t, "/config/metrics", entry.Path)
+	assert.Equal(t, "metrics", entry.Pool)
+	assert.Equal(t, "worker-0", entry.Node)
+	assert.Equal(t, "Ignition/2.17.0", entry.UserAgent)
+	assert.Equal(t, "3.1.0", entry.IgnitionVersion)
+	assert.Equal(t, "rendered-metrics-1", entry.RenderedConfig)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.NotZero(t, entry.Bytes)
+
+	entry = accessLogEntry{}
+	require.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, "metrics-broken", entry.Pool)
+	assert.Empty(t, entry.RenderedConfig)
+	assert.Equal(t, http.StatusInternalServerError, entry.Status)
+	assert.False(t, decoder.More())
+}
+
+// mockPoolServer is a mockServer which knows the pools which exist.
+type mockPoolServer struct {
+	mockServer
+	pools []string
+}
+
+func (ms *mockPoolServer) hasPool(pool string) bool {
+	return slices.Contains(ms.pools, pool)
+}
+
+func TestAPIHandlerMetricsKnownPools(t *testing.T) {
+	ms := &mockPoolServer{
+		mockServer: mockServer{GetConfigFn: func(poolRequest) (*runtime.RawExtension, error) {
+			return nil, errors.New("broken")
+		}},
+		pools: []string{"metrics-known"},
+	}
+	handler := NewServerAPIHandler(ms)
+
+	known := testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics-known", "3.1.0", "500"))
+	unknown := testutil.ToFloat64(mcsConfigRequests.WithLabelValues(unknownPool, "3.1.0", "500"))
+
+	for _, pool := range []string{"metrics-known", "metrics-random-1", "metrics-random-2"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, setV3_1AcceptHeaderOnReq(httptest.NewRequest(http.MethodGet, "http://testrequest/config/"+pool, nil)))
+		checkStatus(t, w.Result(), http.StatusInternalServerError)
+	}
+
+	assert.Equal(t, known+1, testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics-known", "3.1.0", "500")))
+	assert.Equal(t, unknown+2, testutil.ToFloat64(mcsConfigRequests.WithLabelValues(unknownPool, "3.1.0", "500")))
+	assert.Zero(t, testutil.ToFloat64(mcsConfigRequests.WithLabelValues("metrics-random-1", "3.1.0", "500")))
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"k8s.io/klog/v2"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+)
+
+// newNodeConfigPolicy is which rendered MachineConfig of a pool the server
+// serves to new nodes while the pool rolls out a new config.
+type newNodeConfigPolicy string
+
+const (
+	// newNodeConfigPolicyCurrent always serves the config the pool's nodes
+	// are on, so new nodes do not join a rollout that may still fail.
+	newNodeConfigPolicyCurrent newNodeConfigPolicy = "Current"
+	// newNodeConfigPolicyTarget always serves the config the pool is moving
+	// to, so new nodes do not need an update right after joining.
+	newNodeConfigPolicyTarget newNodeConfigPolicy = "Target"
+	// newNodeConfigPolicyTargetAfterUpdated serves the config the pool is
+	// moving to once enough of its nodes updated to it.
+	newNodeConfigPolicyTargetAfterUpdated newNodeConfigPolicy = "TargetAfterUpdated"
+)
+
+// getNewNodeConfigPolicy returns the new node config policy of a pool, and
+// for the TargetAfterUpdated policy the percentage of its nodes which must
+// have updated, 0 meaning at least one of them.
+func getNewNodeConfigPolicy(pool *mcfgv1.MachineConfigPool) (newNodeConfigPolicy, int, error) {
+	value, ok := pool.Annotations[ctrlcommon.NewNodeConfigPolicyAnnotationKey]
+	if !ok {
+		value = string(newNodeConfigPolicyTargetAfterUpdated)
+	}
+	switch policy := newNodeConfigPolicy(value); policy {
+	case newNodeConfigPolicyCurrent, newNodeConfigPolicyTarget:
+		return policy, 0, nil
+	case newNodeConfigPolicyTargetAfterUpdated:
+		percentValue, ok := pool.Annotations[ctrlcommon.NewNodeConfigUpdatedPercentAnnotationKey]
+		if !ok {
+			return policy, 0, nil
+		}
+		percent, err := strconv.Atoi(percentValue)
+		if err != nil || percent < 0 || percent > 100 {
+			return policy, 0, fmt.Errorf("invalid %s %q on pool %s: must be a percentage between 0 and 100",
+				ctrlcommon.NewNodeConfigUpdatedPercentAnnotationKey, percentValue, pool.Name)
+		}
+		return policy, percent, nil
+	default:
+		return newNodeConfigPolicyTargetAfterUpdated, 0, fmt.Errorf("invalid %s %q on pool %s: must be one of %s, %s or %s",
+			ctrlcommon.NewNodeConfigPolicyAnnotationKey, value, pool.Name, newNodeConfigPolicyCurrent, newNodeConfigPolicyTarget, newNodeConfigPolicyTargetAfterUpdated)
+	}
+}
+
+// newNodeConfig returns the rendered MachineConfig served to new nodes of the
+// pool following its new node config policy, and which of the pool's configs
+// it is, to record in the node annotations.
+// By default, we roll out the latest if at least one node has successfully updated.
+// This avoids deadlocks in situations where the old configuration broke somehow
+// (e.g. pull secret expired)
+// and also avoids provisioning a new node, only to update it not long thereafter.
+func newNodeConfig(mp *mcfgv1.MachineConfigPool) (string, string) {
+	policy, percent, err := getNewNodeConfigPolicy(mp)
+	if err != nil {
+		klog.Warningf("Falling back to new node config policy %s: %v", policy, err)
+	}
+
+	target := false
+	switch policy {
+	case newNodeConfigPolicyTarget:
+		target = true
+	case newNodeConfigPolicyTargetAfterUpdated:
+		// at least one node must have updated, whatever the percentage
+		required := max(1, (int(mp.Status.MachineCount)*percent+99)/100)
+		target = int(mp.Status.UpdatedMachineCount) >= required
+	}
+
+	if target {
+		return mp.Spec.Configuration.Name, fmt.Sprintf("Target (policy %s)", policy)
+	}
+	return mp.Status.Configuration.Name, fmt.Sprintf("Current (policy %s)", policy)
+}
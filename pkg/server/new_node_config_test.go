@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+func TestNewNodeConfig(t *testing.T) {
+	newPool := func(annotations map[string]string, machines, updated int32) *mcfgv1.MachineConfigPool {
+		return &mcfgv1.MachineConfigPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Annotations: annotations},
+			Spec: mcfgv1.MachineConfigPoolSpec{
+				Configuration: mcfgv1.MachineConfigPoolStatusConfiguration{ObjectReference: corev1.ObjectReference{Name: "rendered-worker-target"}},
+			},
+			Status: mcfgv1.MachineConfigPoolStatus{
+				Configuration:       mcfgv1.MachineConfigPoolStatusConfiguration{ObjectReference: corev1.ObjectReference{Name: "rendered-worker-current"}},
+				MachineCount:        machines,
+				UpdatedMachineCount: updated,
+			},
+		}
+	}
+	policy := func(policy, percent string) map[string]string {
+		annotations := map[string]string{ctrlcommon.NewNodeConfigPolicyAnnotationKey: policy}
+		if percent != "" {
+			annotations[ctrlcommon.NewNodeConfigUpdatedPercentAnnotationKey] = percent
+		}
+		return annotations
+	}
+
+	tests := []struct {
+		name              string
+		pool              *mcfgv1.MachineConfigPool
+		expectedConfig    string
+		expectedSelection string
+	}{
+		{
+			name:              "default before any node updated",
+			pool:              newPool(nil, 10, 0),
+			expectedConfig:    "rendered-worker-current",
+			expectedSelection: "Current (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "default after one node updated",
+			pool:              newPool(nil, 10, 1),
+			expectedConfig:    "rendered-worker-target",
+			expectedSelection: "Target (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "current",
+			pool:              newPool(policy("Current", ""), 10, 9),
+			expectedConfig:    "rendered-worker-current",
+			expectedSelection: "Current (policy Current)",
+		},
+		{
+			name:              "target",
+			pool:              newPool(policy("Target", ""), 10, 0),
+			expectedConfig:    "rendered-worker-target",
+			expectedSelection: "Target (policy Target)",
+		},
+		{
+			name:              "percentage not reached",
+			pool:              newPool(policy("TargetAfterUpdated", "50"), 9, 4),
+			expectedConfig:    "rendered-worker-current",
+			expectedSelection: "Current (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "percentage reached",
+			pool:              newPool(policy("TargetAfterUpdated", "50"), 9, 5),
+			expectedConfig:    "rendered-worker-target",
+			expectedSelection: "Target (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "zero percent still needs one node",
+			pool:              newPool(policy("TargetAfterUpdated", "0"), 9, 0),
+			expectedConfig:    "rendered-worker-current",
+			expectedSelection: "Current (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "invalid percentage falls back to one node",
+			pool:              newPool(policy("TargetAfterUpdated", "150"), 9, 1),
+			expectedConfig:    "rendered-worker-target",
+			expectedSelection: "Target (policy TargetAfterUpdated)",
+		},
+		{
+			name:              "invalid policy falls back to the default",
+			pool:              newPool(policy("Canary", ""), 9, 1),
+			expectedConfig:    "rendered-worker-target",
+			expectedSelection: "Target (policy TargetAfterUpdated)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, selection := newNodeConfig(test.pool)
+			assert.Equal(t, test.expectedConfig, config)
+			assert.Equal(t, test.expectedSelection, selection)
+		})
+	}
+}
+
+func TestGetNewNodeConfigPolicyErrors(t *testing.T) {
+	pool := &mcfgv1.MachineConfigPool{ObjectMeta: metav1.ObjectMeta{Name: "worker", Annotations: map[string]string{
+		ctrlcommon.NewNodeConfigPolicyAnnotationKey: "Canary",
+	}}}
+	_, _, err := getNewNodeConfigPolicy(pool)
+	assert.ErrorContains(t, err, "must be one of Current, Target or TargetAfterUpdated")
+
+	pool.Annotations = map[string]string{
+		ctrlcommon.NewNodeConfigPolicyAnnotationKey:         "TargetAfterUpdated",
+		ctrlcommon.NewNodeConfigUpdatedPercentAnnotationKey: "half",
+	}
+	_, _, err = getNewNodeConfigPolicy(pool)
+	assert.ErrorContains(t, err, "must be a percentage between 0 and 100")
+}
+
+func TestNodeAnnotationsRecordConfigSelection(t *testing.T) {
+	anno, err := getNodeAnnotation("rendered-worker-target", "", "Target (policy Target)", &mcfgv1.MachineConfig{})
+	require.NoError(t, err)
+	annotations := map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(anno), &annotations))
+	assert.Equal(t, "Target (policy Target)", annotations[daemonconsts.InitialConfigSelectionAnnotationKey])
+
+	anno, err = getNodeAnnotation("rendered-worker-target", "", "", &mcfgv1.MachineConfig{})
+	require.NoError(t, err)
+	annotations = map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(anno), &annotations))
+	assert.NotContains(t, annotations, daemonconsts.InitialConfigSelectionAnnotationKey)
+}
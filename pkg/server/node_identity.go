@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	mcfglistersv1 "github.com/openshift/client-go/machineconfiguration/listers/machineconfiguration/v1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/pkg/helpers"
+)
+
+const (
+	// NodeIdentityBootstrapToken authenticates machines with one-time
+	// bootstrap tokens.
+	NodeIdentityBootstrapToken = "bootstrap-token"
+	// NodeIdentityClientCertificate authenticates machines with a client
+	// certificate issued to a node.
+	NodeIdentityClientCertificate = "client-certificate"
+
+	// BootstrapTokenSecretType is the type of the Secrets holding bootstrap
+	// tokens, in the MCO namespace.
+	BootstrapTokenSecretType corev1.SecretType = "machineconfiguration.openshift.io/bootstrap-token"
+	// BootstrapTokenSecretPrefix prefixes the id of a bootstrap token to name
+	// its Secret.
+	BootstrapTokenSecretPrefix = "machine-bootstrap-token-"
+	// BootstrapTokenSecretKey is the key of the secret part of the token.
+	BootstrapTokenSecretKey = "token-secret"
+	// BootstrapTokenPoolKey is the key of the pool the token can fetch the
+	// config of.
+	BootstrapTokenPoolKey = "pool"
+	// BootstrapTokenMachineKey is the key of the machine the token was minted
+	// for.
+	BootstrapTokenMachineKey = "machine"
+	// BootstrapTokenExpirationKey is the key of the RFC3339 time after which
+	// the token is no longer valid.
+	BootstrapTokenExpirationKey = "expiration"
+	// BootstrapTokenPoolLabelKey labels the Secrets of pool tokens, minted
+	// without a machine, with their pool.
+	BootstrapTokenPoolLabelKey = "machineconfiguration.openshift.io/bootstrap-token-pool"
+
+	bootstrapTokenIDBytes     = 3
+	bootstrapTokenSecretBytes = 8
+	nodeCommonNamePrefix      = "system:node:"
+	nodesGroup                = "system:nodes"
+)
+
+var (
+	// errNoCredentials is returned when a request carries no credentials of
+	// any of the authentication methods.
+	errNoCredentials = errors.New("no node credentials")
+	// errInvalidCredentials is wrapped by the errors of authenticators when a
+	// request carries credentials which do not prove the identity of a node.
+	errInvalidCredentials = errors.New("invalid node credentials")
+)
+
+// NodeIdentity is the identity of the machine which requested a config.
+type NodeIdentity struct {
+	// Machine is the name of the machine or node, empty for pool bootstrap
+	// tokens, which do not identify a single machine.
+	Machine string
+	// Method is the method which authenticated the machine.
+	Method string
+	// commit is called right before the config is served, e.g. to consume a
+	// one-time token. The config is not served if it fails.
+	commit func(context.Context) error
+}
+
+// NodeAuthenticator proves the identity of the machine which requested the
+// config of a pool. It allows attestation mechanisms such as TPM quotes to
+// be plugged into the machine config server.
+type NodeAuthenticator interface {
+	// Authenticate returns the identity of the machine, or nil if the request
+	// carries no credentials for the method. It returns an error wrapping
+	// errInvalidCredentials if the credentials are not valid for the pool.
+	Authenticate(ctx context.Context, r *http.Request, pool string) (*NodeIdentity, error)
+}
+
+// authenticateNode returns the identity of the first authenticator which
+// recognizes the credentials of the request.
+func authenticateNode(ctx context.Context, authenticators []NodeAuthenticator, r *http.Request, pool string) (*NodeIdentity, error) {
+	for _, authenticator := range authenticators {
+		identity, err := authenticator.Authenticate(ctx, r, pool)
+		if err != nil {
+			return nil, err
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, errNoCredentials
+}
+
+// bootstrapTokenAuthenticator authenticates machines with tokens sent as a
+// bearer token, which can be embedded in the stub Ignition config of a machine
+// with the httpHeaders of its config source. A token is "<id>.<secret>" and is
+// stored in the BootstrapTokenSecretPrefix+<id> Secret of the MCO namespace.
+// It can fetch the config of a single pool. Tokens minted for a machine can
+// only fetch the config of that node, and are deleted once they did. Pool
+// tokens, minted without a machine for the stub Ignition config shared by all
+// the machines of a pool, can be used until they expire, but not to fetch the
+// host config fragments of a node, as they do not identify one.
+type bootstrapTokenAuthenticator struct {
+	kubeClient clientset.Interface
+	now        func() time.Time
+}
+
+// NewBootstrapTokenAuthenticator returns an authenticator of one-time
+// bootstrap tokens.
+func NewBootstrapTokenAuthenticator(kubeClient clientset.Interface) NodeAuthenticator {
+	return &bootstrapTokenAuthenticator{kubeClient: kubeClient, now: time.Now}
+}
+
+func (a *bootstrapTokenAuthenticator) Authenticate(ctx context.Context, r *http.Request, pool string) (*NodeIdentity, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, nil
+	}
+	id, tokenSecret, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || id == "" || tokenSecret == "" {
+		return nil, fmt.Errorf("%w: malformed bootstrap token", errInvalidCredentials)
+	}
+
+	secret, err := a.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Get(ctx, BootstrapTokenSecretPrefix+id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: unknown or used bootstrap token %q", errInvalidCredentials, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get bootstrap token %q: %w", id, err)
+	}
+	if secret.Type != BootstrapTokenSecretType ||
+		subtle.ConstantTimeCompare(secret.Data[BootstrapTokenSecretKey], []byte(tokenSecret)) != 1 {
+		return nil, fmt.Errorf("%w: bootstrap token %q does not match", errInvalidCredentials, id)
+	}
+	if expiration, ok := secret.Data[BootstrapTokenExpirationKey]; ok {
+		expires, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil || a.now().After(expires) {
+			return nil, fmt.Errorf("%w: bootstrap token %q expired", errInvalidCredentials, id)
+		}
+	}
+	if tokenPool := string(secret.Data[BootstrapTokenPoolKey]); tokenPool != pool {
+		return nil, fmt.Errorf("%w: bootstrap token %q is for pool %q", errInvalidCredentials, id, tokenPool)
+	}
+
+	machine := string(secret.Data[BootstrapTokenMachineKey])
+	node := r.URL.Query().Get("node")
+	if machine == "" {
+		if node != "" {
+			return nil, fmt.Errorf("%w: pool bootstrap token %q cannot fetch the config of node %q", errInvalidCredentials, id, node)
+		}
+		return &NodeIdentity{Method: NodeIdentityBootstrapToken}, nil
+	}
+	if node != machine {
+		return nil, fmt.Errorf("%w: bootstrap token %q is for machine %q, not %q", errInvalidCredentials, id, machine, node)
+	}
+	return &NodeIdentity{
+		Machine: machine,
+		Method:  NodeIdentityBootstrapToken,
+		commit: func(ctx context.Context) error {
+			// the UID precondition makes sure the token is used once, even by
+			// concurrent requests
+			err := a.kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{UID: &secret.UID},
+			})
+			if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+				return fmt.Errorf("%w: bootstrap token %q was already used", errInvalidCredentials, id)
+			}
+			return err
+		},
+	}, nil
+}
+
+// NewBootstrapTokenSecret mints a bootstrap token to fetch the config of a
+// pool, and returns it with the Secret to create to make the machine config
+// server accept it. A token minted for a machine can be used once, by
+// requests for that node; one minted without a machine is a pool token, used
+// by any machine of the pool until it expires.
+func NewBootstrapTokenSecret(pool, machine string, ttl time.Duration) (*corev1.Secret, string, error) {
+	id, err := randomHex(bootstrapTokenIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	tokenSecret, err := randomHex(bootstrapTokenSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      BootstrapTokenSecretPrefix + id,
+			Namespace: ctrlcommon.MCONamespace,
+		},
+		Type: BootstrapTokenSecretType,
+		Data: map[string][]byte{
+			BootstrapTokenSecretKey:     []byte(tokenSecret),
+			BootstrapTokenPoolKey:       []byte(pool),
+			BootstrapTokenMachineKey:    []byte(machine),
+			BootstrapTokenExpirationKey: []byte(time.Now().Add(ttl).UTC().Format(time.RFC3339)),
+		},
+	}
+	if machine == "" {
+		secret.Labels = map[string]string{BootstrapTokenPoolLabelKey: pool}
+	}
+	return secret, id + "." + tokenSecret, nil
+}
+
+// GetBootstrapToken returns the token stored in a bootstrap token Secret and
+// when it expires.
+func GetBootstrapToken(secret *corev1.Secret) (string, time.Time, error) {
+	id, ok := strings.CutPrefix(secret.Name, BootstrapTokenSecretPrefix)
+	if !ok || secret.Type != BootstrapTokenSecretType || len(secret.Data[BootstrapTokenSecretKey]) == 0 {
+		return "", time.Time{}, fmt.Errorf("secret %s is not a bootstrap token", secret.Name)
+	}
+	expires, err := time.Parse(time.RFC3339, string(secret.Data[BootstrapTokenExpirationKey]))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid expiration of bootstrap token %s: %w", secret.Name, err)
+	}
+	return id + "." + string(secret.Data[BootstrapTokenSecretKey]), expires, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate bootstrap token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// clientCertificateAuthenticator authenticates nodes with the client
+// certificates issued to their kubelet. The certificate chain is verified by
+// the TLS server against the configured client CAs. A node can only fetch the
+// config of its own pool, requested with its own name.
+type clientCertificateAuthenticator struct {
+	kubeClient clientset.Interface
+	mcpLister  mcfglistersv1.MachineConfigPoolLister
+}
+
+// NewClientCertificateAuthenticator returns an authenticator of node client
+// certificates. The TLS config of the server must verify client certificates.
+func NewClientCertificateAuthenticator(kubeClient clientset.Interface, mcpLister mcfglistersv1.MachineConfigPoolLister) NodeAuthenticator {
+	return &clientCertificateAuthenticator{kubeClient: kubeClient, mcpLister: mcpLister}
+}
+
+func (a *clientCertificateAuthenticator) Authenticate(ctx context.Context, r *http.Request, pool string) (*NodeIdentity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	node, ok := strings.CutPrefix(cert.Subject.CommonName, nodeCommonNamePrefix)
+	if !ok || node == "" || !slices.Contains(cert.Subject.Organization, nodesGroup) {
+		return nil, fmt.Errorf("%w: client certificate %q was not issued to a node", errInvalidCredentials, cert.Subject.CommonName)
+	}
+	if requested := r.URL.Query().Get("node"); requested != node {
+		return nil, fmt.Errorf("%w: node %q cannot fetch the config of node %q", errInvalidCredentials, node, requested)
+	}
+
+	nodeObj, err := a.kubeClient.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: unknown node %q", errInvalidCredentials, node)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get node %q: %w", node, err)
+	}
+	nodePool, err := helpers.GetPrimaryPoolForNode(a.mcpLister, nodeObj)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the pool of node %q: %w", node, err)
+	}
+	if nodePool == nil || nodePool.Name != pool {
+		return nil, fmt.Errorf("%w: node %q is not in pool %q", errInvalidCredentials, node, pool)
+	}
+	return &NodeIdentity{Machine: node, Method: NodeIdentityClientCertificate}, nil
+}
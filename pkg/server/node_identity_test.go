@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	mcfglistersv1 "github.com/openshift/client-go/machineconfiguration/listers/machineconfiguration/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	"github.com/openshift/machine-config-operator/test/helpers"
+)
+
+func newTokenRequest(method, pool, token string) *http.Request {
+	return newNodeTokenRequest(method, pool, "", token)
+}
+
+func newNodeTokenRequest(method, pool, node, token string) *http.Request {
+	target := "http://testrequest/config/" + pool
+	if node != "" {
+		target += "?node=" + node
+	}
+	r := setAcceptHeaderOnReq(httptest.NewRequest(method, target, nil))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestBootstrapTokenAuthenticator(t *testing.T) {
+	secret, token, err := NewBootstrapTokenSecret("worker", "worker-0", time.Hour)
+	require.NoError(t, err)
+	id, _, _ := strings.Cut(token, ".")
+	assert.Equal(t, BootstrapTokenSecretPrefix+id, secret.Name)
+
+	kubeClient := fakeclientset.NewSimpleClientset(secret)
+	authenticator := NewBootstrapTokenAuthenticator(kubeClient)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		pool    string
+		node    string
+		token   string
+		now     time.Time
+		invalid bool
+	}{
+		{
+			name: "no token",
+			pool: "worker",
+		},
+		{
+			name:    "malformed token",
+			pool:    "worker",
+			token:   "malformed",
+			invalid: true,
+		},
+		{
+			name:    "unknown token",
+			pool:    "worker",
+			token:   "abcdef.0123456789abcdef",
+			invalid: true,
+		},
+		{
+			name:    "wrong secret",
+			pool:    "worker",
+			node:    "worker-0",
+			token:   id + ".0123456789abcdef",
+			invalid: true,
+		},
+		{
+			name:    "wrong pool",
+			pool:    "master",
+			node:    "worker-0",
+			token:   token,
+			invalid: true,
+		},
+		{
+			name:    "expired token",
+			pool:    "worker",
+			node:    "worker-0",
+			token:   token,
+			now:     time.Now().Add(2 * time.Hour),
+			invalid: true,
+		},
+		{
+			name:    "no node",
+			pool:    "worker",
+			token:   token,
+			invalid: true,
+		},
+		{
+			name:    "other node",
+			pool:    "worker",
+			node:    "worker-1",
+			token:   token,
+			invalid: true,
+		},
+		{
+			name:  "valid token",
+			pool:  "worker",
+			node:  "worker-0",
+			token: token,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			authenticator.(*bootstrapTokenAuthenticator).now = func() time.Time {
+				if testCase.now.IsZero() {
+					return time.Now()
+				}
+				return testCase.now
+			}
+			identity, err := authenticator.Authenticate(ctx, newNodeTokenRequest(http.MethodGet, testCase.pool, testCase.node, testCase.token), testCase.pool)
+			if testCase.invalid {
+				assert.ErrorIs(t, err, errInvalidCredentials)
+				assert.Nil(t, identity)
+				return
+			}
+			require.NoError(t, err)
+			if testCase.token == "" {
+				assert.Nil(t, identity)
+				return
+			}
+			require.NotNil(t, identity)
+			assert.Equal(t, "worker-0", identity.Machine)
+			assert.Equal(t, NodeIdentityBootstrapToken, identity.Method)
+		})
+	}
+
+	// the token is consumed when the config is served
+	identity, err := authenticator.Authenticate(ctx, newNodeTokenRequest(http.MethodGet, "worker", "worker-0", token), "worker")
+	require.NoError(t, err)
+	require.NoError(t, identity.commit(ctx))
+	_, err = kubeClient.CoreV1().Secrets(ctrlcommon.MCONamespace).Get(ctx, secret.Name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.ErrorIs(t, identity.commit(ctx), errInvalidCredentials)
+	_, err = authenticator.Authenticate(ctx, newNodeTokenRequest(http.MethodGet, "worker", "worker-0", token), "worker")
+	assert.ErrorIs(t, err, errInvalidCredentials)
+}
+
+func TestPoolBootstrapToken(t *testing.T) {
+	secret, token, err := NewBootstrapTokenSecret("worker", "", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "worker", secret.Labels[BootstrapTokenPoolLabelKey])
+
+	stored, expires, err := GetBootstrapToken(secret)
+	require.NoError(t, err)
+	assert.Equal(t, token, stored)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Minute)
+
+	kubeClient := fakeclientset.NewSimpleClientset(secret)
+	authenticator := NewBootstrapTokenAuthenticator(kubeClient)
+	ctx := context.Background()
+
+	// pool tokens are used by any machine of the pool, and are not consumed
+	for range 2 {
+		identity, err := authenticator.Authenticate(ctx, newTokenRequest(http.MethodGet, "worker", token), "worker")
+		require.NoError(t, err)
+		assert.Equal(t, &NodeIdentity{Method: NodeIdentityBootstrapToken}, identity)
+	}
+	_, err = authenticator.Authenticate(ctx, newTokenRequest(http.MethodGet, "master", token), "master")
+	assert.ErrorIs(t, err, errInvalidCredentials)
+	// they do not identify a node, so cannot fetch its host config fragments
+	for _, node := range []string{"worker-0", "52:54:00:aa:bb:cc"} {
+		_, err = authenticator.Authenticate(ctx, newNodeTokenRequest(http.MethodGet, "worker", node, token), "worker")
+		assert.ErrorIs(t, err, errInvalidCredentials)
+	}
+
+	_, _, err = GetBootstrapToken(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pull-secret"}})
+	assert.Error(t, err)
+}
+
+func TestClientCertificateAuthenticator(t *testing.T) {
+	newPool := func(name, role string) *mcfgv1.MachineConfigPool {
+		return &mcfgv1.MachineConfigPool{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: mcfgv1.MachineConfigPoolSpec{
+				NodeSelector: metav1.AddLabelToSelector(&metav1.LabelSelector{}, "node-role.kubernetes.io/"+role, ""),
+			},
+		}
+	}
+	newNode := func(name, role string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node-role.kubernetes.io/" + role: ""}}}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(newPool("master", "master")))
+	require.NoError(t, indexer.Add(newPool("worker", "worker")))
+	kubeClient := fakeclientset.NewSimpleClientset(newNode("master-0", "master"), newNode("worker-0", "worker"), newNode("edge-0", "edge"))
+	authenticator := NewClientCertificateAuthenticator(kubeClient, mcfglistersv1.NewMachineConfigPoolLister(indexer))
+
+	nodeCert := func(node string) pkix.Name {
+		return pkix.Name{CommonName: "system:node:" + node, Organization: []string{"system:nodes"}}
+	}
+	withCert := func(subject pkix.Name, pool, node string) *http.Request {
+		r := newNodeTokenRequest(http.MethodGet, pool, node, "")
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: subject}}}}
+		return r
+	}
+	ctx := context.Background()
+
+	identity, err := authenticator.Authenticate(ctx, newTokenRequest(http.MethodGet, "worker", ""), "worker")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	identity, err = authenticator.Authenticate(ctx, withCert(nodeCert("worker-0"), "worker", "worker-0"), "worker")
+	require.NoError(t, err)
+	assert.Equal(t, &NodeIdentity{Machine: "worker-0", Method: NodeIdentityClientCertificate}, identity)
+
+	identity, err = authenticator.Authenticate(ctx, withCert(nodeCert("master-0"), "master", "master-0"), "master")
+	require.NoError(t, err)
+	assert.Equal(t, &NodeIdentity{Machine: "master-0", Method: NodeIdentityClientCertificate}, identity)
+
+	testCases := []struct {
+		name    string
+		subject pkix.Name
+		pool    string
+		node    string
+	}{
+		{name: "not a node", subject: pkix.Name{CommonName: "system:admin", Organization: []string{"system:masters"}}, pool: "worker", node: "worker-0"},
+		{name: "not in the nodes group", subject: pkix.Name{CommonName: "system:node:worker-0"}, pool: "worker", node: "worker-0"},
+		{name: "no node", subject: nodeCert("worker-0"), pool: "worker"},
+		{name: "other node", subject: nodeCert("worker-0"), pool: "worker", node: "master-0"},
+		{name: "other pool", subject: nodeCert("worker-0"), pool: "master", node: "worker-0"},
+		{name: "no pool", subject: nodeCert("edge-0"), pool: "worker", node: "edge-0"},
+		{name: "unknown node", subject: nodeCert("worker-1"), pool: "worker", node: "worker-1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := authenticator.Authenticate(ctx, withCert(tc.subject, tc.pool, tc.node), tc.pool)
+			assert.ErrorIs(t, err, errInvalidCredentials)
+		})
+	}
+}
+
+func TestAPIHandlerNodeIdentity(t *testing.T) {
+	secret, token, err := NewBootstrapTokenSecret("worker", "worker-0", time.Hour)
+	require.NoError(t, err)
+	kubeClient := fakeclientset.NewSimpleClientset(secret)
+
+	ms := &mockServer{
+		GetConfigFn: func(poolRequest) (*runtime.RawExtension, error) {
+			return &runtime.RawExtension{Raw: helpers.MarshalOrDie(ctrlcommon.NewIgnConfig())}, nil
+		},
+	}
+	handler := NewServerAPIHandler(ms, NewBootstrapTokenAuthenticator(kubeClient))
+	serve := func(r *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	resp := serve(newTokenRequest(http.MethodGet, "worker", ""))
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusUnauthorized)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	checkBodyLength(t, resp, 0)
+
+	resp = serve(newNodeTokenRequest(http.MethodGet, "master", "worker-0", token))
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusForbidden)
+	checkBodyLength(t, resp, 0)
+
+	// HEAD requests do not consume the token
+	resp = serve(newNodeTokenRequest(http.MethodHead, "worker", "worker-0", token))
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusOK)
+
+	resp = serve(newNodeTokenRequest(http.MethodGet, "worker", "worker-0", token))
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusOK)
+	checkBodyLength(t, resp, expectedContentLength)
+
+	resp = serve(newNodeTokenRequest(http.MethodGet, "worker", "worker-0", token))
+	defer resp.Body.Close()
+	checkStatus(t, resp, http.StatusForbidden)
+}
@@ -70,10 +70,11 @@ func newAppendersBuilder(version *semver.Version, kubeconfigFn kubeconfigFunc, c
 	}
 }
 
-// WithNodeAnnotations adds the node annotations appender with the specified config and image.
-func (ab *appendersBuilder) WithNodeAnnotations(currMachineConfig, image string) *appendersBuilder {
+// WithNodeAnnotations adds the node annotations appender with the specified config and image,
+// and if given, how the config was selected.
+func (ab *appendersBuilder) WithNodeAnnotations(currMachineConfig, image, selection string) *appendersBuilder {
 	ab.customAppenders = append(ab.customAppenders, func(cfg *ign3types.Config, mc *mcfgv1.MachineConfig) error {
-		return appendNodeAnnotations(cfg, currMachineConfig, image, mc)
+		return appendNodeAnnotations(cfg, currMachineConfig, image, selection, mc)
 	})
 	return ab
 }
@@ -204,8 +205,8 @@ func appendKubeConfig(conf *ign3types.Config, f kubeconfigFunc) error {
 	return nil
 }
 
-func appendNodeAnnotations(conf *ign3types.Config, currConf, image string, mc *mcfgv1.MachineConfig) error {
-	anno, err := getNodeAnnotation(currConf, image, mc)
+func appendNodeAnnotations(conf *ign3types.Config, currConf, image, selection string, mc *mcfgv1.MachineConfig) error {
+	anno, err := getNodeAnnotation(currConf, image, selection, mc)
 	if err != nil {
 		return err
 	}
@@ -216,13 +217,16 @@ func appendNodeAnnotations(conf *ign3types.Config, currConf, image string, mc *m
 	return nil
 }
 
-func getNodeAnnotation(conf, image string, mc *mcfgv1.MachineConfig) (string, error) {
+func getNodeAnnotation(conf, image, selection string, mc *mcfgv1.MachineConfig) (string, error) {
 	nodeAnnotations := map[string]string{
 		daemonconsts.CurrentMachineConfigAnnotationKey:     conf,
 		daemonconsts.DesiredMachineConfigAnnotationKey:     conf,
 		daemonconsts.FirstPivotMachineConfigAnnotationKey:  conf,
 		daemonconsts.MachineConfigDaemonStateAnnotationKey: daemonconsts.MachineConfigDaemonStateDone,
 	}
+	if selection != "" {
+		nodeAnnotations[daemonconsts.InitialConfigSelectionAnnotationKey] = selection
+	}
 
 	// Determine which image to use:
 	// 1. Pre-built image from MC annotations (install-time hybrid OCL) takes priority
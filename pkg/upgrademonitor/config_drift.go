@@ -0,0 +1,123 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	mcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+// configDriftFieldManager is the field manager applying the config drift
+// report annotation on MachineConfigNodes.
+const configDriftFieldManager = "machine-config-daemon-config-drift"
+
+// ConfigDriftKind is how a file or unit drifted from its MachineConfig.
+type ConfigDriftKind string
+
+const (
+	// ConfigDriftKindContent means the contents differ.
+	ConfigDriftKindContent ConfigDriftKind = "Content"
+	// ConfigDriftKindMode means the file mode differs.
+	ConfigDriftKindMode ConfigDriftKind = "Mode"
+	// ConfigDriftKindMissing means the file is missing.
+	ConfigDriftKindMissing ConfigDriftKind = "Missing"
+	// ConfigDriftKindEnabledState means the unit is enabled, disabled or
+	// masked differently.
+	ConfigDriftKindEnabledState ConfigDriftKind = "EnabledState"
+)
+
+// ConfigDriftEntry is a file, systemd unit or dropin which drifted from the
+// node's current MachineConfig.
+type ConfigDriftEntry struct {
+	// Path is the path of the drifted file, unit or dropin.
+	Path string `json:"path"`
+	// Unit is the name of the systemd unit, for units and dropins.
+	Unit string `json:"unit,omitempty"`
+	// Kind is how the item drifted.
+	Kind ConfigDriftKind `json:"kind"`
+	// ExpectedHash and ActualHash are the SHA256 of the expected and on-disk
+	// contents. ActualHash is empty if the file is missing.
+	ExpectedHash string `json:"expectedHash,omitempty"`
+	ActualHash   string `json:"actualHash,omitempty"`
+	// Expected and Actual are the expected and on-disk file mode or unit
+	// enabled state, for Mode and EnabledState drift.
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// FirstSeen is when the drift was first reported.
+	FirstSeen metav1.Time `json:"firstSeen"`
+}
+
+func (e ConfigDriftEntry) key() string {
+	return string(e.Kind) + " " + e.Path
+}
+
+// UpdateMachineConfigNodeConfigDrift publishes the config drift of a node on
+// its MachineConfigNode, or removes it if entries is empty. Entries which were
+// already reported keep the time they were first seen.
+func UpdateMachineConfigNodeConfigDrift(fgHandler ctrlcommon.FeatureGatesHandler, mcfgClient mcfgclientset.Interface, nodeName string, entries []ConfigDriftEntry) error {
+	if fgHandler == nil || mcfgClient == nil {
+		return nil
+	}
+
+	mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var existing []ConfigDriftEntry
+	if value, ok := mcn.Annotations[daemonconsts.ConfigDriftReportAnnotationKey]; ok {
+		if err := json.Unmarshal([]byte(value), &existing); err != nil {
+			klog.Warningf("Replacing unparseable config drift report of MachineConfigNode %s: %v", nodeName, err)
+			existing = nil
+		}
+	}
+
+	merged := mergeConfigDriftEntries(existing, entries, metav1.Now())
+	if reflect.DeepEqual(existing, merged) {
+		return nil
+	}
+
+	value := ""
+	if len(merged) > 0 {
+		out, err := json.Marshal(merged)
+		if err != nil {
+			return fmt.Errorf("could not marshal config drift report: %w", err)
+		}
+		value = string(out)
+	}
+	return ApplyMachineConfigNodeAnnotation(mcfgClient, nodeName, configDriftFieldManager, daemonconsts.ConfigDriftReportAnnotationKey, value)
+}
+
+// mergeConfigDriftEntries returns entries, with the FirstSeen time of those
+// already in existing carried over and the others first seen now. A drift
+// whose hashes or states changed is still the same drift.
+func mergeConfigDriftEntries(existing, entries []ConfigDriftEntry, now metav1.Time) []ConfigDriftEntry {
+	firstSeen := map[string]metav1.Time{}
+	for _, e := range existing {
+		firstSeen[e.key()] = e.FirstSeen
+	}
+
+	var merged []ConfigDriftEntry
+	for _, e := range entries {
+		if seen, ok := firstSeen[e.key()]; ok {
+			e.FirstSeen = seen
+		} else {
+			// Serialization drops sub-second precision, so do the same here
+			// so that an unchanged report compares equal.
+			e.FirstSeen = metav1.NewTime(now.Rfc3339Copy().Time)
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
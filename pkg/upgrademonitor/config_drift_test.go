@@ -0,0 +1,62 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	fakemcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+func TestUpdateMachineConfigNodeConfigDrift(t *testing.T) {
+	mcfgClient := fakemcfgclientset.NewClientset(&mcfgv1.MachineConfigNode{ObjectMeta: metav1.ObjectMeta{Name: "node"}})
+	fgHandler := ctrlcommon.NewFeatureGatesHardcodedHandler(nil, nil)
+
+	getReport := func() ([]ConfigDriftEntry, bool) {
+		mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), "node", metav1.GetOptions{})
+		require.NoError(t, err)
+		value, ok := mcn.Annotations[daemonconsts.ConfigDriftReportAnnotationKey]
+		if !ok {
+			return nil, false
+		}
+		var entries []ConfigDriftEntry
+		require.NoError(t, json.Unmarshal([]byte(value), &entries))
+		return entries, true
+	}
+
+	content := ConfigDriftEntry{Path: "/etc/foo", Kind: ConfigDriftKindContent, ExpectedHash: "a", ActualHash: "b"}
+	require.NoError(t, UpdateMachineConfigNodeConfigDrift(fgHandler, mcfgClient, "node", []ConfigDriftEntry{content}))
+	report, ok := getReport()
+	require.True(t, ok)
+	require.Len(t, report, 1)
+	assert.Equal(t, "/etc/foo", report[0].Path)
+	assert.False(t, report[0].FirstSeen.IsZero())
+	firstSeen := report[0].FirstSeen
+
+	// Drift which is still there keeps when it was first seen, even if it
+	// changed further.
+	time.Sleep(time.Second)
+	content.ActualHash = "c"
+	mode := ConfigDriftEntry{Path: "/etc/bar", Kind: ConfigDriftKindMode, Expected: "-rw-r--r--", Actual: "-rwxrwxrwx"}
+	require.NoError(t, UpdateMachineConfigNodeConfigDrift(fgHandler, mcfgClient, "node", []ConfigDriftEntry{content, mode}))
+	report, _ = getReport()
+	require.Len(t, report, 2)
+	assert.Equal(t, "c", report[0].ActualHash)
+	assert.True(t, firstSeen.Equal(&report[0].FirstSeen))
+	assert.True(t, report[1].FirstSeen.After(firstSeen.Time))
+
+	require.NoError(t, UpdateMachineConfigNodeConfigDrift(fgHandler, mcfgClient, "node", nil))
+	_, ok = getReport()
+	assert.False(t, ok)
+
+	// Nodes without a MachineConfigNode are skipped.
+	assert.NoError(t, UpdateMachineConfigNodeConfigDrift(fgHandler, mcfgClient, "other", []ConfigDriftEntry{content}))
+}
@@ -0,0 +1,70 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+// pinnedImageGCFieldManager is the field manager applying the pinned image
+// garbage collection report annotation on MachineConfigNodes.
+const pinnedImageGCFieldManager = "machine-config-daemon-pinned-image-gc"
+
+// PinnedImageGCReport is what the daemon reclaimed by removing images which
+// were dropped from the PinnedImageSets of its node.
+type PinnedImageGCReport struct {
+	// RemovedImages are the images removed by the last garbage collection.
+	RemovedImages []string `json:"removedImages"`
+	// ReclaimedBytes is the size of the images removed by the last garbage
+	// collection.
+	ReclaimedBytes int64 `json:"reclaimedBytes"`
+	// TotalReclaimedBytes is the size of all the images removed since the
+	// report was first published.
+	TotalReclaimedBytes int64 `json:"totalReclaimedBytes"`
+	// LastCollected is when the last garbage collection removed images.
+	LastCollected metav1.Time `json:"lastCollected"`
+}
+
+// UpdateMachineConfigNodePinnedImageGC publishes the images removed by a
+// garbage collection of unpinned images on the node's MachineConfigNode,
+// adding the reclaimed bytes to the total of the previous reports.
+func UpdateMachineConfigNodePinnedImageGC(fgHandler ctrlcommon.FeatureGatesHandler, mcfgClient mcfgclientset.Interface, nodeName string, removedImages []string, reclaimedBytes int64) error {
+	if fgHandler == nil || mcfgClient == nil || len(removedImages) == 0 {
+		return nil
+	}
+
+	mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var previous PinnedImageGCReport
+	if value, ok := mcn.Annotations[daemonconsts.PinnedImageGCReportAnnotationKey]; ok {
+		if err := json.Unmarshal([]byte(value), &previous); err != nil {
+			klog.Warningf("Replacing unparseable pinned image garbage collection report of MachineConfigNode %s: %v", nodeName, err)
+		}
+	}
+
+	report := PinnedImageGCReport{
+		RemovedImages:       removedImages,
+		ReclaimedBytes:      reclaimedBytes,
+		TotalReclaimedBytes: previous.TotalReclaimedBytes + reclaimedBytes,
+		LastCollected:       metav1.Now(),
+	}
+	out, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("could not marshal pinned image garbage collection report: %w", err)
+	}
+	return ApplyMachineConfigNodeAnnotation(mcfgClient, nodeName, pinnedImageGCFieldManager, daemonconsts.PinnedImageGCReportAnnotationKey, string(out))
+}
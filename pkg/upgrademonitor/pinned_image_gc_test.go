@@ -0,0 +1,64 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	fakemcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+func TestUpdateMachineConfigNodePinnedImageGC(t *testing.T) {
+	mcfgClient := fakemcfgclientset.NewClientset(&mcfgv1.MachineConfigNode{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node",
+		Annotations: map[string]string{"other": "annotation"},
+	}})
+	fgHandler := ctrlcommon.NewFeatureGatesHardcodedHandler(nil, nil)
+
+	getReport := func() (PinnedImageGCReport, bool) {
+		mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), "node", metav1.GetOptions{})
+		require.NoError(t, err)
+		value, ok := mcn.Annotations[daemonconsts.PinnedImageGCReportAnnotationKey]
+		if !ok {
+			return PinnedImageGCReport{}, false
+		}
+		var report PinnedImageGCReport
+		require.NoError(t, json.Unmarshal([]byte(value), &report))
+		return report, true
+	}
+
+	// Nothing removed, nothing reported.
+	require.NoError(t, UpdateMachineConfigNodePinnedImageGC(fgHandler, mcfgClient, "node", nil, 0))
+	_, ok := getReport()
+	assert.False(t, ok)
+
+	require.NoError(t, UpdateMachineConfigNodePinnedImageGC(fgHandler, mcfgClient, "node", []string{"image1", "image2"}, 300))
+	report, ok := getReport()
+	require.True(t, ok)
+	assert.Equal(t, []string{"image1", "image2"}, report.RemovedImages)
+	assert.Equal(t, int64(300), report.ReclaimedBytes)
+	assert.Equal(t, int64(300), report.TotalReclaimedBytes)
+	assert.False(t, report.LastCollected.IsZero())
+
+	// The total accumulates across garbage collections.
+	require.NoError(t, UpdateMachineConfigNodePinnedImageGC(fgHandler, mcfgClient, "node", []string{"image3"}, 200))
+	report, _ = getReport()
+	assert.Equal(t, []string{"image3"}, report.RemovedImages)
+	assert.Equal(t, int64(200), report.ReclaimedBytes)
+	assert.Equal(t, int64(500), report.TotalReclaimedBytes)
+
+	// Annotations of other writers are left alone.
+	mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "annotation", mcn.Annotations["other"])
+
+	// Nodes without a MachineConfigNode are skipped.
+	assert.NoError(t, UpdateMachineConfigNodePinnedImageGC(fgHandler, mcfgClient, "other", []string{"image1"}, 100))
+}
@@ -0,0 +1,141 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+const (
+	// pinnedImageSetProgressFieldManager is the field manager applying the
+	// pinned image set progress annotation on MachineConfigNodes.
+	pinnedImageSetProgressFieldManager = "machine-config-daemon-pinned-image-progress"
+	// maxPinnedImageSetProgressSize is the largest progress annotation
+	// published, leaving most of the 256KiB all the annotations of an object
+	// share to the others.
+	maxPinnedImageSetProgressSize = 64 * 1024
+)
+
+// PinnedImageState is where an image of a PinnedImageSet is in being
+// prefetched by a node.
+type PinnedImageState string
+
+const (
+	// PinnedImagePending means the image waits to be pulled.
+	PinnedImagePending PinnedImageState = "Pending"
+	// PinnedImagePulling means the image is being pulled.
+	PinnedImagePulling PinnedImageState = "Pulling"
+	// PinnedImagePulled means the image is on the node.
+	PinnedImagePulled PinnedImageState = "Pulled"
+	// PinnedImageFailed means the image failed to be pulled.
+	PinnedImageFailed PinnedImageState = "Failed"
+)
+
+// PinnedImageProgress is the prefetch progress of an image.
+type PinnedImageProgress struct {
+	// Name is the pull spec of the image.
+	Name  string           `json:"name"`
+	State PinnedImageState `json:"state"`
+	// Reason is why the image failed to be pulled.
+	Reason string `json:"reason,omitempty"`
+	// DownloadedBytes and TotalBytes are the bytes of the image the node
+	// downloaded and has to download. Layers shared with images which were
+	// sized before are not counted. The container runtime does not report the
+	// progress of a pull, so the bytes of an image are only counted as
+	// downloaded once it is pulled.
+	DownloadedBytes int64 `json:"downloadedBytes"`
+	TotalBytes      int64 `json:"totalBytes"`
+}
+
+// PinnedImageSetProgress is the prefetch progress of the images of a
+// PinnedImageSet.
+type PinnedImageSetProgress struct {
+	// Name is the name of the PinnedImageSet.
+	Name            string                `json:"name"`
+	Images          []PinnedImageProgress `json:"images"`
+	DownloadedBytes int64                 `json:"downloadedBytes"`
+	TotalBytes      int64                 `json:"totalBytes"`
+	// Truncated is the number of images left out of Images to keep the
+	// progress within the size of an annotation, so that a short list is
+	// never mistaken for all the images. Their bytes are still counted in
+	// the totals.
+	Truncated int `json:"truncated"`
+}
+
+// UpdateMachineConfigNodePinnedImageSetProgress publishes the per image
+// prefetch progress of the PinnedImageSets of a node on its
+// MachineConfigNode, or removes it if progress is empty. Progress too large
+// for the annotation leaves out images, see truncatePinnedImageSetProgress.
+func UpdateMachineConfigNodePinnedImageSetProgress(fgHandler ctrlcommon.FeatureGatesHandler, mcfgClient mcfgclientset.Interface, nodeName string, progress []PinnedImageSetProgress) error {
+	if fgHandler == nil || mcfgClient == nil {
+		return nil
+	}
+
+	mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	value := ""
+	if len(progress) > 0 {
+		out, err := marshalPinnedImageSetProgress(progress)
+		if err != nil {
+			return err
+		}
+		value = string(out)
+	}
+	existing, hasExisting := mcn.Annotations[daemonconsts.PinnedImageSetProgressAnnotationKey]
+	if (hasExisting && existing == value) || (!hasExisting && value == "") {
+		return nil
+	}
+	return ApplyMachineConfigNodeAnnotation(mcfgClient, nodeName, pinnedImageSetProgressFieldManager, daemonconsts.PinnedImageSetProgressAnnotationKey, value)
+}
+
+// marshalPinnedImageSetProgress marshals progress, truncated to fit in
+// maxPinnedImageSetProgressSize.
+func marshalPinnedImageSetProgress(progress []PinnedImageSetProgress) ([]byte, error) {
+	for level := 0; ; level++ {
+		truncated, ok := truncatePinnedImageSetProgress(progress, level)
+		out, err := json.Marshal(truncated)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal pinned image set progress: %w", err)
+		}
+		if len(out) <= maxPinnedImageSetProgressSize || !ok {
+			return out, nil
+		}
+	}
+}
+
+// truncatePinnedImageSetProgress returns progress with fewer images listed
+// the higher the level: level 0 lists all the images, level 1 leaves out the
+// pulled ones and level 2 lists none. The totals of the image sets are kept
+// and the images left out counted. It returns false once there is nothing
+// left to leave out.
+func truncatePinnedImageSetProgress(progress []PinnedImageSetProgress, level int) ([]PinnedImageSetProgress, bool) {
+	if level == 0 {
+		return progress, true
+	}
+	truncated := make([]PinnedImageSetProgress, 0, len(progress))
+	for _, setProgress := range progress {
+		images := []PinnedImageProgress{}
+		for _, image := range setProgress.Images {
+			if level == 1 && image.State != PinnedImagePulled {
+				images = append(images, image)
+			}
+		}
+		setProgress.Truncated += len(setProgress.Images) - len(images)
+		setProgress.Images = images
+		truncated = append(truncated, setProgress)
+	}
+	return truncated, level < 2
+}
@@ -0,0 +1,112 @@
+package upgrademonitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	fakemcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+)
+
+func TestUpdateMachineConfigNodePinnedImageSetProgress(t *testing.T) {
+	mcfgClient := fakemcfgclientset.NewClientset(&mcfgv1.MachineConfigNode{ObjectMeta: metav1.ObjectMeta{Name: "node"}})
+	fgHandler := ctrlcommon.NewFeatureGatesHardcodedHandler(nil, nil)
+
+	getProgress := func() ([]PinnedImageSetProgress, bool) {
+		mcn, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Get(context.TODO(), "node", metav1.GetOptions{})
+		require.NoError(t, err)
+		value, ok := mcn.Annotations[daemonconsts.PinnedImageSetProgressAnnotationKey]
+		if !ok {
+			return nil, false
+		}
+		var progress []PinnedImageSetProgress
+		require.NoError(t, json.Unmarshal([]byte(value), &progress))
+		return progress, true
+	}
+
+	progress := []PinnedImageSetProgress{{
+		Name: "worker-set",
+		Images: []PinnedImageProgress{
+			{Name: "image1", State: PinnedImagePulled, DownloadedBytes: 100, TotalBytes: 100},
+			{Name: "image2", State: PinnedImageFailed, Reason: "manifest unknown", TotalBytes: 200},
+		},
+		DownloadedBytes: 100,
+		TotalBytes:      300,
+	}}
+	require.NoError(t, UpdateMachineConfigNodePinnedImageSetProgress(fgHandler, mcfgClient, "node", progress))
+	published, ok := getProgress()
+	require.True(t, ok)
+	assert.Equal(t, progress, published)
+
+	// Unchanged progress does not update the MachineConfigNode.
+	mcfgClient.ClearActions()
+	require.NoError(t, UpdateMachineConfigNodePinnedImageSetProgress(fgHandler, mcfgClient, "node", progress))
+	for _, action := range mcfgClient.Actions() {
+		assert.Equal(t, "get", action.GetVerb())
+	}
+
+	// No image sets, no progress.
+	require.NoError(t, UpdateMachineConfigNodePinnedImageSetProgress(fgHandler, mcfgClient, "node", nil))
+	_, ok = getProgress()
+	assert.False(t, ok)
+
+	// Nodes without a MachineConfigNode are skipped.
+	assert.NoError(t, UpdateMachineConfigNodePinnedImageSetProgress(fgHandler, mcfgClient, "other", progress))
+}
+
+func TestMarshalPinnedImageSetProgress(t *testing.T) {
+	newProgress := func(pulled, pending int) []PinnedImageSetProgress {
+		setProgress := PinnedImageSetProgress{Name: "worker-set", Images: []PinnedImageProgress{}}
+		for i := 0; i < pulled+pending; i++ {
+			image := PinnedImageProgress{Name: fmt.Sprintf("quay.io/openshift/image@sha256:%064d", i), State: PinnedImagePulled, DownloadedBytes: 100, TotalBytes: 100}
+			if i >= pulled {
+				image.State = PinnedImagePending
+				image.DownloadedBytes = 0
+			}
+			setProgress.Images = append(setProgress.Images, image)
+			setProgress.DownloadedBytes += image.DownloadedBytes
+			setProgress.TotalBytes += image.TotalBytes
+		}
+		return []PinnedImageSetProgress{setProgress}
+	}
+	unmarshal := func(out []byte) PinnedImageSetProgress {
+		assert.LessOrEqual(t, len(out), maxPinnedImageSetProgressSize)
+		var progress []PinnedImageSetProgress
+		require.NoError(t, json.Unmarshal(out, &progress))
+		require.Len(t, progress, 1)
+		return progress[0]
+	}
+
+	// Progress which fits is published as is.
+	progress := newProgress(2, 1)
+	out, err := marshalPinnedImageSetProgress(progress)
+	require.NoError(t, err)
+	assert.Equal(t, progress[0], unmarshal(out))
+	assert.Contains(t, string(out), `"truncated":0`)
+
+	// Pulled images are left out first.
+	out, err = marshalPinnedImageSetProgress(newProgress(1000, 10))
+	require.NoError(t, err)
+	published := unmarshal(out)
+	assert.Len(t, published.Images, 10)
+	assert.Equal(t, 1000, published.Truncated)
+	assert.Contains(t, string(out), `"truncated":1000`)
+	assert.Equal(t, int64(100000), published.DownloadedBytes)
+	assert.Equal(t, int64(101000), published.TotalBytes)
+
+	// Then all of them.
+	out, err = marshalPinnedImageSetProgress(newProgress(0, 1000))
+	require.NoError(t, err)
+	published = unmarshal(out)
+	assert.Empty(t, published.Images)
+	assert.Equal(t, 1000, published.Truncated)
+	assert.Equal(t, int64(100000), published.TotalBytes)
+}
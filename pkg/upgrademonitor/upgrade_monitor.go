@@ -534,3 +534,23 @@ type ApplyCallback struct {
 	StatusConfigFn      func(applyConfig *machineconfigurationv1.MachineConfigNodeStatusApplyConfiguration)
 	MachineConfigNodeFn func(*mcfgv1.MachineConfigNode)
 }
+
+// ApplyMachineConfigNodeAnnotation sets an annotation of a MachineConfigNode,
+// or removes it if value is empty, with a server-side apply by fieldManager.
+//
+// The MachineConfigNode status API has no fields for the update plan, config
+// drift, pinned image garbage collection and pinned image set progress
+// reports, so until it does they are published as JSON annotations through
+// this function. Applying each with a field manager of its own means they do
+// not conflict with the other writers of the object. The MachineConfigNode
+// must exist, as it would otherwise be created without a spec.
+func ApplyMachineConfigNodeAnnotation(mcfgClient mcfgclientset.Interface, nodeName, fieldManager, key, value string) error {
+	mcnodeApplyConfig := machineconfigurationv1.MachineConfigNode(nodeName)
+	if value != "" {
+		mcnodeApplyConfig = mcnodeApplyConfig.WithAnnotations(map[string]string{key: value})
+	}
+	if _, err := mcfgClient.MachineconfigurationV1().MachineConfigNodes().Apply(context.TODO(), mcnodeApplyConfig, metav1.ApplyOptions{FieldManager: fieldManager, Force: true}); err != nil {
+		return fmt.Errorf("failed to apply %s annotation of MachineConfigNode %q: %w", key, nodeName, err)
+	}
+	return nil
+}